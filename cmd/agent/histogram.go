@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HistogramPayload is the gateway's form of a histogram sample: counts per
+// bucket rather than the exposition format's cumulative ones, and no +Inf
+// bucket, since count covers it.
+type HistogramPayload struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+// scrapedHistogram gathers the _bucket, _sum and _count lines of one
+// histogram series.
+type scrapedHistogram struct {
+	name     string
+	labels   map[string]string
+	buckets  map[float64]float64 // le -> cumulative count
+	sum      float64
+	count    float64
+	hasCount bool
+}
+
+// histogramScrape collects the histogram series of a scrape, in the order
+// they first appear.
+type histogramScrape struct {
+	byKey map[string]*scrapedHistogram
+	order []*scrapedHistogram
+}
+
+func newHistogramScrape() *histogramScrape {
+	return &histogramScrape{byKey: make(map[string]*scrapedHistogram)}
+}
+
+// add takes a sample line's series and value if it belongs to a metric
+// declared as a histogram, and reports whether it did. Lines that belong to
+// one but can't be parsed are swallowed too.
+func (h *histogramScrape) add(series string, value float64, info map[string]*metricInfo) bool {
+	name, rest, hasLabels := strings.Cut(series, "{")
+	var base, suffix string
+	for _, s := range []string{"_bucket", "_sum", "_count"} {
+		if b, ok := strings.CutSuffix(name, s); ok {
+			if m, ok := info[b]; ok && m.typ == "histogram" {
+				base, suffix = b, s
+				break
+			}
+		}
+	}
+	if base == "" {
+		return false
+	}
+
+	seriesLabels := map[string]string{}
+	if hasLabels {
+		body, ok := strings.CutSuffix(rest, "}")
+		if !ok {
+			return true
+		}
+		var err error
+		if seriesLabels, err = parseLabels(body); err != nil {
+			return true
+		}
+	}
+	le, hasLE := seriesLabels["le"]
+	delete(seriesLabels, "le")
+
+	key := histogramKey(base, seriesLabels)
+	sh, ok := h.byKey[key]
+	if !ok {
+		sh = &scrapedHistogram{name: base, labels: seriesLabels, buckets: make(map[float64]float64)}
+		h.byKey[key] = sh
+		h.order = append(h.order, sh)
+	}
+	switch suffix {
+	case "_bucket":
+		if !hasLE {
+			return true
+		}
+		bound, err := strconv.ParseFloat(le, 64)
+		if err != nil {
+			return true
+		}
+		sh.buckets[bound] = value
+	case "_sum":
+		sh.sum = value
+	case "_count":
+		sh.count, sh.hasCount = value, true
+	}
+	return true
+}
+
+// payloads converts the collected series, skipping any that are
+// inconsistent, such as buckets that shrink as le grows.
+func (h *histogramScrape) payloads(now int64) []MetricPayload {
+	var out []MetricPayload
+	for _, sh := range h.order {
+		p, ok := sh.payload()
+		if !ok {
+			continue
+		}
+		l := labels()
+		for k, v := range sh.labels {
+			l[k] = v
+		}
+		out = append(out, MetricPayload{Name: sh.name, Timestamp: now, Labels: l, Histogram: p})
+	}
+	return out
+}
+
+func (sh *scrapedHistogram) payload() (*HistogramPayload, bool) {
+	bounds := make([]float64, 0, len(sh.buckets))
+	for b := range sh.buckets {
+		bounds = append(bounds, b)
+	}
+	sort.Float64s(bounds)
+
+	p := &HistogramPayload{Sum: sh.sum}
+	var prev float64
+	for _, b := range bounds {
+		cumulative := sh.buckets[b]
+		if cumulative < prev {
+			return nil, false
+		}
+		if math.IsInf(b, +1) {
+			if !sh.hasCount {
+				sh.count, sh.hasCount = cumulative, true
+			}
+			break
+		}
+		p.Bounds = append(p.Bounds, b)
+		p.Counts = append(p.Counts, uint64(math.Round(cumulative-prev)))
+		prev = cumulative
+	}
+	if !sh.hasCount || sh.count < prev || len(p.Bounds) == 0 || math.IsInf(p.Bounds[0], -1) {
+		return nil, false
+	}
+	p.Count = uint64(math.Round(sh.count))
+	return p, true
+}
+
+func histogramKey(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\xff%s\xff%s", k, labels[k])
+	}
+	return b.String()
+}
+
+var labelValueUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n")
+
+// parseLabels reads the inside of a sample line's braces, such as
+// `method="GET",le="0.5"`.
+func parseLabels(s string) (map[string]string, error) {
+	out := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if s == "" {
+			return out, nil
+		}
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok || !strings.HasPrefix(rest, `"`) {
+			return nil, fmt.Errorf("malformed labels")
+		}
+		rest = rest[1:]
+		end := -1
+		for i := 0; i < len(rest); i++ {
+			if rest[i] == '\\' {
+				i++
+			} else if rest[i] == '"' {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated label value")
+		}
+		out[strings.TrimSpace(name)] = labelValueUnescaper.Replace(rest[:end])
+		s = rest[end+1:]
+	}
+}
@@ -32,6 +32,34 @@ type MetricPayload struct {
 	Value     float64           `json:"value"`
 	Timestamp int64             `json:"timestamp"`
 	Labels    map[string]string `json:"labels"`
+	Type      string            `json:"type,omitempty"`
+	Unit      string            `json:"unit,omitempty"`
+	Help      string            `json:"help,omitempty"`
+	// Histogram is set instead of Value for histogram metrics.
+	Histogram *HistogramPayload `json:"histogram,omitempty"`
+}
+
+// metricInfo is the metadata sent along with a metric's samples.
+type metricInfo struct {
+	typ, unit, help string
+}
+
+// metadataInterval is how often metadata is attached to a batch. The
+// gateway keeps it, so there is no point repeating it every few seconds.
+const metadataInterval = 10 * time.Minute
+
+var systemMetrics = map[string]metricInfo{
+	"system_cpu_percent":    {"gauge", "percent", "CPU utilization across all cores."},
+	"system_mem_percent":    {"gauge", "percent", "Share of physical memory in use."},
+	"system_mem_used_gb":    {"gauge", "gigabytes", "Physical memory in use."},
+	"system_mem_total_gb":   {"gauge", "gigabytes", "Total physical memory."},
+	"system_disk_percent":   {"gauge", "percent", "Share of the root filesystem in use."},
+	"system_disk_free_gb":   {"gauge", "gigabytes", "Free space on the root filesystem."},
+	"system_load_1":         {"gauge", "", "Load average over 1 minute."},
+	"system_load_5":         {"gauge", "", "Load average over 5 minutes."},
+	"system_load_15":        {"gauge", "", "Load average over 15 minutes."},
+	"system_net_sent_bytes": {"counter", "bytes", "Bytes sent on all network interfaces since boot."},
+	"system_net_recv_bytes": {"counter", "bytes", "Bytes received on all network interfaces since boot."},
 }
 
 func main() {
@@ -52,6 +80,7 @@ func main() {
 		fmt.Printf("  scrape: %s\n", *scrapeURL)
 	}
 
+	var lastMetadata time.Time
 	for {
 		var batch []MetricPayload
 
@@ -61,6 +90,14 @@ func main() {
 			batch = append(batch, collectAppMetrics()...)
 		}
 
+		if time.Since(lastMetadata) >= metadataInterval {
+			lastMetadata = time.Now()
+		} else {
+			for i := range batch {
+				batch[i].Type, batch[i].Unit, batch[i].Help = "", "", ""
+			}
+		}
+
 		if len(batch) > 0 {
 			sendBatch(batch)
 		}
@@ -78,8 +115,10 @@ func collectSystemMetrics() []MetricPayload {
 	var out []MetricPayload
 
 	add := func(name string, val float64) {
+		info := systemMetrics[name]
 		out = append(out, MetricPayload{
 			Name: name, Value: val, Timestamp: now, Labels: labels(),
+			Type: info.typ, Unit: info.unit, Help: info.help,
 		})
 	}
 
@@ -117,10 +156,16 @@ func collectAppMetrics() []MetricPayload {
 
 	now := time.Now().Unix()
 	var out []MetricPayload
+	info := make(map[string]*metricInfo)
+	histograms := newHistogramScrape()
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
-		if strings.HasPrefix(line, "#") || line == "" {
+		if strings.HasPrefix(line, "#") {
+			parseMetadataLine(line, info)
+			continue
+		}
+		if line == "" {
 			continue
 		}
 		parts := strings.Fields(line)
@@ -131,13 +176,56 @@ func collectAppMetrics() []MetricPayload {
 		if err != nil {
 			continue
 		}
+		if histograms.add(parts[0], val, info) {
+			continue
+		}
 		out = append(out, MetricPayload{
 			Name: parts[0], Value: val, Timestamp: now, Labels: labels(),
 		})
 	}
+	out = append(out, histograms.payloads(now)...)
+	// Metadata lines come before a metric's samples, but attach it
+	// afterwards anyway in case an exporter orders them differently.
+	for i := range out {
+		if m, ok := info[out[i].Name]; ok {
+			out[i].Type, out[i].Unit, out[i].Help = m.typ, m.unit, m.help
+		}
+	}
 	return out
 }
 
+var helpUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+
+// parseMetadataLine reads the "# HELP", "# TYPE" and (OpenMetrics)
+// "# UNIT" comments of the exposition format into info. Other comments are
+// ignored, as are types the gateway has no name for, such as untyped.
+func parseMetadataLine(line string, info map[string]*metricInfo) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 4 || fields[0] != "#" {
+		return
+	}
+	kind, name, text := fields[1], fields[2], fields[3]
+	if kind != "HELP" && kind != "TYPE" && kind != "UNIT" {
+		return
+	}
+	m, ok := info[name]
+	if !ok {
+		m = &metricInfo{}
+		info[name] = m
+	}
+	switch kind {
+	case "HELP":
+		m.help = helpUnescaper.Replace(text)
+	case "TYPE":
+		switch text {
+		case "counter", "gauge", "histogram", "summary":
+			m.typ = text
+		}
+	case "UNIT":
+		m.unit = text
+	}
+}
+
 func sendBatch(batch []MetricPayload) {
 	data, _ := json.Marshal(batch)
 	req, _ := http.NewRequest("POST", *ingestURL, bytes.NewBuffer(data))
@@ -151,7 +239,28 @@ func sendBatch(batch []MetricPayload) {
 		return
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusAccepted {
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusUnprocessableEntity, http.StatusTooManyRequests:
+	default:
 		log.Printf("Server rejected batch: %s", resp.Status)
+		return
+	}
+	// The gateway drops samples outside its acceptance window, usually a
+	// sign of clock skew on this host, and those over the account's limits,
+	// and says which.
+	var result struct {
+		Rejected   int `json:"rejected"`
+		Rejections []struct {
+			Name      string `json:"name"`
+			Timestamp int64  `json:"timestamp"`
+			Reason    string `json:"reason"`
+		} `json:"rejections"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Rejected == 0 {
+		return
+	}
+	log.Printf("Server rejected %d of %d samples", result.Rejected, len(batch))
+	for _, r := range result.Rejections {
+		log.Printf("  %s at %d: %s", r.Name, r.Timestamp, r.Reason)
 	}
 }
@@ -13,10 +13,10 @@ import (
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	pb "pmts/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/protobuf/proto"
 )
 
 const alertCooldown = 5 * time.Minute
@@ -86,14 +86,12 @@ func main() {
 	}
 	defer nc.Close()
 
-	_, err = nc.QueueSubscribe("metrics.upload", "alert-workers", func(m *nats.Msg) {
-		req := &pb.UploadRequest{}
-		if err := proto.Unmarshal(m.Data, req); err != nil {
-			logger.Error("Bad NATS message", "error", err)
-			return
-		}
-		checkRules(req.List, req.UserId, cache, logger)
-	})
+	js, err := jetstream.New(nc)
+	if err != nil {
+		logger.Error("Failed to open JetStream", "error", err)
+		os.Exit(1)
+	}
+	consumer, err := startAlertConsumer(context.Background(), js, cache, logger)
 	if err != nil {
 		logger.Error("Failed to subscribe", "error", err)
 		os.Exit(1)
@@ -104,6 +102,8 @@ func main() {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	logger.Info("Shutting down...")
+	consumer.Drain()
+	<-consumer.Closed()
 }
 
 func checkRules(list []*pb.TimeSeries, userID int64, cache *RuleCache, logger *slog.Logger) {
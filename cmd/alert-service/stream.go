@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+
+	pb "pmts/proto"
+	"pmts/stream"
+)
+
+// startAlertConsumer checks each batch from the stream against the rules,
+// acking it afterwards so a batch in flight when the service dies is
+// checked again on restart, once NATS_ACK_WAIT has passed. A check that
+// fails is retried with the same backoff storage uses. The durable consumer
+// starts at new batches: a fresh install should not fire alerts for
+// whatever the stream holds.
+func startAlertConsumer(ctx context.Context, js jetstream.JetStream, cache *RuleCache, logger *slog.Logger) (jetstream.ConsumeContext, error) {
+	rd, err := stream.RedeliveryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	str, err := stream.Ensure(ctx, js)
+	if err != nil {
+		return nil, fmt.Errorf("create stream: %w", err)
+	}
+	cons, err := str.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       "alert-workers",
+		FilterSubject: stream.UploadSubject,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       rd.AckWait,
+		MaxDeliver:    rd.MaxDeliver,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create consumer: %w", err)
+	}
+	return cons.Consume(func(m jetstream.Msg) {
+		req := &pb.UploadRequest{}
+		if err := proto.Unmarshal(m.Data(), req); err != nil {
+			logger.Error("Bad NATS message", "error", err)
+			m.Term()
+			return
+		}
+		if err := checkBatch(req, cache, logger); err != nil {
+			attempt := uint64(1)
+			if md, err := m.Metadata(); err == nil {
+				attempt = md.NumDelivered
+			}
+			logger.Error("Alert check failed", "error", err, "user", req.UserId, "attempt", attempt, "max_deliver", rd.MaxDeliver)
+			m.NakWithDelay(rd.Delay(attempt))
+			return
+		}
+		m.Ack()
+	})
+}
+
+// checkBatch runs the rules over a batch, turning a panic into an error so
+// the batch is retried with backoff, and eventually dropped, instead of
+// taking the service down on every redelivery.
+func checkBatch(req *pb.UploadRequest, cache *RuleCache, logger *slog.Logger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("check rules: %v", r)
+		}
+	}()
+	checkRules(req.List, req.UserId, cache, logger)
+	return nil
+}
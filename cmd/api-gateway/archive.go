@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "pmts/proto"
+)
+
+// A tenant archive holds a user's metadata, alert rules and samples as a
+// sequence of pb.ArchiveRecord, in one of two formats: NDJSON, one record
+// per line in protobuf's JSON mapping, or length-delimited protobuf, each
+// record prefixed by its size as a uvarint.
+const (
+	ndjsonType   = "application/x-ndjson"
+	protobufType = "application/x-protobuf"
+
+	// archiveTimeout bounds an export or import; a large tenant takes a
+	// while either way.
+	archiveTimeout = 30 * time.Minute
+	// importMessageBytes is roughly how much of an archive goes into each
+	// ImportTenant message, well under gRPC's 4MB limit.
+	importMessageBytes = 1 << 20
+	// maxArchiveRecord bounds a single record, a chunk of at most 1000
+	// samples being far smaller.
+	maxArchiveRecord = 16 << 20
+)
+
+// archiveFormat picks the format from the format parameter ("ndjson" or
+// "protobuf"), falling back to the given content type, then NDJSON.
+func archiveFormat(param, contentType string) (string, error) {
+	switch param {
+	case "ndjson":
+		return ndjsonType, nil
+	case "protobuf":
+		return protobufType, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown format %q: expected ndjson or protobuf", param)
+	}
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil && mt == protobufType {
+		return protobufType, nil
+	}
+	return ndjsonType, nil
+}
+
+// handleExport serves GET /api/export, which streams the caller's whole
+// tenant as an archive for POST /api/import.
+func (g *Gateway) handleExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+	format, err := archiveFormat(r.URL.Query().Get("format"), "")
+	if err != nil {
+		http.Error(w, "Bad format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), archiveTimeout)
+	defer cancel()
+
+	stream, err := g.client.ExportTenant(ctx, &pb.ExportRequest{UserId: userID})
+	if err != nil {
+		slog.Error("ExportTenant gRPC failed", "error", err)
+		http.Error(w, "Failed to export", http.StatusInternalServerError)
+		return
+	}
+	// As for queries, wait for the first record so an export that fails
+	// outright still gets an error status.
+	rec, err := stream.Recv()
+	if err != nil && err != io.EOF {
+		slog.Error("ExportTenant gRPC failed", "error", err)
+		http.Error(w, "Failed to export", http.StatusInternalServerError)
+		return
+	}
+
+	ext := "ndjson"
+	if format == protobufType {
+		ext = "pb"
+	}
+	w.Header().Set("Content-Type", format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pmts-export-%d.%s"`, userID, ext))
+	bw := bufio.NewWriter(w)
+	records := 0
+	for ; err == nil; rec, err = stream.Recv() {
+		if format == protobufType {
+			_, err = protodelim.MarshalTo(bw, rec)
+		} else {
+			var line []byte
+			if line, err = protojson.Marshal(rec); err == nil {
+				bw.Write(line)
+				err = bw.WriteByte('\n')
+			}
+		}
+		if err != nil {
+			break
+		}
+		records++
+	}
+	if err != io.EOF {
+		// Headers are gone by now, so the truncated archive is all the
+		// client gets; importing it fails on the last record or leaves
+		// part of the tenant behind.
+		slog.Error("Export interrupted", "error", err, "user_id", userID, "records", records)
+	}
+	bw.Flush()
+}
+
+// importResponse is the body POST /api/import returns.
+type importResponse struct {
+	Records          int    `json:"records"`
+	StoredSamples    int64  `json:"stored_samples"`
+	DuplicateSamples int64  `json:"duplicate_samples"`
+	RulesCreated     int32  `json:"rules_created"`
+	RulesReplaced    int32  `json:"rules_replaced"`
+	RulesSkipped     int32  `json:"rules_skipped"`
+	MetadataSet      int32  `json:"metadata_set"`
+	MetadataSkipped  int32  `json:"metadata_skipped"`
+	Error            string `json:"error,omitempty"`
+}
+
+var importConflicts = map[string]pb.ImportRequest_Conflict{
+	"":              pb.ImportRequest_KEEP_EXISTING,
+	"keep_existing": pb.ImportRequest_KEEP_EXISTING,
+	"overwrite":     pb.ImportRequest_OVERWRITE,
+	"reject":        pb.ImportRequest_REJECT,
+}
+
+// handleImport serves POST /api/import, which restores an archive from
+// GET /api/export into the caller's tenant, whoever it was exported from.
+// conflict says what happens to data the tenant already has: keep_existing
+// (the default), overwrite, or reject, which stops at the first conflict
+// with 409 and leaves what came before it imported. The body's format is
+// taken from format or else the Content-Type.
+func (g *Gateway) handleImport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	conflict, ok := importConflicts[q.Get("conflict")]
+	if !ok {
+		http.Error(w, "Invalid conflict: expected keep_existing, overwrite or reject", http.StatusBadRequest)
+		return
+	}
+	format, err := archiveFormat(q.Get("format"), r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, "Bad format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), archiveTimeout)
+	defer cancel()
+
+	stream, err := g.client.ImportTenant(ctx)
+	if err != nil {
+		slog.Error("ImportTenant gRPC failed", "error", err)
+		http.Error(w, "Failed to import", http.StatusInternalServerError)
+		return
+	}
+	read := archiveReader(bufio.NewReader(r.Body), format)
+	req := &pb.ImportRequest{UserId: userID, Conflict: conflict}
+	size, records := 0, 0
+	var readErr error
+	for {
+		rec, err := read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readErr = fmt.Errorf("record %d: %w", records+1, err)
+			break
+		}
+		records++
+		req.Records = append(req.Records, rec)
+		size += proto.Size(rec)
+		if size < importMessageBytes {
+			continue
+		}
+		// Storage closes the stream early when it stops at a conflict;
+		// CloseAndRecv below then picks up its response.
+		if err := stream.Send(req); err != nil {
+			break
+		}
+		req, size = &pb.ImportRequest{}, 0
+	}
+	if readErr == nil && len(req.Records) > 0 {
+		stream.Send(req)
+	}
+	if readErr != nil {
+		// Abandon the import; whatever storage already received stays.
+		cancel()
+		http.Error(w, "Bad archive: "+readErr.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		slog.Error("ImportTenant gRPC failed", "error", err)
+		http.Error(w, "Failed to import", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Error != "" {
+		w.WriteHeader(http.StatusConflict)
+	}
+	json.NewEncoder(w).Encode(importResponse{
+		Records:          records,
+		StoredSamples:    resp.StoredSamples,
+		DuplicateSamples: resp.DuplicateSamples,
+		RulesCreated:     resp.RulesCreated,
+		RulesReplaced:    resp.RulesReplaced,
+		RulesSkipped:     resp.RulesSkipped,
+		MetadataSet:      resp.MetadataSet,
+		MetadataSkipped:  resp.MetadataSkipped,
+		Error:            resp.Error,
+	})
+}
+
+// archiveReader returns a function reading one record at a time from an
+// archive in the given format, and io.EOF at its end.
+func archiveReader(br *bufio.Reader, format string) func() (*pb.ArchiveRecord, error) {
+	if format == protobufType {
+		opts := protodelim.UnmarshalOptions{MaxSize: maxArchiveRecord}
+		return func() (*pb.ArchiveRecord, error) {
+			rec := &pb.ArchiveRecord{}
+			if err := opts.UnmarshalFrom(br, rec); err != nil {
+				if errors.Is(err, io.ErrUnexpectedEOF) {
+					return nil, errors.New("truncated record")
+				}
+				return nil, err
+			}
+			if rec.Record == nil {
+				return nil, errors.New("empty record")
+			}
+			return rec, nil
+		}
+	}
+	sc := bufio.NewScanner(br)
+	sc.Buffer(make([]byte, 0, 64<<10), maxArchiveRecord)
+	return func() (*pb.ArchiveRecord, error) {
+		for sc.Scan() {
+			if len(sc.Bytes()) == 0 {
+				continue
+			}
+			rec := &pb.ArchiveRecord{}
+			if err := protojson.Unmarshal(sc.Bytes(), rec); err != nil {
+				return nil, err
+			}
+			if rec.Record == nil {
+				return nil, errors.New("empty record")
+			}
+			return rec, nil
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
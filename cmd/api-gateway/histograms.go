@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// histogramPayload is a histogram sample in an ingest payload. Counts are
+// per bucket, not cumulative: counts[i] observations fell between
+// bounds[i-1] and bounds[i]. Count includes observations above the last
+// bound, so Prometheus' +Inf bucket needs no bound of its own.
+type histogramPayload struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+func (h *histogramPayload) validate() error {
+	if len(h.Bounds) != len(h.Counts) {
+		return fmt.Errorf("%d bounds but %d counts", len(h.Bounds), len(h.Counts))
+	}
+	var n uint64
+	for i, b := range h.Bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) || (i > 0 && b <= h.Bounds[i-1]) {
+			return fmt.Errorf("bounds must be finite and increasing")
+		}
+		n += h.Counts[i]
+	}
+	if n > h.Count {
+		return fmt.Errorf("buckets hold %d observations but count is %d", n, h.Count)
+	}
+	return nil
+}
+
+// handleQuantiles serves GET /api/histograms/quantiles. It takes name,
+// match, from, to and step like /api/metrics, plus q, a comma-separated
+// list of quantiles, and returns one series per histogram series and
+// quantile in the same JSON form.
+func (g *Gateway) handleQuantiles(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	req := &pb.QuantileRequest{UserId: userID}
+	if v := q.Get("from"); v != "" {
+		req.StartTime, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v := q.Get("to"); v != "" {
+		req.EndTime, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v := q.Get("step"); v != "" {
+		step, err := parseStep(v)
+		if err != nil {
+			http.Error(w, "Invalid step", http.StatusBadRequest)
+			return
+		}
+		if step > 0 && req.StartTime <= 0 {
+			http.Error(w, "A step needs from", http.StatusBadRequest)
+			return
+		}
+		req.Step = step
+	}
+	if v := q.Get("q"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			quantile, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || !(quantile >= 0 && quantile <= 1) {
+				http.Error(w, "Invalid q: expected quantiles between 0 and 1", http.StatusBadRequest)
+				return
+			}
+			req.Quantiles = append(req.Quantiles, quantile)
+		}
+	}
+	name, matchers, err := parseMatchParams(q.Get("name"), q.Get("match"))
+	if err != nil {
+		http.Error(w, "Bad selector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.MatchName, req.Matchers = name, matchers
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := g.client.QueryQuantiles(ctx, req)
+	if err != nil {
+		slog.Error("QueryQuantiles gRPC failed", "error", err)
+		http.Error(w, "Failed to estimate quantiles", http.StatusInternalServerError)
+		return
+	}
+	out := make([]jsonMetric, 0, len(resp.List))
+	for _, ts := range resp.List {
+		out = append(out, toJSONMetric(ts))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
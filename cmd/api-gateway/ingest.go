@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// acceptanceWindow bounds the sample timestamps ingest accepts: more than
+// maxAge in the past or maxFuture ahead of now is rejected, which catches
+// misconfigured clocks before their data lands years away from the rest.
+// A zero bound is not enforced. The storage service applies the same
+// window, read from the same variables.
+type acceptanceWindow struct {
+	maxAge    time.Duration
+	maxFuture time.Duration
+}
+
+func windowFromEnv() acceptanceWindow {
+	return acceptanceWindow{
+		maxAge:    envDuration("INGEST_MAX_AGE", 24*time.Hour),
+		maxFuture: envDuration("INGEST_MAX_FUTURE_SKEW", 10*time.Minute),
+	}
+}
+
+// check returns why ts falls outside the window, or "" if it doesn't.
+func (w acceptanceWindow) check(ts int64, now time.Time) string {
+	t := time.Unix(ts, 0)
+	if w.maxAge > 0 && t.Before(now.Add(-w.maxAge)) {
+		return "too_old"
+	}
+	if w.maxFuture > 0 && t.After(now.Add(w.maxFuture)) {
+		return "too_far_in_future"
+	}
+	return ""
+}
+
+// envDuration reads a Go duration such as "36h" from the environment,
+// falling back to def when the variable is unset or invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return def
+}
+
+// maxReportedRejections caps the rejections listed in an ingest response;
+// the count covers all of them.
+const maxReportedRejections = 100
+
+// ingestResponse tells a client what became of its samples. Accepted ones
+// are queued for storage, which may still drop duplicates.
+type ingestResponse struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+	// Limited counts the rejections for exceeding the user's limits.
+	Limited    int               `json:"limited,omitempty"`
+	Rejections []ingestRejection `json:"rejections,omitempty"`
+
+	// retryAt is when to try again after a limit was hit: when the daily
+	// sample limit resets, or a second on for the ingest rate.
+	retryAt int64
+}
+
+type ingestRejection struct {
+	// Index is the sample's position in the request body.
+	Index     int    `json:"index"`
+	Name      string `json:"name"`
+	Timestamp int64  `json:"timestamp"`
+	Reason    string `json:"reason"`
+}
+
+func (r *ingestResponse) reject(index int, name string, ts int64, reason string) {
+	r.Rejected++
+	if len(r.Rejections) < maxReportedRejections {
+		r.Rejections = append(r.Rejections, ingestRejection{Index: index, Name: name, Timestamp: ts, Reason: reason})
+	}
+}
+
+func (r *ingestResponse) limit(index int, name string, ts int64, reason string) {
+	r.Limited++
+	r.reject(index, name, ts, reason)
+}
+
+// write sends the response: 202 if anything was accepted, 429 if every
+// sample was rejected for a limit, and 422 if otherwise rejected. A 429
+// for the daily sample limit or the ingest rate says when to retry.
+func (r *ingestResponse) write(w http.ResponseWriter) {
+	status := http.StatusAccepted
+	switch {
+	case r.Accepted > 0:
+	case r.Limited > 0 && r.Limited == r.Rejected:
+		status = http.StatusTooManyRequests
+		if r.retryAt > 0 {
+			w.Header().Set("Retry-After", strconv.FormatInt(max(r.retryAt-time.Now().Unix(), 1), 10))
+		}
+	default:
+		status = http.StatusUnprocessableEntity
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(r)
+}
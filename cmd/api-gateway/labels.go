@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// labelScope holds the optional parameters that narrow label discovery:
+// name, match, from and to, read the same way as for /api/metrics.
+type labelScope struct {
+	name     string
+	matchers []*pb.LabelMatcher
+	start    int64
+	end      int64
+}
+
+func parseLabelScope(r *http.Request) (labelScope, error) {
+	q := r.URL.Query()
+	var scope labelScope
+	var err error
+	scope.name, scope.matchers, err = parseMatchParams(q.Get("name"), q.Get("match"))
+	if err != nil {
+		return scope, err
+	}
+	if v := q.Get("from"); v != "" {
+		scope.start, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v := q.Get("to"); v != "" {
+		scope.end, _ = strconv.ParseInt(v, 10, 64)
+	}
+	return scope, nil
+}
+
+// handleLabelNames serves GET /api/labels.
+func (g *Gateway) handleLabelNames(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+	scope, err := parseLabelScope(r)
+	if err != nil {
+		http.Error(w, "Bad selector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp, err := g.client.ListLabelNames(ctx, &pb.LabelNamesRequest{
+		UserId:    userID,
+		MatchName: scope.name,
+		Matchers:  scope.matchers,
+		StartTime: scope.start,
+		EndTime:   scope.end,
+	})
+	if err != nil {
+		slog.Error("ListLabelNames gRPC failed", "error", err)
+		http.Error(w, "Failed to list labels", http.StatusInternalServerError)
+		return
+	}
+	writeStrings(w, resp.Names)
+}
+
+// handleLabelValues serves GET /api/labels/{name}/values.
+func (g *Gateway) handleLabelValues(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+	scope, err := parseLabelScope(r)
+	if err != nil {
+		http.Error(w, "Bad selector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp, err := g.client.ListLabelValues(ctx, &pb.LabelValuesRequest{
+		UserId:    userID,
+		LabelName: r.PathValue("name"),
+		MatchName: scope.name,
+		Matchers:  scope.matchers,
+		StartTime: scope.start,
+		EndTime:   scope.end,
+	})
+	if err != nil {
+		slog.Error("ListLabelValues gRPC failed", "error", err)
+		http.Error(w, "Failed to list label values", http.StatusInternalServerError)
+		return
+	}
+	writeStrings(w, resp.Values)
+}
+
+// writeStrings encodes a list as a JSON array, never null.
+func writeStrings(w http.ResponseWriter, list []string) {
+	if list == nil {
+		list = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
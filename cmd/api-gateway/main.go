@@ -11,21 +11,26 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/rs/cors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/proto"
 
 	pb "pmts/proto"
+	"pmts/stream"
 )
 
 type Gateway struct {
 	client pb.MonitoringServiceClient
-	nc     *nats.Conn
+	js     jetstream.JetStream
+	window acceptanceWindow
+	quotas *quotaCache
 }
 
 func main() {
@@ -56,16 +61,35 @@ func main() {
 		os.Exit(1)
 	}
 	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		logger.Error("Failed to open JetStream", "error", err)
+		os.Exit(1)
+	}
+	if _, err := stream.Ensure(context.Background(), js); err != nil {
+		logger.Error("Failed to create stream", "error", err)
+		os.Exit(1)
+	}
 
-	gw := &Gateway{client: client, nc: nc}
+	gw := &Gateway{client: client, js: js, window: windowFromEnv(), quotas: newQuotaCache(client)}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/health", gw.handleHealth)
 	mux.HandleFunc("/api/metrics", gw.handleGetMetrics)
 	mux.HandleFunc("/api/metrics/names", gw.handleMetricNames)
+	mux.HandleFunc("DELETE /api/series", gw.handleDeleteSeries)
+	mux.HandleFunc("GET /api/export", gw.handleExport)
+	mux.HandleFunc("POST /api/import", gw.handleImport)
+	mux.HandleFunc("GET /api/labels", gw.handleLabelNames)
+	mux.HandleFunc("GET /api/labels/{name}/values", gw.handleLabelValues)
+	mux.HandleFunc("GET /api/stats", gw.handleStats)
+	mux.HandleFunc("GET /api/usage", gw.handleUsage)
+	mux.HandleFunc("GET /api/metadata", gw.handleMetadata)
+	mux.HandleFunc("GET /api/histograms/quantiles", gw.handleQuantiles)
 	mux.HandleFunc("/api/ingest", gw.handleIngest)
 	mux.HandleFunc("/api/register", gw.handleRegister)
 	mux.HandleFunc("/api/rules", gw.handleRules)
+	mux.HandleFunc("/api/retention", gw.handleRetention)
 	mux.HandleFunc("/metrics/demo", gw.handleDemoMetrics)
 
 	c := cors.New(cors.Options{
@@ -106,6 +130,7 @@ func (g *Gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
 func (g *Gateway) handleDemoMetrics(w http.ResponseWriter, r *http.Request) {
 	val := rand.Float64() * 100
 	w.Write([]byte("# HELP platform_go_cpu Simulated CPU usage\n"))
+	w.Write([]byte("# TYPE platform_go_cpu gauge\n"))
 	fmt.Fprintf(w, "platform_go_cpu %f\n", val)
 }
 
@@ -136,17 +161,26 @@ func (g *Gateway) handleIngest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Type, unit and help are optional metadata about the metric; they
+	// need not be repeated on every sample.
 	type AgentPayload struct {
 		Name      string            `json:"name"`
 		Value     float64           `json:"value"`
 		Timestamp int64             `json:"timestamp"`
 		Labels    map[string]string `json:"labels"`
-	}
-
-	buildTS := func(p AgentPayload) *pb.TimeSeries {
-		ts := p.Timestamp
-		if ts == 0 {
-			ts = time.Now().Unix()
+		Type      string            `json:"type,omitempty"`
+		Unit      string            `json:"unit,omitempty"`
+		Help      string            `json:"help,omitempty"`
+		// Histogram, when set, is sent instead of value.
+		Histogram *histogramPayload `json:"histogram,omitempty"`
+	}
+
+	buildTS := func(p AgentPayload, ts int64) *pb.TimeSeries {
+		if h := p.Histogram; h != nil {
+			return &pb.TimeSeries{
+				Metric:     &pb.Metric{Name: p.Name, Labels: p.Labels},
+				Histograms: []*pb.HistogramSample{{Timestamp: ts, Bounds: h.Bounds, Counts: h.Counts, Sum: h.Sum, Count: h.Count}},
+			}
 		}
 		return &pb.TimeSeries{
 			Metric:  &pb.Metric{Name: p.Name, Labels: p.Labels},
@@ -177,23 +211,79 @@ func (g *Gateway) handleIngest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var list []*pb.TimeSeries
-	for _, p := range payloads {
-		list = append(list, buildTS(p))
+	// index is each series' position in payloads.
+	var index []int
+	var resp ingestResponse
+	metadata := make(map[string]*pb.MetricMetadata)
+	now := time.Now()
+	for i, p := range payloads {
+		if p.Histogram != nil {
+			if err := p.Histogram.validate(); err != nil {
+				http.Error(w, "Invalid histogram for "+strconv.Quote(p.Name)+": "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		ts := p.Timestamp
+		if ts == 0 {
+			ts = now.Unix()
+		}
+		if reason := g.window.check(ts, now); reason != "" {
+			resp.reject(i, p.Name, ts, reason)
+		} else {
+			list = append(list, buildTS(p, ts))
+			index = append(index, i)
+		}
+		if p.Type == "" && p.Unit == "" && p.Help == "" {
+			continue
+		}
+		typ, ok := parseMetricType(p.Type)
+		if !ok {
+			http.Error(w, "Unknown metric type "+strconv.Quote(p.Type)+": expected counter, gauge, histogram or summary", http.StatusBadRequest)
+			return
+		}
+		md, ok := metadata[p.Name]
+		if !ok {
+			md = &pb.MetricMetadata{MetricName: p.Name}
+			metadata[p.Name] = md
+		}
+		if typ != pb.MetricMetadata_UNKNOWN {
+			md.Type = typ
+		}
+		if p.Unit != "" {
+			md.Unit = p.Unit
+		}
+		if p.Help != "" {
+			md.Help = p.Help
+		}
 	}
 
+	if len(list) > 0 {
+		list, index = g.applyLimits(r.Context(), userID, list, index, &resp)
+	}
+	resp.Accepted = len(list)
+	if len(list) == 0 && len(metadata) == 0 {
+		resp.write(w)
+		return
+	}
 	pbReq := &pb.UploadRequest{UserId: userID, List: list}
+	for _, md := range metadata {
+		pbReq.Metadata = append(pbReq.Metadata, md)
+	}
 	data, err := proto.Marshal(pbReq)
 	if err != nil {
 		http.Error(w, "Internal error", http.StatusInternalServerError)
 		return
 	}
-	if err := g.nc.Publish("metrics.upload", data); err != nil {
+	// Wait for the stream to store the batch, so an accepted batch is never
+	// lost if a worker is down.
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := g.js.Publish(ctx, stream.UploadSubject, data); err != nil {
 		slog.Error("Failed to publish to NATS", "error", err)
 		http.Error(w, "Queue error", http.StatusServiceUnavailable)
 		return
 	}
-	w.WriteHeader(http.StatusAccepted)
-	w.Write([]byte("Accepted"))
+	resp.write(w)
 }
 
 func (g *Gateway) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
@@ -217,38 +307,98 @@ func (g *Gateway) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
 	if v := q.Get("to"); v != "" {
 		req.EndTime, _ = strconv.ParseInt(v, 10, 64)
 	}
+	if v := q.Get("step"); v != "" {
+		step, err := parseStep(v)
+		if err != nil {
+			http.Error(w, "Invalid step", http.StatusBadRequest)
+			return
+		}
+		req.Step = step
+	}
+	if v := q.Get("max_points"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid max_points", http.StatusBadRequest)
+			return
+		}
+		req.MaxPoints = int32(n)
+	}
+	if v := q.Get("agg"); v != "" {
+		agg, ok := pb.GetMetricsRequest_Aggregation_value[strings.ToUpper(v)]
+		if !ok {
+			http.Error(w, "Invalid agg: expected avg, min, max, sum, count or last", http.StatusBadRequest)
+			return
+		}
+		req.Aggregation = pb.GetMetricsRequest_Aggregation(agg)
+	}
+	name, matchers, err := parseMatchParams(req.MatchName, q.Get("match"))
+	if err != nil {
+		http.Error(w, "Bad selector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.MatchName, req.Matchers = name, matchers
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	// Results are streamed from storage and written out as they arrive, so
+	// long ranges never have to fit in memory here or in one gRPC message.
+	// That also makes them slower to finish, hence the longer timeout.
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
 	defer cancel()
 
-	resp, err := g.client.GetMetrics(ctx, req)
+	stream, err := g.client.StreamMetrics(ctx, req)
 	if err != nil {
-		slog.Error("GetMetrics gRPC failed", "error", err)
+		slog.Error("StreamMetrics gRPC failed", "error", err)
+		http.Error(w, "Failed to fetch metrics", http.StatusInternalServerError)
+		return
+	}
+	// Wait for the first message before writing anything, so a query that
+	// fails outright still gets an error status.
+	resp, err := stream.Recv()
+	if err != nil && err != io.EOF {
+		slog.Error("StreamMetrics gRPC failed", "error", err)
 		http.Error(w, "Failed to fetch metrics", http.StatusInternalServerError)
 		return
 	}
 
-	type JSONSample struct {
-		T int64   `json:"t"`
-		V float64 `json:"v"`
+	ndjson := q.Get("format") == "ndjson" || strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+	out := newSeriesWriter(w, ndjson)
+	for err == nil {
+		for _, ts := range resp.List {
+			if err := out.writeChunk(ts); err != nil {
+				slog.Error("Failed to write metrics", "error", err)
+				out.fail("Failed to write metrics")
+				return
+			}
+		}
+		resp, err = stream.Recv()
 	}
-	type JSONMetric struct {
-		Name    string       `json:"name"`
-		Samples []JSONSample `json:"samples"`
+	if err != io.EOF {
+		slog.Error("StreamMetrics gRPC failed", "error", err)
+		out.fail("Failed to fetch metrics")
+		return
 	}
+	out.close()
+}
 
-	var result []JSONMetric
-	for _, ts := range resp.List {
-		jm := JSONMetric{Name: ts.Metric.Name}
-		for _, s := range ts.Samples {
-			jm.Samples = append(jm.Samples, JSONSample{T: s.Timestamp, V: s.Value})
+// parseStep accepts either whole seconds ("300") or a Go duration ("5m").
+func parseStep(v string) (int64, error) {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("negative step")
 		}
-		result = append(result, jm)
+		return n, nil
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative step")
+	}
+	return int64(d / time.Second), nil
 }
 
+// handleDeleteMetric serves DELETE /api/metrics, which forgets the named
+// metric: its samples, metadata and the alert rules that watch it.
 func (g *Gateway) handleDeleteMetric(w http.ResponseWriter, r *http.Request) {
 	userID, ok := g.verifyKey(r, w)
 	if !ok {
@@ -411,3 +561,109 @@ func (g *Gateway) handleRules(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
+
+func (g *Gateway) handleRetention(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		resp, err := g.client.GetRetentionPolicies(ctx, &pb.GetPoliciesRequest{UserId: userID})
+		if err != nil {
+			slog.Error("GetRetentionPolicies gRPC failed", "error", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		type PolicyJSON struct {
+			ID            int64  `json:"id"`
+			MetricName    string `json:"metric,omitempty"`
+			Selector      string `json:"selector,omitempty"`
+			RetentionDays int32  `json:"retention_days"`
+		}
+		policies := []PolicyJSON{}
+		for _, p := range resp.Policies {
+			policies = append(policies, PolicyJSON{
+				ID:            p.PolicyId,
+				MetricName:    p.MetricName,
+				Selector:      formatSelector("", p.Matchers),
+				RetentionDays: p.RetentionDays,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(policies)
+
+	case http.MethodPost:
+		var payload struct {
+			Metric        string `json:"metric"`
+			Selector      string `json:"selector"`
+			RetentionDays int32  `json:"retention_days"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Bad JSON", http.StatusBadRequest)
+			return
+		}
+		if payload.RetentionDays <= 0 {
+			http.Error(w, "retention_days must be positive", http.StatusBadRequest)
+			return
+		}
+		req := &pb.CreatePolicyRequest{
+			UserId:        userID,
+			MetricName:    payload.Metric,
+			RetentionDays: payload.RetentionDays,
+		}
+		if payload.Selector != "" {
+			name, matchers, err := parseSelector(payload.Selector)
+			if err != nil {
+				http.Error(w, "Bad selector: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if name != "" {
+				if req.MetricName != "" && req.MetricName != name {
+					http.Error(w, "Selector metric name conflicts with metric", http.StatusBadRequest)
+					return
+				}
+				req.MetricName = name
+			}
+			req.Matchers = matchers
+		}
+		resp, err := g.client.CreateRetentionPolicy(ctx, req)
+		if err != nil {
+			slog.Error("CreateRetentionPolicy gRPC failed", "error", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]int64{"id": resp.PolicyId})
+
+	case http.MethodDelete:
+		idStr := r.URL.Query().Get("id")
+		if idStr == "" {
+			http.Error(w, "Missing policy id", http.StatusBadRequest)
+			return
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid id", http.StatusBadRequest)
+			return
+		}
+		resp, err := g.client.DeleteRetentionPolicy(ctx, &pb.DeletePolicyRequest{
+			PolicyId: id,
+			UserId:   userID,
+		})
+		if err != nil || !resp.Ok {
+			http.Error(w, "Delete failed", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Deleted"))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// parseMetricType accepts a metric type by name, case-insensitively. An
+// empty name is UNKNOWN.
+func parseMetricType(name string) (pb.MetricMetadata_Type, bool) {
+	if name == "" {
+		return pb.MetricMetadata_UNKNOWN, true
+	}
+	v, ok := pb.MetricMetadata_Type_value[strings.ToUpper(name)]
+	return pb.MetricMetadata_Type(v), ok
+}
+
+// handleMetadata serves GET /api/metadata, optionally for a single metric
+// given by name.
+func (g *Gateway) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp, err := g.client.GetMetadata(ctx, &pb.GetMetadataRequest{UserId: userID, MetricName: r.URL.Query().Get("name")})
+	if err != nil {
+		slog.Error("GetMetadata gRPC failed", "error", err)
+		http.Error(w, "Failed to fetch metadata", http.StatusInternalServerError)
+		return
+	}
+
+	type MetadataJSON struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		Unit string `json:"unit,omitempty"`
+		Help string `json:"help,omitempty"`
+	}
+	list := []MetadataJSON{}
+	for _, md := range resp.Metadata {
+		list = append(list, MetadataJSON{
+			Name: md.MetricName,
+			Type: strings.ToLower(md.Type.String()),
+			Unit: md.Unit,
+			Help: md.Help,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
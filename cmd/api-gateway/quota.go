@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// Why a sample was refused for a limit; the storage service drops samples
+// for the same reasons.
+const (
+	limitTooManyLabels    = "too_many_labels"
+	limitLabelValueLength = "label_value_too_long"
+	limitSeries           = "series_limit_exceeded"
+	limitSamplesPerDay    = "daily_sample_limit_exceeded"
+	limitIngestRate       = "ingest_rate_exceeded"
+)
+
+// rateBurstSeconds is how many seconds' worth of max_samples_per_second a
+// user may send at once; storage allows the same.
+const rateBurstSeconds = 10
+
+// quotaCache keeps each user's limits and usage from the storage service
+// for QUOTA_CACHE_TTL (default 10s), so ingest can turn away what is over
+// them without a round trip per request. Storage enforces the limits again
+// on write; checking here is what lets a client be told. The exception is
+// the ingest rate, which storage cannot meter on batches it reads from the
+// queue later, so it is metered here alone; each gateway replica allows a
+// user the full rate.
+type quotaCache struct {
+	client pb.MonitoringServiceClient
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	users map[int64]*cachedQuota
+	rates map[int64]*tokenBucket
+}
+
+type cachedQuota struct {
+	resp    *pb.QuotaResponse
+	fetched time.Time
+	// accepted counts the samples let through that storage had not counted
+	// when resp was fetched, as they may still be queued.
+	accepted int64
+}
+
+// tokenBucket meters a rate: it holds up to a burst of tokens, refilled
+// continuously, and each sample takes one.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take removes up to n tokens from a bucket refilled at rate a second up
+// to burst, returning how many it got. A new bucket starts full.
+func (b *tokenBucket) take(n int64, rate, burst float64, now time.Time) int64 {
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(burst, b.tokens+elapsed*rate)
+	}
+	b.last = now
+	got := min(n, int64(b.tokens))
+	b.tokens -= float64(got)
+	return got
+}
+
+func newQuotaCache(client pb.MonitoringServiceClient) *quotaCache {
+	return &quotaCache{
+		client: client,
+		ttl:    envDuration("QUOTA_CACHE_TTL", 10*time.Second),
+		users:  make(map[int64]*cachedQuota),
+		rates:  make(map[int64]*tokenBucket),
+	}
+}
+
+// get returns userID's cached quota, fetching it when stale or past the
+// daily reset.
+func (c *quotaCache) get(ctx context.Context, userID int64, now time.Time) (*cachedQuota, error) {
+	c.mu.Lock()
+	q, ok := c.users[userID]
+	c.mu.Unlock()
+	if ok && now.Sub(q.fetched) < c.ttl && now.Unix() < q.resp.ResetsAt {
+		return q, nil
+	}
+	resp, err := c.query(ctx, &pb.QuotaRequest{UserId: userID})
+	if err != nil {
+		return nil, err
+	}
+	fresh := &cachedQuota{resp: resp, fetched: now}
+	c.mu.Lock()
+	// What was let through before stays pending until storage's count has
+	// grown by as much, since it may still be queued.
+	if q, ok := c.users[userID]; ok && q.resp.ResetsAt == resp.ResetsAt {
+		fresh.accepted = max(q.accepted-max(resp.SamplesToday-q.resp.SamplesToday, 0), 0)
+	}
+	c.users[userID] = fresh
+	c.mu.Unlock()
+	return fresh, nil
+}
+
+// query asks storage for a quota, bypassing the cache.
+func (c *quotaCache) query(ctx context.Context, req *pb.QuotaRequest) (*pb.QuotaResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	return c.client.GetQuota(ctx, req)
+}
+
+// applyLimits removes from list the series and samples that would take
+// userID over its limits, recording each in resp. index gives each
+// series' position in the request body and is filtered alongside. Should
+// the quota be unavailable, everything is let through for storage to check.
+func (g *Gateway) applyLimits(ctx context.Context, userID int64, list []*pb.TimeSeries, index []int, resp *ingestResponse) ([]*pb.TimeSeries, []int) {
+	now := time.Now()
+	q, err := g.quotas.get(ctx, userID, now)
+	if err != nil {
+		slog.Warn("Quota unavailable, skipping limit checks", "error", err, "user_id", userID)
+		return list, index
+	}
+	limits := q.resp.Limits
+	keep := func(fn func(i int, ts *pb.TimeSeries) string) {
+		n := 0
+		for i, ts := range list {
+			if reason := fn(i, ts); reason != "" {
+				resp.limit(index[i], ts.Metric.Name, firstTimestamp(ts), reason)
+				continue
+			}
+			list[n], index[n] = ts, index[i]
+			n++
+		}
+		list, index = list[:n], index[:n]
+	}
+
+	keep(func(_ int, ts *pb.TimeSeries) string {
+		return checkLabels(limits, ts.Metric)
+	})
+
+	// The batch can only add as many series as it has entries, so storage
+	// need only be asked which are new beyond the limit near it.
+	if limits.GetMaxSeries() > 0 && q.resp.Series+int64(len(list)) > limits.MaxSeries {
+		req := &pb.QuotaRequest{UserId: userID}
+		for _, ts := range list {
+			req.Series = append(req.Series, ts.Metric)
+		}
+		fresh, err := g.quotas.query(ctx, req)
+		if err != nil {
+			slog.Warn("Quota unavailable, skipping series limit", "error", err, "user_id", userID)
+		} else {
+			over := make(map[int]bool, len(fresh.OverSeries))
+			for _, i := range fresh.OverSeries {
+				over[int(i)] = true
+			}
+			keep(func(i int, _ *pb.TimeSeries) string {
+				if over[i] {
+					return limitSeries
+				}
+				return ""
+			})
+		}
+	}
+
+	// Entries hold one sample each.
+	g.quotas.mu.Lock()
+	defer g.quotas.mu.Unlock()
+	if limit := limits.GetMaxSamplesPerDay(); limit > 0 {
+		left := limit - q.resp.SamplesToday - q.accepted
+		keep(func(i int, _ *pb.TimeSeries) string {
+			if int64(i) >= left {
+				resp.retryAt = q.resp.ResetsAt
+				return limitSamplesPerDay
+			}
+			return ""
+		})
+	}
+	if rate := limits.GetMaxSamplesPerSecond(); rate > 0 {
+		b, ok := g.quotas.rates[userID]
+		if !ok {
+			b = &tokenBucket{}
+			g.quotas.rates[userID] = b
+		}
+		got := b.take(int64(len(list)), float64(rate), float64(rate*rateBurstSeconds), now)
+		keep(func(i int, _ *pb.TimeSeries) string {
+			if int64(i) >= got {
+				resp.retryAt = max(resp.retryAt, now.Unix()+1)
+				return limitIngestRate
+			}
+			return ""
+		})
+	}
+	q.accepted += int64(len(list))
+	return list, index
+}
+
+// checkLabels returns which limit metric's labels break, or "".
+func checkLabels(limits *pb.TenantLimits, metric *pb.Metric) string {
+	if n := limits.GetMaxLabelsPerSeries(); n > 0 && len(metric.Labels) > int(n) {
+		return limitTooManyLabels
+	}
+	if n := limits.GetMaxLabelValueLength(); n > 0 {
+		for _, v := range metric.Labels {
+			if len(v) > int(n) {
+				return limitLabelValueLength
+			}
+		}
+	}
+	return ""
+}
+
+func firstTimestamp(ts *pb.TimeSeries) int64 {
+	if len(ts.Samples) > 0 {
+		return ts.Samples[0].Timestamp
+	}
+	if len(ts.Histograms) > 0 {
+		return ts.Histograms[0].Timestamp
+	}
+	return 0
+}
+
+// handleUsage serves GET /api/usage: the caller's limits next to what it
+// has used of them today. A zero limit is unlimited. samples_today counts
+// samples as they were accepted, before duplicates of stored samples were
+// dropped, so resending a batch uses up max_samples_per_day again.
+// limited_today counts only what storage dropped, such as from bulk
+// uploads and imports; ingest turns most samples over a limit away before
+// they get that far.
+func (g *Gateway) handleUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+	resp, err := g.quotas.query(r.Context(), &pb.QuotaRequest{UserId: userID})
+	if err != nil {
+		slog.Error("GetQuota gRPC failed", "error", err)
+		http.Error(w, "Failed to fetch usage", http.StatusInternalServerError)
+		return
+	}
+
+	type LimitsJSON struct {
+		MaxSeries           int64 `json:"max_series"`
+		MaxSamplesPerDay    int64 `json:"max_samples_per_day"`
+		MaxLabelsPerSeries  int32 `json:"max_labels_per_series"`
+		MaxLabelValueLength int32 `json:"max_label_value_length"`
+		MaxSamplesPerSecond int64 `json:"max_samples_per_second"`
+	}
+	type UsageJSON struct {
+		Series       int64 `json:"series"`
+		SamplesToday int64 `json:"samples_today"`
+		LimitedToday int64 `json:"limited_today"`
+	}
+	out := struct {
+		Limits   LimitsJSON `json:"limits"`
+		Usage    UsageJSON  `json:"usage"`
+		ResetsAt int64      `json:"resets_at"`
+	}{
+		Limits: LimitsJSON{
+			MaxSeries:           resp.Limits.GetMaxSeries(),
+			MaxSamplesPerDay:    resp.Limits.GetMaxSamplesPerDay(),
+			MaxLabelsPerSeries:  resp.Limits.GetMaxLabelsPerSeries(),
+			MaxLabelValueLength: resp.Limits.GetMaxLabelValueLength(),
+			MaxSamplesPerSecond: resp.Limits.GetMaxSamplesPerSecond(),
+		},
+		Usage: UsageJSON{
+			Series:       resp.Series,
+			SamplesToday: resp.SamplesToday,
+			LimitedToday: resp.LimitedToday,
+		},
+		ResetsAt: resp.ResetsAt,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "pmts/proto"
+)
+
+// fakeQuotaClient answers GetQuota with resp; other calls are not expected.
+type fakeQuotaClient struct {
+	pb.MonitoringServiceClient
+	resp *pb.QuotaResponse
+}
+
+func (c *fakeQuotaClient) GetQuota(ctx context.Context, req *pb.QuotaRequest, opts ...grpc.CallOption) (*pb.QuotaResponse, error) {
+	return c.resp, nil
+}
+
+func oneSampleSeries(n int) ([]*pb.TimeSeries, []int) {
+	list := make([]*pb.TimeSeries, n)
+	index := make([]int, n)
+	for i := range list {
+		list[i] = &pb.TimeSeries{Metric: &pb.Metric{Name: "m"}, Samples: []*pb.Sample{{Timestamp: int64(i), Value: 1}}}
+		index[i] = i
+	}
+	return list, index
+}
+
+func TestApplyLimits(t *testing.T) {
+	resetsAt := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name       string
+		limits     *pb.TenantLimits
+		stored     int64
+		batches    []int
+		accepted   []int
+		wantReason string
+	}{
+		{"unlimited", &pb.TenantLimits{}, 0, []int{50, 50}, []int{50, 50}, ""},
+		{"daily limit", &pb.TenantLimits{MaxSamplesPerDay: 100}, 60, []int{30, 30}, []int{30, 10}, limitSamplesPerDay},
+		{"ingest rate", &pb.TenantLimits{MaxSamplesPerSecond: 2}, 0, []int{15, 15}, []int{15, 5}, limitIngestRate},
+		{"both", &pb.TenantLimits{MaxSamplesPerDay: 18, MaxSamplesPerSecond: 2}, 0, []int{15, 15}, []int{15, 3}, limitSamplesPerDay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeQuotaClient{resp: &pb.QuotaResponse{Limits: tt.limits, SamplesToday: tt.stored, ResetsAt: resetsAt}}
+			g := &Gateway{client: client, quotas: newQuotaCache(client)}
+			var last ingestResponse
+			for i, n := range tt.batches {
+				list, index := oneSampleSeries(n)
+				last = ingestResponse{}
+				list, _ = g.applyLimits(context.Background(), 1, list, index, &last)
+				if len(list) != tt.accepted[i] {
+					t.Errorf("batch %d: accepted %d of %d, want %d", i, len(list), n, tt.accepted[i])
+				}
+			}
+			if tt.wantReason == "" {
+				return
+			}
+			if len(last.Rejections) == 0 || last.Rejections[0].Reason != tt.wantReason {
+				t.Errorf("rejections = %v, want %q", last.Rejections, tt.wantReason)
+			}
+			if last.retryAt == 0 {
+				t.Error("no retry time for a limit that was hit")
+			}
+		})
+	}
+}
+
+func TestQuotaCacheKeepsQueuedSamples(t *testing.T) {
+	ctx := context.Background()
+	resetsAt := time.Now().Add(time.Hour).Unix()
+	client := &fakeQuotaClient{resp: &pb.QuotaResponse{Limits: &pb.TenantLimits{MaxSamplesPerDay: 100}, ResetsAt: resetsAt}}
+	g := &Gateway{client: client, quotas: newQuotaCache(client)}
+	g.quotas.ttl = 0
+	admit := func(n int) int {
+		list, index := oneSampleSeries(n)
+		list, _ = g.applyLimits(ctx, 1, list, index, &ingestResponse{})
+		return len(list)
+	}
+
+	steps := []struct {
+		// stored is what storage reports having counted by the request.
+		stored int64
+		n      int
+		want   int
+	}{
+		{0, 60, 60},
+		// Storage has not caught up, so the 60 are still queued.
+		{0, 60, 40},
+		{0, 10, 0},
+		// Once it has counted them they are not counted twice.
+		{100, 10, 0},
+	}
+	for i, s := range steps {
+		client.resp = &pb.QuotaResponse{Limits: client.resp.Limits, SamplesToday: s.stored, ResetsAt: resetsAt}
+		if got := admit(s.n); got != s.want {
+			t.Errorf("step %d: accepted %d of %d, want %d", i, got, s.n, s.want)
+		}
+	}
+
+	// Partly caught up: 70 of the 100 let through are counted.
+	g = &Gateway{client: client, quotas: newQuotaCache(client)}
+	g.quotas.ttl = 0
+	client.resp = &pb.QuotaResponse{Limits: client.resp.Limits, ResetsAt: resetsAt}
+	admit(100)
+	client.resp = &pb.QuotaResponse{Limits: &pb.TenantLimits{MaxSamplesPerDay: 200}, SamplesToday: 70, ResetsAt: resetsAt}
+	if got := admit(150); got != 100 {
+		t.Errorf("accepted %d with 70 stored and 30 queued of 200, want 100", got)
+	}
+
+	// A new day starts from storage's count alone.
+	client.resp = &pb.QuotaResponse{Limits: client.resp.Limits, ResetsAt: resetsAt + 86400}
+	if got := admit(150); got != 150 {
+		t.Errorf("accepted %d on a new day, want 150", got)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+
+	pb "pmts/proto"
+)
+
+// parseSelector parses a Prometheus-style series selector such as
+// `system_cpu_percent{host=~"web-.*",env!="staging"}`. Both the metric name
+// and the braces are optional, but at least one of them must be present.
+func parseSelector(input string) (string, []*pb.LabelMatcher, error) {
+	p := &selectorParser{input: input}
+	p.skipSpace()
+	name := p.scanWhile(isMetricNameChar)
+	p.skipSpace()
+
+	var matchers []*pb.LabelMatcher
+	if p.peek() == '{' {
+		p.pos++
+		for {
+			p.skipSpace()
+			if p.peek() == '}' {
+				p.pos++
+				break
+			}
+			m, err := p.parseMatcher()
+			if err != nil {
+				return "", nil, err
+			}
+			matchers = append(matchers, m)
+
+			p.skipSpace()
+			switch p.peek() {
+			case ',':
+				p.pos++
+			case '}':
+			default:
+				return "", nil, p.errorf("expected ',' or '}'")
+			}
+		}
+		p.skipSpace()
+	}
+	if p.pos != len(p.input) {
+		return "", nil, p.errorf("unexpected trailing input")
+	}
+	if name == "" && len(matchers) == 0 {
+		return "", nil, fmt.Errorf("empty selector")
+	}
+	return name, matchers, nil
+}
+
+// parseMatchParams combines the name and match query parameters. A metric
+// name in the selector must agree with name when both are given.
+func parseMatchParams(name, match string) (string, []*pb.LabelMatcher, error) {
+	if match == "" {
+		return name, nil, nil
+	}
+	selName, matchers, err := parseSelector(match)
+	if err != nil {
+		return "", nil, err
+	}
+	if selName != "" {
+		if name != "" && name != selName {
+			return "", nil, fmt.Errorf("metric name %q conflicts with name parameter", selName)
+		}
+		name = selName
+	}
+	return name, matchers, nil
+}
+
+type selectorParser struct {
+	input string
+	pos   int
+}
+
+func (p *selectorParser) parseMatcher() (*pb.LabelMatcher, error) {
+	label := p.scanWhile(isLabelNameChar)
+	if label == "" {
+		return nil, p.errorf("expected label name")
+	}
+	p.skipSpace()
+
+	var typ pb.LabelMatcher_Type
+	switch {
+	case strings.HasPrefix(p.input[p.pos:], "=~"):
+		typ, p.pos = pb.LabelMatcher_RE, p.pos+2
+	case strings.HasPrefix(p.input[p.pos:], "!~"):
+		typ, p.pos = pb.LabelMatcher_NRE, p.pos+2
+	case strings.HasPrefix(p.input[p.pos:], "!="):
+		typ, p.pos = pb.LabelMatcher_NEQ, p.pos+2
+	case strings.HasPrefix(p.input[p.pos:], "="):
+		typ, p.pos = pb.LabelMatcher_EQ, p.pos+1
+	default:
+		return nil, p.errorf("expected one of =, !=, =~, !~ after %q", label)
+	}
+	p.skipSpace()
+
+	value, err := p.parseString()
+	if err != nil {
+		return nil, err
+	}
+	if typ == pb.LabelMatcher_RE || typ == pb.LabelMatcher_NRE {
+		if err := checkRegex(value); err != nil {
+			return nil, fmt.Errorf("invalid regex for label %q: %w", label, err)
+		}
+	}
+	return &pb.LabelMatcher{Type: typ, Name: label, Value: value}, nil
+}
+
+// checkRegex accepts the RE2 syntax storage matches with, less the
+// constructs it cannot translate for Postgres: word boundaries and
+// multi-line anchors. Refusing them here makes them a client error.
+func checkRegex(value string) error {
+	if _, err := regexp.Compile(value); err != nil {
+		return err
+	}
+	re, _ := syntax.Parse(value, syntax.Perl)
+	var check func(re *syntax.Regexp) error
+	check = func(re *syntax.Regexp) error {
+		switch re.Op {
+		case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+			return fmt.Errorf("word boundaries are not supported")
+		case syntax.OpBeginLine, syntax.OpEndLine:
+			return fmt.Errorf("multi-line anchors are not supported")
+		}
+		for _, sub := range re.Sub {
+			if err := check(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return check(re)
+}
+
+// parseString reads a double-, single- or back-quoted string literal using
+// Go escaping rules, which match PromQL's.
+func (p *selectorParser) parseString() (string, error) {
+	quote := p.peek()
+	if quote != '"' && quote != '\'' && quote != '`' {
+		return "", p.errorf("expected quoted label value")
+	}
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if c == '\\' && quote != '`' {
+			p.pos += 2
+			continue
+		}
+		p.pos++
+		if c == quote {
+			raw := p.input[start:p.pos]
+			if quote == '\'' {
+				// strconv only accepts single quotes around one rune, so
+				// re-quote the body as a double-quoted literal.
+				body := raw[1 : len(raw)-1]
+				body = strings.ReplaceAll(body, `\'`, `'`)
+				body = strings.ReplaceAll(body, `"`, `\"`)
+				raw = `"` + body + `"`
+			}
+			s, err := strconv.Unquote(raw)
+			if err != nil {
+				return "", fmt.Errorf("invalid string literal %s: %w", p.input[start:p.pos], err)
+			}
+			return s, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *selectorParser) peek() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *selectorParser) skipSpace() {
+	for p.pos < len(p.input) && strings.IndexByte(" \t\r\n", p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *selectorParser) scanWhile(ok func(byte) bool) string {
+	start := p.pos
+	for p.pos < len(p.input) && ok(p.input[p.pos]) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *selectorParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("selector position %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func isLabelNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Metric names are accepted a little more loosely than in Prometheus since
+// the SDK lets users pick arbitrary names.
+func isMetricNameChar(c byte) bool {
+	return isLabelNameChar(c) || c == ':' || c == '.' || c == '-'
+}
+
+// formatSelector renders a metric name and matchers back into selector
+// syntax, the inverse of parseSelector.
+func formatSelector(name string, matchers []*pb.LabelMatcher) string {
+	if len(matchers) == 0 {
+		return name
+	}
+	ops := map[pb.LabelMatcher_Type]string{
+		pb.LabelMatcher_EQ:  "=",
+		pb.LabelMatcher_NEQ: "!=",
+		pb.LabelMatcher_RE:  "=~",
+		pb.LabelMatcher_NRE: "!~",
+	}
+	parts := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		parts = append(parts, m.Name+ops[m.Type]+strconv.Quote(m.Value))
+	}
+	return name + "{" + strings.Join(parts, ",") + "}"
+}
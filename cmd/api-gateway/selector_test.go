@@ -0,0 +1,127 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "pmts/proto"
+)
+
+func TestParseSelector(t *testing.T) {
+	eq := func(name, value string) *pb.LabelMatcher {
+		return &pb.LabelMatcher{Type: pb.LabelMatcher_EQ, Name: name, Value: value}
+	}
+	tests := []struct {
+		input    string
+		name     string
+		matchers []*pb.LabelMatcher
+		wantErr  bool
+	}{
+		{input: "system_cpu_percent", name: "system_cpu_percent"},
+		{input: "  app.requests-total  ", name: "app.requests-total"},
+		{input: `cpu{host="web-1"}`, name: "cpu", matchers: []*pb.LabelMatcher{eq("host", "web-1")}},
+		{
+			input: `cpu{host=~"web-.*", env!="staging",dc!~'eu|us'}`,
+			name:  "cpu",
+			matchers: []*pb.LabelMatcher{
+				{Type: pb.LabelMatcher_RE, Name: "host", Value: "web-.*"},
+				{Type: pb.LabelMatcher_NEQ, Name: "env", Value: "staging"},
+				{Type: pb.LabelMatcher_NRE, Name: "dc", Value: "eu|us"},
+			},
+		},
+		{input: `{job="api"}`, matchers: []*pb.LabelMatcher{eq("job", "api")}},
+		{input: `cpu{}`, name: "cpu"},
+		{input: `cpu{host="a",}`, name: "cpu", matchers: []*pb.LabelMatcher{eq("host", "a")}},
+		{input: `cpu{path="a\"b\\c"}`, name: "cpu", matchers: []*pb.LabelMatcher{eq("path", `a"b\c`)}},
+		{input: `cpu{path='it\'s "x"'}`, name: "cpu", matchers: []*pb.LabelMatcher{eq("path", `it's "x"`)}},
+		{input: "cpu{path=`C:\\tmp`}", name: "cpu", matchers: []*pb.LabelMatcher{eq("path", `C:\tmp`)}},
+
+		{input: "", wantErr: true},
+		{input: "{}", wantErr: true},
+		{input: `cpu{host}`, wantErr: true},
+		{input: `cpu{host=web}`, wantErr: true},
+		{input: `cpu{host="web"`, wantErr: true},
+		{input: `cpu{host="web}`, wantErr: true},
+		{input: `cpu{host="a" env="b"}`, wantErr: true},
+		{input: `cpu{host=~"("}`, wantErr: true},
+		{input: `cpu{host=~"\\bweb"}`, wantErr: true},
+		{input: `cpu{host!~"(?m)^web$"}`, wantErr: true},
+		{input: `cpu{host="a"} extra`, wantErr: true},
+		{input: `cpu{="a"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			name, matchers, err := parseSelector(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseSelector(%q) = %q, %v, want an error", tt.input, name, matchers)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSelector(%q): %v", tt.input, err)
+			}
+			if name != tt.name {
+				t.Errorf("name = %q, want %q", name, tt.name)
+			}
+			if !equalMatchers(matchers, tt.matchers) {
+				t.Errorf("matchers = %v, want %v", matchers, tt.matchers)
+			}
+		})
+	}
+}
+
+func TestParseSelectorRoundTrip(t *testing.T) {
+	for _, input := range []string{
+		"cpu",
+		`cpu{host="web-1"}`,
+		`mem{host=~"web-.*",env!="staging",dc!~"eu|us"}`,
+		`disk{path="a\"b\\c\n"}`,
+	} {
+		name, matchers, err := parseSelector(input)
+		if err != nil {
+			t.Fatalf("parseSelector(%q): %v", input, err)
+		}
+		if got := formatSelector(name, matchers); got != input {
+			t.Errorf("formatSelector(parseSelector(%q)) = %q", input, got)
+		}
+	}
+}
+
+func TestParseMatchParams(t *testing.T) {
+	tests := []struct {
+		name, match string
+		wantName    string
+		wantErr     bool
+	}{
+		{name: "cpu", wantName: "cpu"},
+		{match: `cpu{host="a"}`, wantName: "cpu"},
+		{name: "cpu", match: `{host="a"}`, wantName: "cpu"},
+		{name: "cpu", match: `cpu{host="a"}`, wantName: "cpu"},
+		{name: "cpu", match: `mem{host="a"}`, wantErr: true},
+		{name: "cpu", match: `{host=}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		name, _, err := parseMatchParams(tt.name, tt.match)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMatchParams(%q, %q) error = %v, want error %v", tt.name, tt.match, err, tt.wantErr)
+			continue
+		}
+		if err == nil && name != tt.wantName {
+			t.Errorf("parseMatchParams(%q, %q) name = %q, want %q", tt.name, tt.match, name, tt.wantName)
+		}
+	}
+}
+
+func equalMatchers(a, b []*pb.LabelMatcher) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
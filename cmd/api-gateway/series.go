@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// deleteSeriesResponse is the body DELETE /api/series returns.
+type deleteSeriesResponse struct {
+	DeletedSamples int64 `json:"deleted_samples"`
+	Series         int64 `json:"series"`
+	DryRun         bool  `json:"dry_run"`
+}
+
+// handleDeleteSeries serves DELETE /api/series, which removes the samples
+// of the series name and match select between from and to, either of which
+// may be left out. A selector is required, and unlike the label endpoints a
+// malformed from or to is refused rather than ignored, since it would
+// otherwise widen the range deleted. With dry_run=true nothing is deleted
+// and the counts are what would have been. Alert rules and metadata are left
+// alone, since the metric lives on; DELETE /api/metrics is what forgets a
+// metric along with its rules.
+func (g *Gateway) handleDeleteSeries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	name, matchers, err := parseMatchParams(q.Get("name"), q.Get("match"))
+	if err != nil {
+		http.Error(w, "Bad selector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name == "" && len(matchers) == 0 {
+		http.Error(w, "Missing series selector", http.StatusBadRequest)
+		return
+	}
+	var start, end int64
+	if v := q.Get("from"); v != "" {
+		if start, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid from", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if end, err = strconv.ParseInt(v, 10, 64); err != nil || end <= 0 {
+			http.Error(w, "Invalid to", http.StatusBadRequest)
+			return
+		}
+	}
+	if end > 0 && start > end {
+		http.Error(w, "Invalid time range: from is after to", http.StatusBadRequest)
+		return
+	}
+	dryRun := false
+	if v := q.Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid dry_run", http.StatusBadRequest)
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := g.client.DeleteSeries(ctx, &pb.DeleteSeriesRequest{
+		UserId:    userID,
+		MatchName: name,
+		Matchers:  matchers,
+		StartTime: start,
+		EndTime:   end,
+		DryRun:    dryRun,
+	})
+	if err != nil {
+		slog.Error("gRPC DeleteSeries failed", "error", err)
+		http.Error(w, "Failed to delete series", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deleteSeriesResponse{
+		DeletedSamples: resp.DeletedSamples,
+		Series:         resp.Series,
+		DryRun:         dryRun,
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// defaultStatsLimit caps the metrics and labels /api/stats lists unless
+// the caller passes its own limit; 0 lists everything.
+const defaultStatsLimit = 20
+
+// handleStats serves GET /api/stats: series cardinality and recent volume
+// per metric, and the labels with the most distinct values.
+func (g *Gateway) handleStats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.verifyKey(r, w)
+	if !ok {
+		return
+	}
+	limit := int64(defaultStatsLimit)
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	// Stats walk every series the tenant has, so allow more than the
+	// usual few seconds.
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := g.client.GetUsageStats(ctx, &pb.UsageStatsRequest{UserId: userID, Limit: int32(limit)})
+	if err != nil {
+		slog.Error("GetUsageStats gRPC failed", "error", err)
+		http.Error(w, "Failed to compute stats", http.StatusInternalServerError)
+		return
+	}
+
+	type MetricJSON struct {
+		Name           string `json:"name"`
+		Series         int64  `json:"series"`
+		SamplesLastDay int64  `json:"samples_last_day"`
+		BytesLastDay   int64  `json:"bytes_last_day"`
+		FirstSeen      int64  `json:"first_seen"`
+		LastSeen       int64  `json:"last_seen"`
+	}
+	type LabelJSON struct {
+		Name           string `json:"name"`
+		DistinctValues int64  `json:"distinct_values"`
+		Series         int64  `json:"series"`
+	}
+	out := struct {
+		Series         int64        `json:"series"`
+		SamplesLastDay int64        `json:"samples_last_day"`
+		BytesLastDay   int64        `json:"bytes_last_day"`
+		Metrics        []MetricJSON `json:"metrics"`
+		Labels         []LabelJSON  `json:"labels"`
+	}{
+		Series:         resp.Series,
+		SamplesLastDay: resp.SamplesLastDay,
+		BytesLastDay:   resp.BytesLastDay,
+		Metrics:        []MetricJSON{},
+		Labels:         []LabelJSON{},
+	}
+	for _, m := range resp.Metrics {
+		out.Metrics = append(out.Metrics, MetricJSON{
+			Name:           m.Name,
+			Series:         m.Series,
+			SamplesLastDay: m.SamplesLastDay,
+			BytesLastDay:   m.BytesLastDay,
+			FirstSeen:      m.FirstSeen,
+			LastSeen:       m.LastSeen,
+		})
+	}
+	for _, l := range resp.Labels {
+		out.Labels = append(out.Labels, LabelJSON{Name: l.Name, DistinctValues: l.DistinctValues, Series: l.Series})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
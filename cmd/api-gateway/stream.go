@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"maps"
+	"net/http"
+
+	pb "pmts/proto"
+)
+
+type jsonSample struct {
+	T int64   `json:"t"`
+	V float64 `json:"v"`
+}
+
+type jsonMetric struct {
+	Name    string            `json:"name"`
+	Labels  map[string]string `json:"labels"`
+	Samples []jsonSample      `json:"samples"`
+}
+
+func toJSONMetric(ts *pb.TimeSeries) jsonMetric {
+	jm := jsonMetric{Name: ts.Metric.Name, Labels: ts.Metric.Labels, Samples: make([]jsonSample, 0, len(ts.Samples))}
+	if jm.Labels == nil {
+		jm.Labels = map[string]string{}
+	}
+	for _, s := range ts.Samples {
+		jm.Samples = append(jm.Samples, jsonSample{T: s.Timestamp, V: s.Value})
+	}
+	return jm
+}
+
+// seriesWriter writes series chunks from StreamMetrics to an HTTP response
+// as they arrive. Chunks of one series always arrive back to back.
+type seriesWriter interface {
+	writeChunk(ts *pb.TimeSeries) error
+	// fail reports an error that happened after output had started.
+	fail(msg string)
+	close() error
+}
+
+func newSeriesWriter(w http.ResponseWriter, ndjson bool) seriesWriter {
+	bw := bufio.NewWriter(w)
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+	if ndjson {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		return &ndjsonWriter{w: bw, flush: flush}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return &jsonArrayWriter{w: bw, flush: flush}
+}
+
+// ndjsonWriter writes every chunk as its own JSON line, so a long series
+// spans several lines that repeat its name and labels.
+type ndjsonWriter struct {
+	w     *bufio.Writer
+	flush func() error
+}
+
+func (n *ndjsonWriter) writeChunk(ts *pb.TimeSeries) error {
+	data, err := json.Marshal(toJSONMetric(ts))
+	if err != nil {
+		return err
+	}
+	n.w.Write(data)
+	n.w.WriteByte('\n')
+	return n.flush()
+}
+
+func (n *ndjsonWriter) fail(msg string) {
+	data, _ := json.Marshal(map[string]string{"error": msg})
+	n.w.Write(data)
+	n.w.WriteByte('\n')
+	n.flush()
+}
+
+func (n *ndjsonWriter) close() error {
+	return n.flush()
+}
+
+// jsonArrayWriter produces the same array of series as a buffered response
+// would, stitching consecutive chunks of a series back into one object.
+type jsonArrayWriter struct {
+	w       *bufio.Writer
+	flush   func() error
+	current *pb.Metric
+}
+
+func (j *jsonArrayWriter) writeChunk(ts *pb.TimeSeries) error {
+	same := j.current != nil && j.current.Name == ts.Metric.Name && maps.Equal(j.current.Labels, ts.Metric.Labels)
+	if !same {
+		if j.current != nil {
+			j.w.WriteString("]},")
+		} else {
+			j.w.WriteByte('[')
+		}
+		jm := toJSONMetric(&pb.TimeSeries{Metric: ts.Metric})
+		name, _ := json.Marshal(jm.Name)
+		labels, _ := json.Marshal(jm.Labels)
+		j.w.WriteString(`{"name":`)
+		j.w.Write(name)
+		j.w.WriteString(`,"labels":`)
+		j.w.Write(labels)
+		j.w.WriteString(`,"samples":[`)
+	}
+	for i, s := range ts.Samples {
+		data, err := json.Marshal(jsonSample{T: s.Timestamp, V: s.Value})
+		if err != nil {
+			return err
+		}
+		if same || i > 0 {
+			j.w.WriteByte(',')
+		}
+		j.w.Write(data)
+	}
+	j.current = ts.Metric
+	return j.flush()
+}
+
+// fail can't report anything inside a JSON array; the truncated output
+// makes the client's parse fail instead.
+func (j *jsonArrayWriter) fail(msg string) {
+	j.flush()
+}
+
+func (j *jsonArrayWriter) close() error {
+	if j.current == nil {
+		j.w.WriteString("[]\n")
+	} else {
+		j.w.WriteString("]}]\n")
+	}
+	return j.flush()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	pb "pmts/proto"
+)
+
+// aggregateSamples combines time-ordered samples into one point per
+// step-wide bucket, timestamped at the bucket start. It is the in-process
+// counterpart of the SQL aggregations used by the Postgres backend.
+func aggregateSamples(samples []*pb.Sample, step int64, agg pb.GetMetricsRequest_Aggregation) ([]*pb.Sample, error) {
+	if _, ok := aggregationSQL[agg]; !ok {
+		return nil, fmt.Errorf("unknown aggregation %v", agg)
+	}
+	var out []*pb.Sample
+	for i := 0; i < len(samples); {
+		bucket := alignDown(samples[i].Timestamp, step)
+		j := i
+		for j < len(samples) && alignDown(samples[j].Timestamp, step) == bucket {
+			j++
+		}
+		out = append(out, &pb.Sample{Timestamp: bucket, Value: aggregate(samples[i:j], agg)})
+		i = j
+	}
+	return out, nil
+}
+
+func aggregate(samples []*pb.Sample, agg pb.GetMetricsRequest_Aggregation) float64 {
+	switch agg {
+	case pb.GetMetricsRequest_MIN:
+		v := samples[0].Value
+		for _, s := range samples[1:] {
+			v = min(v, s.Value)
+		}
+		return v
+	case pb.GetMetricsRequest_MAX:
+		v := samples[0].Value
+		for _, s := range samples[1:] {
+			v = max(v, s.Value)
+		}
+		return v
+	case pb.GetMetricsRequest_COUNT:
+		return float64(len(samples))
+	case pb.GetMetricsRequest_LAST:
+		return samples[len(samples)-1].Value
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += s.Value
+	}
+	if agg == pb.GetMetricsRequest_SUM {
+		return sum
+	}
+	return sum / float64(len(samples))
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "pmts/proto"
+)
+
+// ExportTenant streams everything a user has as archive records: metadata,
+// then alert rules, then the raw float and histogram samples of every
+// series in chunks. Rules are stripped of their IDs so the archive can be
+// imported into any user.
+func (s *Server) ExportTenant(req *pb.ExportRequest, stream pb.MonitoringService_ExportTenantServer) error {
+	ctx := stream.Context()
+	uid := req.UserId
+	if uid == 0 {
+		uid = 1
+	}
+
+	metadata, err := s.store.GetMetadata(ctx, uid, "")
+	if err != nil {
+		return err
+	}
+	for _, md := range metadata {
+		if err := stream.Send(&pb.ArchiveRecord{Record: &pb.ArchiveRecord_Metadata{Metadata: md}}); err != nil {
+			return err
+		}
+	}
+	rules, err := s.store.GetAlertRules(ctx, uid)
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		rule := &pb.AlertRule{MetricName: r.MetricName, Threshold: r.Threshold, WebhookUrl: r.WebhookUrl}
+		if err := stream.Send(&pb.ArchiveRecord{Record: &pb.ArchiveRecord_Rule{Rule: rule}}); err != nil {
+			return err
+		}
+	}
+
+	send := func(_ int64, chunk *pb.TimeSeries) error {
+		return stream.Send(&pb.ArchiveRecord{Record: &pb.ArchiveRecord_Series{Series: chunk}})
+	}
+	q := &SeriesQuery{UserID: uid}
+	if err := s.store.QuerySeries(ctx, q, send); err != nil {
+		return err
+	}
+	if err := s.store.QueryHistograms(ctx, q, send); err != nil {
+		return err
+	}
+	slog.Info("Exported tenant", "user_id", uid, "metadata", len(metadata), "rules", len(rules))
+	return nil
+}
+
+// errImportConflict stops a REJECT import.
+var errImportConflict = errors.New("import conflict")
+
+// tenantImport applies archive records to one user. Series chunks are
+// buffered and written uploadTxSamples at a time.
+type tenantImport struct {
+	ctx      context.Context
+	s        *Server
+	userID   int64
+	conflict pb.ImportRequest_Conflict
+	resp     *pb.ImportResponse
+
+	// Fetched on first use, and kept up to date as records are applied.
+	rules       []*pb.AlertRule
+	rulesLoaded bool
+	metadata    map[string]*pb.MetricMetadata
+
+	pending  []*pb.TimeSeries
+	buffered int
+}
+
+// ImportTenant writes a streamed archive into a user. Records are applied
+// in the order they arrive; under REJECT the first conflict ends the import
+// with the response's error set, leaving what came before it imported.
+func (s *Server) ImportTenant(stream pb.MonitoringService_ImportTenantServer) error {
+	var imp *tenantImport
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if imp == nil {
+			uid := req.UserId
+			if uid == 0 {
+				uid = 1
+			}
+			imp = &tenantImport{ctx: stream.Context(), s: s, userID: uid, conflict: req.Conflict, resp: &pb.ImportResponse{}}
+		}
+		if err := imp.apply(req.Records); err != nil {
+			return imp.finish(stream, err)
+		}
+	}
+	if imp == nil {
+		return stream.SendAndClose(&pb.ImportResponse{})
+	}
+	return imp.finish(stream, imp.flush())
+}
+
+func (imp *tenantImport) apply(records []*pb.ArchiveRecord) error {
+	for _, rec := range records {
+		// Buffered samples go in before anything that follows them, so a
+		// conflict leaves exactly the records before it imported.
+		if _, ok := rec.Record.(*pb.ArchiveRecord_Series); !ok {
+			if err := imp.flush(); err != nil {
+				return err
+			}
+		}
+		var err error
+		switch r := rec.Record.(type) {
+		case *pb.ArchiveRecord_Metadata:
+			err = imp.setMetadata(r.Metadata)
+		case *pb.ArchiveRecord_Rule:
+			err = imp.addRule(r.Rule)
+		case *pb.ArchiveRecord_Series:
+			if err = validateBatch([]*pb.TimeSeries{r.Series}); err != nil {
+				return err
+			}
+			imp.pending = append(imp.pending, r.Series)
+			imp.buffered += countSamples([]*pb.TimeSeries{r.Series})
+			if imp.buffered >= imp.s.uploadTxSamples {
+				err = imp.flush()
+			}
+		default:
+			err = errors.New("empty archive record")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish sends the response, turning a conflict into its error field.
+func (imp *tenantImport) finish(stream pb.MonitoringService_ImportTenantServer, err error) error {
+	if errors.Is(err, errImportConflict) {
+		imp.resp.Error = err.Error()
+		err = nil
+	}
+	if err != nil {
+		slog.Error("Import failed", "error", err, "user_id", imp.userID)
+		return err
+	}
+	slog.Info("Imported tenant", "user_id", imp.userID, "stored", imp.resp.StoredSamples,
+		"duplicates", imp.resp.DuplicateSamples, "rules", imp.resp.RulesCreated, "error", imp.resp.Error)
+	return stream.SendAndClose(imp.resp)
+}
+
+// flush writes the buffered series chunks.
+func (imp *tenantImport) flush() error {
+	if len(imp.pending) == 0 {
+		return nil
+	}
+	policy := keepFirst
+	switch imp.conflict {
+	case pb.ImportRequest_OVERWRITE:
+		policy = overwrite
+	case pb.ImportRequest_REJECT:
+		policy = rejectConflicts
+	}
+	adm, err := imp.s.quotas.admit(imp.ctx, imp.userID, imp.pending, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	res, err := imp.s.store.ImportSamples(imp.ctx, imp.userID, adm.list, policy)
+	if err != nil {
+		imp.s.quotas.release(imp.userID, adm)
+	}
+	var dupErr *duplicatesError
+	if errors.As(err, &dupErr) {
+		return fmt.Errorf("%w: %v", errImportConflict, err)
+	}
+	if err != nil {
+		return err
+	}
+	imp.resp.LimitedSamples += int64(adm.limited)
+	imp.resp.StoredSamples += int64(res.Stored)
+	imp.resp.DuplicateSamples += int64(res.Duplicates)
+	imp.pending, imp.buffered = nil, 0
+	return nil
+}
+
+func (imp *tenantImport) setMetadata(md *pb.MetricMetadata) error {
+	ctx := imp.ctx
+	if imp.metadata == nil {
+		list, err := imp.s.store.GetMetadata(ctx, imp.userID, "")
+		if err != nil {
+			return err
+		}
+		imp.metadata = make(map[string]*pb.MetricMetadata, len(list))
+		for _, m := range list {
+			imp.metadata[m.MetricName] = m
+		}
+	}
+	if old, ok := imp.metadata[md.MetricName]; ok {
+		switch {
+		case proto.Equal(old, md), imp.conflict == pb.ImportRequest_KEEP_EXISTING:
+			imp.resp.MetadataSkipped++
+			return nil
+		case imp.conflict == pb.ImportRequest_REJECT:
+			return fmt.Errorf("%w: metadata for %s differs from the existing", errImportConflict, md.MetricName)
+		}
+	}
+	if err := imp.s.store.SetMetadata(ctx, imp.userID, []*pb.MetricMetadata{md}); err != nil {
+		return err
+	}
+	imp.metadata[md.MetricName] = md
+	imp.resp.MetadataSet++
+	return nil
+}
+
+// addRule creates a rule unless the user has one on the same metric with
+// the same threshold, which under OVERWRITE is replaced. An identical rule
+// is always skipped.
+func (imp *tenantImport) addRule(rule *pb.AlertRule) error {
+	ctx := imp.ctx
+	if !imp.rulesLoaded {
+		rules, err := imp.s.store.GetAlertRules(ctx, imp.userID)
+		if err != nil {
+			return err
+		}
+		imp.rules, imp.rulesLoaded = rules, true
+	}
+	for i, old := range imp.rules {
+		if old.MetricName != rule.MetricName || old.Threshold != rule.Threshold {
+			continue
+		}
+		if old.WebhookUrl == rule.WebhookUrl || imp.conflict == pb.ImportRequest_KEEP_EXISTING {
+			imp.resp.RulesSkipped++
+			return nil
+		}
+		if imp.conflict == pb.ImportRequest_REJECT {
+			return fmt.Errorf("%w: a rule on %s at %g already exists", errImportConflict, rule.MetricName, rule.Threshold)
+		}
+		if _, err := imp.s.store.DeleteAlertRule(ctx, imp.userID, old.RuleId); err != nil {
+			return err
+		}
+		created, err := imp.createRule(ctx, rule)
+		if err != nil {
+			return err
+		}
+		imp.rules[i] = created
+		imp.resp.RulesReplaced++
+		return nil
+	}
+	created, err := imp.createRule(ctx, rule)
+	if err != nil {
+		return err
+	}
+	imp.rules = append(imp.rules, created)
+	imp.resp.RulesCreated++
+	return nil
+}
+
+func (imp *tenantImport) createRule(ctx context.Context, rule *pb.AlertRule) (*pb.AlertRule, error) {
+	created := &pb.AlertRule{UserId: imp.userID, MetricName: rule.MetricName, Threshold: rule.Threshold, WebhookUrl: rule.WebhookUrl}
+	id, err := imp.s.store.CreateAlertRule(ctx, created)
+	if err != nil {
+		return nil, err
+	}
+	created.RuleId = id
+	return created, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// Each benchmark iteration writes one batch of benchSeries series with
+// benchSamples samples each, after a warm-up batch has created the series.
+const (
+	benchSeries  = 100
+	benchSamples = 100
+)
+
+func BenchmarkAppendSamples(b *testing.B) {
+	b.Run("memory", func(b *testing.B) {
+		store := newMemStorage(30, keepFirst)
+		benchAppend(b, 1, func(ctx context.Context, userID int64, list []*pb.TimeSeries) (int, error) {
+			res, err := store.AppendSamples(ctx, userID, list)
+			return res.Stored, err
+		})
+	})
+	b.Run("tsdb", func(b *testing.B) {
+		store, err := openTSDB(b.TempDir(), 30, keepFirst)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer store.Close()
+		benchAppend(b, 1, func(ctx context.Context, userID int64, list []*pb.TimeSeries) (int, error) {
+			res, err := store.AppendSamples(ctx, userID, list)
+			return res.Stored, err
+		})
+	})
+
+	// Postgres compares the COPY-based AppendSamples with the row-by-row
+	// INSERT path it replaced. It needs a database of its own, named by
+	// PMTS_TEST_DB_CONN; never point it at one holding real tenants.
+	store := benchPostgres(b)
+	if store == nil {
+		return
+	}
+	b.Run("postgres/copy", func(b *testing.B) {
+		userID := benchUser(b, store)
+		benchAppend(b, userID, func(ctx context.Context, userID int64, list []*pb.TimeSeries) (int, error) {
+			res, err := store.AppendSamples(ctx, userID, list)
+			return res.Stored, err
+		})
+	})
+	b.Run("postgres/insert", func(b *testing.B) {
+		userID := benchUser(b, store)
+		benchAppend(b, userID, store.insertRowByRow)
+	})
+}
+
+// benchAppend times persist over b.N batches and reports samples/sec.
+func benchAppend(b *testing.B, userID int64, persist func(context.Context, int64, []*pb.TimeSeries) (int, error)) {
+	ctx := context.Background()
+	base := time.Now().Unix() - 3600
+	if _, err := persist(ctx, userID, benchBatch(benchSeries, 1, base)); err != nil {
+		b.Fatalf("warm-up: %v", err)
+	}
+	total := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n, err := persist(ctx, userID, benchBatch(benchSeries, benchSamples, base+1+int64(i*benchSamples)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		total += n
+	}
+	b.ReportMetric(float64(total)/b.Elapsed().Seconds(), "samples/sec")
+}
+
+// benchPostgres opens the database in PMTS_TEST_DB_CONN, skipping when it
+// is unset.
+func benchPostgres(b *testing.B) *pgStorage {
+	conn := os.Getenv("PMTS_TEST_DB_CONN")
+	if conn == "" {
+		b.Log("PMTS_TEST_DB_CONN not set, skipping postgres")
+		return nil
+	}
+	db, err := sql.Open("pgx", conn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := initDB(db); err != nil {
+		db.Close()
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	return newPgStorage(db, keepFirst)
+}
+
+// benchUser creates a throwaway user, removed along with its samples when
+// the benchmark ends.
+func benchUser(b *testing.B, store *pgStorage) int64 {
+	ctx := context.Background()
+	email := "bench-" + strconv.FormatInt(time.Now().UnixNano(), 10) + "@bench.local"
+	userID, err := store.CreateUser(ctx, email, generateAPIKey())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		db := store.db
+		db.ExecContext(ctx, "DELETE FROM samples WHERE series_id IN (SELECT id FROM series WHERE user_id = $1)", userID)
+		db.ExecContext(ctx, "DELETE FROM series WHERE user_id = $1", userID)
+		db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID)
+	})
+	return userID
+}
+
+func benchBatch(numSeries, perSeries int, startTS int64) []*pb.TimeSeries {
+	list := make([]*pb.TimeSeries, numSeries)
+	for i := range list {
+		samples := make([]*pb.Sample, perSeries)
+		for j := range samples {
+			samples[j] = &pb.Sample{Timestamp: startTS + int64(j), Value: float64(i*perSeries + j)}
+		}
+		list[i] = &pb.TimeSeries{
+			Metric: &pb.Metric{
+				Name:   "bench_metric",
+				Labels: map[string]string{"series": strconv.Itoa(i)},
+			},
+			Samples: samples,
+		}
+	}
+	return list
+}
+
+// insertRowByRow is the pre-COPY write path: one prepared INSERT per sample
+// inside a transaction, kept as the baseline for BenchmarkAppendSamples.
+func (s *pgStorage) insertRowByRow(ctx context.Context, userID int64, list []*pb.TimeSeries) (int, error) {
+	ids := make([]int64, len(list))
+	for i, series := range list {
+		id, err := s.seriesID(ctx, userID, series.Metric)
+		if err != nil {
+			return 0, err
+		}
+		ids[i] = id
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO samples (series_id, timestamp, value) VALUES ($1, $2, $3)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	count := 0
+	for i, series := range list {
+		for _, sample := range series.Samples {
+			if _, err := stmt.ExecContext(ctx, ids[i], sample.Timestamp, sample.Value); err != nil {
+				return 0, err
+			}
+			count++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
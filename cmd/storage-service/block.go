@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// A block is an immutable directory holding every sample in [mint, maxt):
+//
+//	meta.json   time range, compaction level and counts
+//	index.json  series with the offsets of their chunks
+//	chunks      Gorilla-compressed chunks, back to back
+//
+// Blocks are written under a temporary name and renamed into place, so a
+// crash never leaves a half-written block behind.
+const (
+	blocksDir      = "blocks"
+	blockMetaFile  = "meta.json"
+	blockIndexFile = "index.json"
+	blockChunkFile = "chunks"
+)
+
+type blockMeta struct {
+	MinTime    int64 `json:"min_time"`
+	MaxTime    int64 `json:"max_time"`
+	Level      int   `json:"level"`
+	NumSeries  int   `json:"num_series"`
+	NumSamples int   `json:"num_samples"`
+	// Compacted names the blocks this one replaces. Any still on disk when
+	// the TSDB opens are leftovers from an interrupted compaction.
+	Compacted []string `json:"compacted,omitempty"`
+	// HeadCut is the ID of the head cut that wrote this block, if any.
+	HeadCut int64 `json:"head_cut,omitempty"`
+	// Written orders blocks by the age of their data: where two hold a
+	// sample at the same timestamp, the one written later wins. A block
+	// made from others inherits the latest of theirs.
+	Written int64 `json:"written,omitempty"`
+}
+
+type blockSeries struct {
+	UserID int64             `json:"user_id"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Chunks []chunkRef        `json:"chunks"`
+	// Histograms are the series' histogram chunks, kept apart from the
+	// float ones since they are encoded differently.
+	Histograms []chunkRef `json:"histograms,omitempty"`
+}
+
+type chunkRef struct {
+	MinTime int64 `json:"min_time"`
+	MaxTime int64 `json:"max_time"`
+	Offset  int64 `json:"offset"`
+	Length  int   `json:"length"`
+}
+
+type block struct {
+	dir    string
+	meta   blockMeta
+	series []blockSeries
+	chunks *os.File
+	// readers counts queries still reading the block after it was looked up.
+	readers sync.WaitGroup
+}
+
+// seriesData is one series' samples, sorted by timestamp, on their way
+// into a block.
+type seriesData struct {
+	userID     int64
+	metric     *pb.Metric
+	samples    []*pb.Sample
+	histograms []*pb.HistogramSample
+}
+
+func openBlock(dir string) (*block, error) {
+	b := &block{dir: dir}
+	if err := readJSON(filepath.Join(dir, blockMetaFile), &b.meta); err != nil {
+		return nil, err
+	}
+	if err := readJSON(filepath.Join(dir, blockIndexFile), &b.series); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, blockChunkFile))
+	if err != nil {
+		return nil, err
+	}
+	b.chunks = f
+	return b, nil
+}
+
+// openBlocks loads every block under dir, oldest first, and clears away
+// temporary directories left by an interrupted write.
+func openBlocks(dir string) ([]*block, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []*block
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			os.RemoveAll(path)
+			continue
+		}
+		if !e.IsDir() {
+			continue
+		}
+		b, err := openBlock(path)
+		if err != nil {
+			return nil, fmt.Errorf("open block %s: %w", e.Name(), err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	replaced := make(map[string]bool)
+	for _, b := range blocks {
+		for _, name := range b.meta.Compacted {
+			replaced[name] = true
+		}
+	}
+	live := blocks[:0]
+	for _, b := range blocks {
+		if replaced[filepath.Base(b.dir)] {
+			if err := b.remove(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		live = append(live, b)
+	}
+	sortBlocks(live)
+	return live, nil
+}
+
+func sortBlocks(blocks []*block) {
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].meta.MinTime != blocks[j].meta.MinTime {
+			return blocks[i].meta.MinTime < blocks[j].meta.MinTime
+		}
+		return blocks[i].dir < blocks[j].dir
+	})
+}
+
+// writeBlock persists series as a new block under dir and opens it. The
+// time range, level and replaced blocks come from meta; the counts are
+// filled in here, and Written when unset.
+func writeBlock(dir string, meta blockMeta, series []*seriesData) (*block, error) {
+	now := time.Now().UnixNano()
+	if meta.Written == 0 {
+		meta.Written = now
+	}
+	name := fmt.Sprintf("%d-%d-%d", meta.MinTime, meta.MaxTime, now)
+	final := filepath.Join(dir, name)
+	tmp := final + ".tmp"
+	if err := os.MkdirAll(tmp, 0o755); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	f, err := os.Create(filepath.Join(tmp, blockChunkFile))
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	var index []blockSeries
+	var offset int64
+	writeChunk := func(data []byte, minTime, maxTime int64) (chunkRef, error) {
+		if _, err := w.Write(data); err != nil {
+			return chunkRef{}, err
+		}
+		ref := chunkRef{MinTime: minTime, MaxTime: maxTime, Offset: offset, Length: len(data)}
+		offset += int64(len(data))
+		return ref, nil
+	}
+	for _, s := range series {
+		if len(s.samples) == 0 && len(s.histograms) == 0 {
+			continue
+		}
+		entry := blockSeries{UserID: s.userID, Name: s.metric.Name, Labels: s.metric.Labels}
+		if entry.Labels == nil {
+			entry.Labels = map[string]string{}
+		}
+		for start := 0; start < len(s.samples); start += maxChunkSamples {
+			part := s.samples[start:min(start+maxChunkSamples, len(s.samples))]
+			ref, err := writeChunk(encodeChunk(part), part[0].Timestamp, part[len(part)-1].Timestamp)
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			entry.Chunks = append(entry.Chunks, ref)
+		}
+		for start := 0; start < len(s.histograms); start += maxChunkSamples {
+			part := s.histograms[start:min(start+maxChunkSamples, len(s.histograms))]
+			ref, err := writeChunk(encodeHistogramChunk(part), part[0].Timestamp, part[len(part)-1].Timestamp)
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			entry.Histograms = append(entry.Histograms, ref)
+		}
+		index = append(index, entry)
+		meta.NumSamples += len(s.samples) + len(s.histograms)
+	}
+	meta.NumSeries = len(index)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(filepath.Join(tmp, blockIndexFile), index); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(filepath.Join(tmp, blockMetaFile), meta); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		return nil, err
+	}
+	return openBlock(final)
+}
+
+// readSeries decodes the samples of one indexed series that fall within
+// [start, end]; end <= 0 means no upper bound.
+func (b *block) readSeries(s *blockSeries, start, end int64) ([]*pb.Sample, error) {
+	var samples []*pb.Sample
+	for _, c := range s.Chunks {
+		if c.MaxTime < start || (end > 0 && c.MinTime > end) {
+			continue
+		}
+		data := make([]byte, c.Length)
+		if _, err := b.chunks.ReadAt(data, c.Offset); err != nil {
+			return nil, fmt.Errorf("block %s: %w", filepath.Base(b.dir), err)
+		}
+		part, err := decodeChunk(data, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("block %s: %w", filepath.Base(b.dir), err)
+		}
+		samples = append(samples, part...)
+	}
+	return samples, nil
+}
+
+// readHistograms is readSeries for histogram samples.
+func (b *block) readHistograms(s *blockSeries, start, end int64) ([]*pb.HistogramSample, error) {
+	var hs []*pb.HistogramSample
+	for _, c := range s.Histograms {
+		if c.MaxTime < start || (end > 0 && c.MinTime > end) {
+			continue
+		}
+		data := make([]byte, c.Length)
+		if _, err := b.chunks.ReadAt(data, c.Offset); err != nil {
+			return nil, fmt.Errorf("block %s: %w", filepath.Base(b.dir), err)
+		}
+		part, err := decodeHistogramChunk(data, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("block %s: %w", filepath.Base(b.dir), err)
+		}
+		hs = append(hs, part...)
+	}
+	return hs, nil
+}
+
+// hasChunksIn reports whether any of a series' chunks, float or histogram,
+// overlaps [start, end]. That is decided from the index alone, so a chunk
+// spanning the range counts even if none of its samples fall inside.
+func (s *blockSeries) hasChunksIn(start, end int64) bool {
+	for _, c := range slices.Concat(s.Chunks, s.Histograms) {
+		if c.MaxTime >= start && (end <= 0 || c.MinTime <= end) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *block) overlaps(start, end int64) bool {
+	return b.meta.MaxTime > start && (end <= 0 || b.meta.MinTime <= end)
+}
+
+func (b *block) close() error {
+	return b.chunks.Close()
+}
+
+// remove closes the block and deletes it from disk.
+func (b *block) remove() error {
+	b.close()
+	return os.RemoveAll(b.dir)
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON writes v to path atomically via a temporary file.
+func writeJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"slices"
+
+	pb "pmts/proto"
+)
+
+// Chunks store a run of time-ordered samples using the encoding from
+// Facebook's Gorilla paper: timestamps as delta-of-deltas and values as the
+// XOR with the previous value, both written with variable bit widths. A
+// chunk starts with the sample count as a uvarint, followed by the bits.
+
+// maxChunkSamples bounds chunk size so queries over a short range of a
+// large block only decode the chunks that overlap it.
+const maxChunkSamples = 240
+
+var errChunkCorrupt = errors.New("tsdb: corrupt chunk")
+
+// encodeChunk compresses samples, which must be sorted by timestamp.
+func encodeChunk(samples []*pb.Sample) []byte {
+	var w bitWriter
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(samples)))
+
+	var prevTS, prevDelta int64
+	var prevValue uint64
+	var leading, trailing uint8 = 0xff, 0
+	for i, s := range samples {
+		value := math.Float64bits(s.Value)
+		switch i {
+		case 0:
+			w.writeBits(uint64(s.Timestamp), 64)
+			w.writeBits(value, 64)
+		case 1:
+			prevDelta = s.Timestamp - prevTS
+			w.writeBits(uint64(prevDelta), 64)
+			leading, trailing = w.writeXOR(value^prevValue, leading, trailing)
+		default:
+			delta := s.Timestamp - prevTS
+			w.writeDoD(delta - prevDelta)
+			prevDelta = delta
+			leading, trailing = w.writeXOR(value^prevValue, leading, trailing)
+		}
+		prevTS, prevValue = s.Timestamp, value
+	}
+	return w.buf
+}
+
+// decodeChunk returns the samples of a chunk whose timestamps fall within
+// [start, end]; end <= 0 means no upper bound.
+func decodeChunk(data []byte, start, end int64) ([]*pb.Sample, error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, errChunkCorrupt
+	}
+	r := bitReader{buf: data[n:]}
+
+	var samples []*pb.Sample
+	var ts, delta int64
+	var value uint64
+	var leading, trailing uint8
+	for i := uint64(0); i < count; i++ {
+		var err error
+		switch i {
+		case 0:
+			var raw uint64
+			if raw, err = r.readBits(64); err == nil {
+				ts = int64(raw)
+				value, err = r.readBits(64)
+			}
+		case 1:
+			var raw uint64
+			if raw, err = r.readBits(64); err == nil {
+				delta = int64(raw)
+				ts += delta
+				value, leading, trailing, err = r.readXOR(value, leading, trailing)
+			}
+		default:
+			var dod int64
+			if dod, err = r.readDoD(); err == nil {
+				delta += dod
+				ts += delta
+				value, leading, trailing, err = r.readXOR(value, leading, trailing)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if end > 0 && ts > end {
+			break
+		}
+		if ts >= start {
+			samples = append(samples, &pb.Sample{Timestamp: ts, Value: math.Float64frombits(value)})
+		}
+	}
+	return samples, nil
+}
+
+type bitWriter struct {
+	buf   []byte
+	count uint8 // bits still free in the last byte
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.count == 0 {
+		w.buf = append(w.buf, 0)
+		w.count = 8
+	}
+	w.count--
+	if bit {
+		w.buf[len(w.buf)-1] |= 1 << w.count
+	}
+}
+
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit(v>>uint(i)&1 == 1)
+	}
+}
+
+// dodBuckets are the signed bit widths a delta-of-delta may be packed into.
+// Bucket i is announced by i+1 one bits and a zero; len(dodBuckets)+1 one
+// bits announce a full 64-bit value, and a single zero bit means 0.
+var dodBuckets = []int{7, 9, 12, 20}
+
+func (w *bitWriter) writeDoD(dod int64) {
+	if dod == 0 {
+		w.writeBit(false)
+		return
+	}
+	for _, width := range dodBuckets {
+		w.writeBit(true)
+		if dod >= -(1<<(width-1)) && dod < 1<<(width-1) {
+			w.writeBit(false)
+			w.writeBits(uint64(dod), width)
+			return
+		}
+	}
+	w.writeBit(true)
+	w.writeBits(uint64(dod), 64)
+}
+
+// writeXOR writes the XOR of a value with its predecessor, reusing the
+// previous window of meaningful bits when the new one fits inside it.
+func (w *bitWriter) writeXOR(xor uint64, leading, trailing uint8) (uint8, uint8) {
+	if xor == 0 {
+		w.writeBit(false)
+		return leading, trailing
+	}
+	w.writeBit(true)
+	newLeading := uint8(bits.LeadingZeros64(xor))
+	newTrailing := uint8(bits.TrailingZeros64(xor))
+	// The leading count is stored in 5 bits.
+	if newLeading > 31 {
+		newLeading = 31
+	}
+	if leading != 0xff && newLeading >= leading && newTrailing >= trailing {
+		w.writeBit(false)
+		w.writeBits(xor>>trailing, 64-int(leading)-int(trailing))
+		return leading, trailing
+	}
+	w.writeBit(true)
+	sigbits := 64 - newLeading - newTrailing
+	w.writeBits(uint64(newLeading), 5)
+	// 64 significant bits don't fit in 6 bits; 0 stands in for them since
+	// a non-zero XOR always has at least one.
+	w.writeBits(uint64(sigbits), 6)
+	w.writeBits(xor>>newTrailing, int(sigbits))
+	return newLeading, newTrailing
+}
+
+type bitReader struct {
+	buf []byte
+	pos int // in bits
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos >= len(r.buf)*8 {
+		return false, errChunkCorrupt
+	}
+	bit := r.buf[r.pos/8]>>(7-uint(r.pos%8))&1 == 1
+	r.pos++
+	return bit, nil
+}
+
+func (r *bitReader) readBits(n int) (uint64, error) {
+	var v uint64
+	for i := 0; i < n; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v <<= 1
+		if bit {
+			v |= 1
+		}
+	}
+	return v, nil
+}
+
+func (r *bitReader) readDoD() (int64, error) {
+	ones := 0
+	for ones <= len(dodBuckets) {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		ones++
+	}
+	if ones == 0 {
+		return 0, nil
+	}
+	if ones > len(dodBuckets) {
+		v, err := r.readBits(64)
+		return int64(v), err
+	}
+	width := dodBuckets[ones-1]
+	v, err := r.readBits(width)
+	if err != nil {
+		return 0, err
+	}
+	// Sign-extend the width-bit value.
+	return int64(v<<(64-width)) >> (64 - width), nil
+}
+
+func (r *bitReader) readXOR(prev uint64, leading, trailing uint8) (uint64, uint8, uint8, error) {
+	bit, err := r.readBit()
+	if err != nil || !bit {
+		return prev, leading, trailing, err
+	}
+	if bit, err = r.readBit(); err != nil {
+		return 0, 0, 0, err
+	}
+	if bit {
+		l, err := r.readBits(5)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		sig, err := r.readBits(6)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		if sig == 0 {
+			sig = 64
+		}
+		leading, trailing = uint8(l), uint8(64-l-sig)
+	}
+	sigbits := 64 - int(leading) - int(trailing)
+	v, err := r.readBits(sigbits)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return prev ^ v<<trailing, leading, trailing, nil
+}
+
+// Histogram chunks are not bit-packed. After the sample count, each sample
+// is written relative to the one before it: the timestamp delta, a flag
+// byte saying whether a new bucket layout follows (always true for the
+// first sample), the layout, the change of each bucket count, the sum and
+// the change of the total count. Counts only grow between resets, so the
+// changes are small varints.
+func encodeHistogramChunk(hs []*pb.HistogramSample) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(hs)))
+	var prev *pb.HistogramSample
+	for _, h := range hs {
+		buf = appendHistogram(buf, h, prev)
+		prev = h
+	}
+	return buf
+}
+
+// decodeHistogramChunk returns the histogram samples of a chunk whose
+// timestamps fall within [start, end]; end <= 0 means no upper bound.
+func decodeHistogramChunk(data []byte, start, end int64) ([]*pb.HistogramSample, error) {
+	d := decoder{buf: data}
+	count := d.uvarint()
+	var hs []*pb.HistogramSample
+	var prev *pb.HistogramSample
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		h := d.histogram(prev)
+		prev = h
+		if h.Timestamp >= start && (end <= 0 || h.Timestamp <= end) {
+			hs = append(hs, h)
+		}
+	}
+	if d.err != nil {
+		return nil, errChunkCorrupt
+	}
+	return hs, nil
+}
+
+// appendHistogram writes h relative to prev, which may be nil.
+func appendHistogram(buf []byte, h, prev *pb.HistogramSample) []byte {
+	base := &pb.HistogramSample{}
+	if prev != nil {
+		base.Timestamp = prev.Timestamp
+		if slices.Equal(prev.Bounds, h.Bounds) {
+			base = prev
+		}
+	}
+	buf = binary.AppendVarint(buf, h.Timestamp-base.Timestamp)
+	if base == prev {
+		buf = append(buf, 0)
+	} else {
+		buf = append(buf, 1)
+		buf = binary.AppendUvarint(buf, uint64(len(h.Bounds)))
+		for _, b := range h.Bounds {
+			buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(b))
+		}
+	}
+	for i, c := range h.Counts {
+		var was uint64
+		if i < len(base.Counts) {
+			was = base.Counts[i]
+		}
+		buf = binary.AppendVarint(buf, int64(c-was))
+	}
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(h.Sum))
+	return binary.AppendVarint(buf, int64(h.Count-base.Count))
+}
+
+// histogram reads a sample written by appendHistogram with the same prev.
+func (d *decoder) histogram(prev *pb.HistogramSample) *pb.HistogramSample {
+	h := &pb.HistogramSample{}
+	base := &pb.HistogramSample{}
+	if prev != nil {
+		base.Timestamp = prev.Timestamp
+	}
+	h.Timestamp = base.Timestamp + d.varint()
+	if d.byte() == 0 {
+		if prev == nil {
+			d.err = errWALCorrupt
+			return h
+		}
+		base = prev
+		h.Bounds = prev.Bounds
+	} else {
+		n := d.uvarint()
+		if n > uint64(len(d.buf))/8 {
+			d.err = errWALCorrupt
+			return h
+		}
+		h.Bounds = make([]float64, n)
+		for i := range h.Bounds {
+			h.Bounds[i] = math.Float64frombits(d.uint64())
+		}
+	}
+	h.Counts = make([]uint64, len(h.Bounds))
+	for i := range h.Counts {
+		var was uint64
+		if i < len(base.Counts) {
+			was = base.Counts[i]
+		}
+		h.Counts[i] = was + uint64(d.varint())
+	}
+	h.Sum = math.Float64frombits(d.uint64())
+	h.Count = base.Count + uint64(d.varint())
+	return h
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	pb "pmts/proto"
+)
+
+func TestChunkRoundTrip(t *testing.T) {
+	samples := func(n int, ts func(i int) int64, value func(i int) float64) []*pb.Sample {
+		out := make([]*pb.Sample, n)
+		for i := range out {
+			out[i] = &pb.Sample{Timestamp: ts(i), Value: value(i)}
+		}
+		return out
+	}
+	regular := func(i int) int64 { return 1_700_000_000 + int64(i)*15 }
+	tests := []struct {
+		name    string
+		samples []*pb.Sample
+	}{
+		{"empty", nil},
+		{"single", samples(1, regular, func(int) float64 { return 42.5 })},
+		{"two", samples(2, regular, func(i int) float64 { return float64(i) })},
+		{"constant", samples(maxChunkSamples, regular, func(int) float64 { return 1 })},
+		{"counter", samples(maxChunkSamples, regular, func(i int) float64 { return float64(i * 7) })},
+		{"gauge", samples(100, regular, func(i int) float64 { return math.Sin(float64(i)) * 1e6 })},
+		{"jittered", samples(100, func(i int) int64 { return 1_700_000_000 + int64(i)*15 + int64(i%3) - 1 },
+			func(i int) float64 { return float64(i) / 3 })},
+		// Delta-of-deltas beyond every bucket of the variable-width encoding.
+		{"irregular", samples(6, func(i int) int64 { return []int64{0, 1, 100, 5_000, 1 << 20, 1 << 40}[i] },
+			func(i int) float64 { return float64(i) })},
+		{"negative timestamps", samples(3, func(i int) int64 { return -1000 + int64(i)*600 },
+			func(i int) float64 { return -float64(i) })},
+		{"special values", samples(6, regular, func(i int) float64 {
+			return []float64{0, math.Copysign(0, -1), math.Inf(1), math.Inf(-1), math.MaxFloat64, math.SmallestNonzeroFloat64}[i]
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeChunk(encodeChunk(tt.samples), math.MinInt64, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.samples) {
+				t.Fatalf("decoded %d samples, want %d", len(got), len(tt.samples))
+			}
+			for i, want := range tt.samples {
+				if got[i].Timestamp != want.Timestamp || math.Float64bits(got[i].Value) != math.Float64bits(want.Value) {
+					t.Fatalf("sample %d = (%d, %v), want (%d, %v)", i, got[i].Timestamp, got[i].Value, want.Timestamp, want.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkRoundTripNaN(t *testing.T) {
+	in := []*pb.Sample{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: math.NaN()}, {Timestamp: 3, Value: 3}}
+	got, err := decodeChunk(encodeChunk(in), math.MinInt64, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || !math.IsNaN(got[1].Value) || got[2].Value != 3 {
+		t.Errorf("decoded %v", got)
+	}
+}
+
+func TestDecodeChunkRange(t *testing.T) {
+	var in []*pb.Sample
+	for ts := int64(10); ts <= 100; ts += 10 {
+		in = append(in, &pb.Sample{Timestamp: ts, Value: float64(ts)})
+	}
+	data := encodeChunk(in)
+	tests := []struct {
+		start, end int64
+		want       []int64
+	}{
+		{0, 0, []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}},
+		{30, 50, []int64{30, 40, 50}},
+		{35, 45, []int64{40}},
+		{95, 0, []int64{100}},
+		{41, 49, nil},
+		{101, 0, nil},
+	}
+	for _, tt := range tests {
+		got, err := decodeChunk(data, tt.start, tt.end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ts []int64
+		for _, s := range got {
+			ts = append(ts, s.Timestamp)
+		}
+		if !slices.Equal(ts, tt.want) {
+			t.Errorf("decodeChunk(%d, %d) timestamps = %v, want %v", tt.start, tt.end, ts, tt.want)
+		}
+	}
+}
+
+func TestDecodeChunkCorrupt(t *testing.T) {
+	data := encodeChunk([]*pb.Sample{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}, {Timestamp: 4, Value: 3}})
+	for _, tt := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated", data[:len(data)/2]},
+		{"count too large", append([]byte{0xff, 0x01}, data[1:]...)},
+	} {
+		if _, err := decodeChunk(tt.data, math.MinInt64, 0); err == nil {
+			t.Errorf("%s: decodeChunk succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestHistogramChunkRoundTrip(t *testing.T) {
+	in := []*pb.HistogramSample{
+		{Timestamp: 100, Bounds: []float64{0.1, 1, 10}, Counts: []uint64{1, 2, 3}, Sum: 12.5, Count: 10},
+		{Timestamp: 115, Bounds: []float64{0.1, 1, 10}, Counts: []uint64{2, 2, 5}, Sum: 20, Count: 13},
+		// A counter reset and a new bucket layout.
+		{Timestamp: 130, Bounds: []float64{1, 5}, Counts: []uint64{0, 1}, Sum: 7, Count: 1},
+	}
+	got, err := decodeHistogramChunk(encodeHistogramChunk(in), math.MinInt64, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(in) {
+		t.Fatalf("decoded %d histograms, want %d", len(got), len(in))
+	}
+	for i, want := range in {
+		h := got[i]
+		if h.Timestamp != want.Timestamp || h.Sum != want.Sum || h.Count != want.Count ||
+			!slices.Equal(h.Bounds, want.Bounds) || !slices.Equal(h.Counts, want.Counts) {
+			t.Errorf("histogram %d = %v, want %v", i, h, want)
+		}
+	}
+}
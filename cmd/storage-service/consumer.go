@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+
+	pb "pmts/proto"
+	"pmts/stream"
+)
+
+// batchLimits bound how much the consumer coalesces into one write: it
+// flushes once INGEST_BATCH_SAMPLES samples (default 10000) or
+// INGEST_BATCH_MESSAGES messages (default 500) are buffered, or
+// INGEST_BATCH_LATENCY (default 200ms) after the first, whichever comes
+// first. A latency of 0 writes every message on its own. Nothing is pulled
+// from the stream while a write is running, and at most a batch worth of
+// messages is prefetched, so a slow database leaves the backlog in the
+// stream rather than in memory.
+type batchLimits struct {
+	samples  int
+	messages int
+	latency  time.Duration
+}
+
+func batchLimitsFromEnv() batchLimits {
+	return batchLimits{
+		samples:  envInt("INGEST_BATCH_SAMPLES", 10_000),
+		messages: envInt("INGEST_BATCH_MESSAGES", 500),
+		latency:  envDuration("INGEST_BATCH_LATENCY", 200*time.Millisecond),
+	}
+}
+
+// ingestWorker stores the batches a storage consumer receives.
+type ingestWorker struct {
+	ctx    context.Context
+	js     jetstream.JetStream
+	srv    *Server
+	rd     stream.Redelivery
+	limits batchLimits
+	logger *slog.Logger
+}
+
+// ingestMsg is a decoded message waiting to be written.
+type ingestMsg struct {
+	msg     jetstream.Msg
+	req     *pb.UploadRequest
+	attempt uint64
+	// received is when the message was published, which the acceptance
+	// window is measured from: time spent in the stream, with storage down
+	// or redelivering, must not push a batch the gateway accepted out of it.
+	received time.Time
+}
+
+// window is the acceptance window that applies to m. Replays skip it.
+func (w *ingestWorker) window(m *ingestMsg) acceptanceWindow {
+	if m.msg.Subject() == stream.ReplaySubject {
+		return acceptanceWindow{}
+	}
+	return w.srv.window
+}
+
+// run coalesces messages from it into bulk writes until it is closed or
+// fails, flushing whatever is buffered before returning. A failed iterator
+// is stopped, for the caller to open another after a pause, since one that
+// keeps failing would otherwise be retried in a tight loop.
+func (w *ingestWorker) run(it jetstream.MessagesContext) {
+	var pending []*ingestMsg
+	samples := 0
+	var deadline time.Time
+	flush := func() {
+		w.flush(pending)
+		pending, samples = nil, 0
+	}
+	for {
+		var m jetstream.Msg
+		var err error
+		switch wait := time.Until(deadline); {
+		case len(pending) == 0:
+			m, err = it.Next()
+		case wait > 0:
+			m, err = it.Next(jetstream.NextMaxWait(wait))
+		default:
+			err = nats.ErrTimeout
+		}
+		switch {
+		case errors.Is(err, nats.ErrTimeout):
+			flush()
+			continue
+		case errors.Is(err, jetstream.ErrMsgIteratorClosed):
+			flush()
+			return
+		case err != nil:
+			w.logger.Error("NATS consumer error", "error", err)
+			flush()
+			it.Stop()
+			return
+		}
+
+		im := w.decode(m)
+		if im == nil {
+			continue
+		}
+		if len(pending) == 0 {
+			deadline = time.Now().Add(w.limits.latency)
+		}
+		pending = append(pending, im)
+		samples += countSamples(im.req.List)
+		if samples >= w.limits.samples || len(pending) >= w.limits.messages {
+			flush()
+		}
+	}
+}
+
+// decode unpacks a message, dead-lettering it and returning nil if it can
+// never be stored.
+func (w *ingestWorker) decode(m jetstream.Msg) *ingestMsg {
+	im := &ingestMsg{msg: m, req: &pb.UploadRequest{}, attempt: 1, received: time.Now()}
+	if md, err := m.Metadata(); err == nil {
+		im.attempt, im.received = md.NumDelivered, md.Timestamp
+	}
+	if err := proto.Unmarshal(m.Data(), im.req); err != nil {
+		w.logger.Error("Bad NATS message", "error", err)
+		w.fail(im, 0, fmt.Errorf("unmarshal: %w", err))
+		return nil
+	}
+	if err := validateBatch(im.req.List); err != nil {
+		w.logger.Error("Invalid batch", "error", err, "user_id", im.req.UserId)
+		w.fail(im, im.req.UserId, err)
+		return nil
+	}
+	return im
+}
+
+// fail dead-letters a message or, should even that fail, leaves it to be
+// redelivered.
+func (w *ingestWorker) fail(m *ingestMsg, userID int64, reason error) {
+	if err := deadLetter(w.ctx, w.js, m.msg, userID, m.attempt, reason); err != nil {
+		w.logger.Error("Dead-lettering failed", "error", err)
+		m.msg.NakWithDelay(w.rd.Delay(m.attempt))
+		return
+	}
+	m.msg.Term()
+}
+
+// flush writes pending messages in one AppendBatches call, one batch per
+// user, and acks them. Messages whose batch was not written go through
+// store one by one instead, so that a failure is retried or dead-lettered
+// for the message that caused it and no other. Those whose samples were
+// written but whose metadata was not have only the metadata requeued.
+func (w *ingestWorker) flush(pending []*ingestMsg) {
+	if len(pending) == 0 {
+		return
+	}
+	if len(pending) == 1 {
+		w.store(pending[0])
+		return
+	}
+
+	now := time.Now()
+	var batches []userBatch
+	index := make(map[int64]int)
+	owner := make([]int, len(pending))
+	var metadata [][]*pb.MetricMetadata
+	rejected := 0
+	for i, m := range pending {
+		list, r := w.window(m).filter(m.req.List, m.received)
+		rejected += r
+		j, ok := index[m.req.UserId]
+		if !ok {
+			j = len(batches)
+			index[m.req.UserId] = j
+			batches = append(batches, userBatch{userID: m.req.UserId})
+			metadata = append(metadata, nil)
+		}
+		batches[j].list = append(batches[j].list, list...)
+		metadata[j] = append(metadata[j], m.req.Metadata...)
+		owner[i] = j
+	}
+
+	// Each user's batch is cut down to their limits as a whole. Should that
+	// fail, nothing is written and every message takes the slow path.
+	admitted := make([]*admission, len(batches))
+	limited := 0
+	for j := range batches {
+		adm, err := w.srv.quotas.admit(w.ctx, batches[j].userID, batches[j].list, now, false)
+		if err != nil {
+			w.logger.Warn("Quota check failed, storing messages one by one", "error", err)
+			for k := range j {
+				w.srv.quotas.release(batches[k].userID, admitted[k])
+			}
+			batches = nil
+			break
+		}
+		admitted[j], batches[j].list = adm, adm.list
+		limited += adm.limited
+	}
+
+	var res AppendResult
+	written := 0
+	if batches != nil {
+		var err error
+		res, written, err = w.srv.store.AppendBatches(w.ctx, batches)
+		if err != nil {
+			w.logger.Warn("Bulk write failed, storing messages one by one", "error", err,
+				"messages", len(pending), "batches_written", written)
+		}
+	}
+	// The batches not written are admitted again one message at a time.
+	for j := written; j < len(batches); j++ {
+		w.srv.quotas.release(batches[j].userID, admitted[j])
+		limited -= admitted[j].limited
+	}
+	metadataSaved := make([]bool, len(admitted))
+	for j := range written {
+		if len(metadata[j]) == 0 {
+			metadataSaved[j] = true
+			continue
+		}
+		if err := w.srv.store.SetMetadata(w.ctx, batches[j].userID, metadata[j]); err != nil {
+			w.logger.Error("Metadata save failed", "error", err, "user_id", batches[j].userID)
+			continue
+		}
+		metadataSaved[j] = true
+	}
+
+	acked := 0
+	for i, m := range pending {
+		switch j := owner[i]; {
+		case j >= written:
+			w.store(m)
+			continue
+		case !metadataSaved[j]:
+			w.requeueMetadata(m)
+			continue
+		}
+		if err := m.msg.Ack(); err != nil {
+			w.logger.Error("Ack failed", "error", err)
+		}
+		acked++
+	}
+	if acked > 0 {
+		w.logger.Info("Saved batch", "messages", acked, "users", written, "count", res.Stored,
+			"duplicates", res.Duplicates, "rejected", rejected, "limited", limited)
+	}
+}
+
+// store writes one message on its own, acking it once committed. A failed
+// message is redelivered with backoff, or dead-lettered if that was its
+// last attempt or it can never be stored.
+func (w *ingestWorker) store(m *ingestMsg) {
+	req := m.req
+	res, err := w.srv.storeUpload(w.ctx, req.UserId, req, w.window(m), m.received, false)
+	var dupErr *duplicatesError
+	switch {
+	case errors.As(err, &dupErr):
+		// Redelivery would be refused the same way.
+		w.logger.Warn("Batch rejected", "error", err, "user_id", req.UserId)
+		w.fail(m, req.UserId, err)
+	case errors.Is(err, errMetadataNotSaved) && len(req.List) > 0:
+		w.logger.Error("Metadata save failed", "error", err, "user_id", req.UserId)
+		w.requeueMetadata(m)
+	case err != nil:
+		w.logger.Error("DB Save Failed", "error", err, "attempt", m.attempt, "max_deliver", w.rd.MaxDeliver,
+			"duplicates", res.Duplicates, "rejected", res.Rejected)
+		if m.attempt >= uint64(w.rd.MaxDeliver) {
+			w.fail(m, req.UserId, err)
+			return
+		}
+		m.msg.NakWithDelay(w.rd.Delay(m.attempt))
+	default:
+		if err := m.msg.Ack(); err != nil {
+			w.logger.Error("Ack failed", "error", err)
+		}
+		w.logger.Info("Saved batch", "count", res.Stored, "duplicates", res.Duplicates, "rejected", res.Rejected,
+			"limited", res.Limited, "user_id", req.UserId)
+	}
+}
+
+// requeueMetadata acks a message whose samples are stored but whose
+// metadata is not, publishing the metadata again on its own so that a
+// retry cannot write, count or reject the samples a second time. It goes
+// out on the replay subject, which only storage consumes. Should that
+// fail, the whole message is redelivered after all. A requeued message
+// carries no samples, so its own failures are retried as usual.
+func (w *ingestWorker) requeueMetadata(m *ingestMsg) {
+	data, err := proto.Marshal(&pb.UploadRequest{UserId: m.req.UserId, Metadata: m.req.Metadata})
+	if err == nil {
+		_, err = w.js.Publish(w.ctx, stream.ReplaySubject, data)
+	}
+	if err != nil {
+		w.logger.Error("Requeueing metadata failed", "error", err, "user_id", m.req.UserId)
+		m.msg.NakWithDelay(w.rd.Delay(m.attempt))
+		return
+	}
+	if err := m.msg.Ack(); err != nil {
+		w.logger.Error("Ack failed", "error", err)
+	}
+}
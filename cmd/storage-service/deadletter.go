@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+
+	pb "pmts/proto"
+	"pmts/stream"
+)
+
+// Batches storage gives up on are kept, byte for byte, on a stream of
+// their own, with why and when in the headers, so they can be replayed with
+// `storage deadletter replay` once the problem is fixed.
+const (
+	deadLetterStream  = "METRICS_DLQ"
+	deadLetterSubject = "metrics.deadletter"
+	deadLetterMaxAge  = 30 * 24 * time.Hour
+
+	hdrError      = "Pmts-Error"
+	hdrAttempts   = "Pmts-Attempts"
+	hdrUserID     = "Pmts-User-Id"
+	hdrFailedAt   = "Pmts-Failed-At"
+	hdrSubject    = "Pmts-Subject"
+	hdrSequence   = "Pmts-Sequence"
+	hdrReplayedOf = "Pmts-Replay-Of"
+)
+
+func ensureDeadLetterStream(ctx context.Context, js jetstream.JetStream) (jetstream.Stream, error) {
+	return js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     deadLetterStream,
+		Subjects: []string{deadLetterSubject},
+		Storage:  jetstream.FileStorage,
+		MaxAge:   deadLetterMaxAge,
+	})
+}
+
+// deadLetter publishes m's batch to the dead-letter stream with the reason
+// it failed. userID is 0 when the batch could not be decoded.
+func deadLetter(ctx context.Context, js jetstream.JetStream, m jetstream.Msg, userID int64, attempts uint64, reason error) error {
+	msg := nats.NewMsg(deadLetterSubject)
+	msg.Data = m.Data()
+	msg.Header.Set(hdrError, reason.Error())
+	msg.Header.Set(hdrAttempts, strconv.FormatUint(attempts, 10))
+	msg.Header.Set(hdrUserID, strconv.FormatInt(userID, 10))
+	msg.Header.Set(hdrFailedAt, time.Now().UTC().Format(time.RFC3339))
+	msg.Header.Set(hdrSubject, m.Subject())
+	if md, err := m.Metadata(); err == nil {
+		msg.Header.Set(hdrSequence, strconv.FormatUint(md.Sequence.Stream, 10))
+	}
+	_, err := js.PublishMsg(ctx, msg)
+	return err
+}
+
+// deadLetterFilter selects dead-lettered batches. Zero fields match
+// everything.
+type deadLetterFilter struct {
+	seq    uint64
+	userID int64
+	reason string
+	since  time.Duration
+}
+
+func (f deadLetterFilter) match(m *jetstream.RawStreamMsg, now time.Time) bool {
+	if f.seq != 0 && m.Sequence != f.seq {
+		return false
+	}
+	if f.userID != 0 && m.Header.Get(hdrUserID) != strconv.FormatInt(f.userID, 10) {
+		return false
+	}
+	if f.reason != "" && !strings.Contains(m.Header.Get(hdrError), f.reason) {
+		return false
+	}
+	return f.since == 0 || !m.Time.Before(now.Add(-f.since))
+}
+
+// scanDeadLetters calls fn with every dead-lettered batch f matches, oldest
+// first.
+func scanDeadLetters(ctx context.Context, dlq jetstream.Stream, f deadLetterFilter, fn func(*jetstream.RawStreamMsg) error) error {
+	info, err := dlq.Info(ctx)
+	if err != nil {
+		return err
+	}
+	first, last := info.State.FirstSeq, info.State.LastSeq
+	if f.seq != 0 {
+		first, last = f.seq, f.seq
+	}
+	now := time.Now()
+	for seq := first; seq != 0 && seq <= last; seq++ {
+		m, err := dlq.GetMsg(ctx, seq)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if f.match(m, now) {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runDeadLetter implements `storage deadletter list|show|replay`: list
+// summarizes dead-lettered batches, show prints one in full and replay
+// sends them back through storage, removing each once it is republished.
+// All three take the same filters.
+func runDeadLetter(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: storage deadletter list|show|replay [flags]")
+	}
+	flags := flag.NewFlagSet("deadletter "+args[0], flag.ExitOnError)
+	var f deadLetterFilter
+	flags.Uint64Var(&f.seq, "seq", 0, "only the batch with this sequence number")
+	flags.Int64Var(&f.userID, "user", 0, "only batches from this user")
+	flags.StringVar(&f.reason, "error", "", "only batches whose error contains this text")
+	flags.DurationVar(&f.since, "since", 0, "only batches dead-lettered within this long, e.g. 6h")
+	dryRun := flags.Bool("dry-run", false, "replay: list what would be replayed without doing it")
+	flags.Parse(args[1:])
+
+	nc, err := nats.Connect(natsAddr())
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	dlq, err := ensureDeadLetterStream(ctx, js)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SEQ\tFAILED\tUSER\tATTEMPTS\tSERIES\tSAMPLES\tERROR")
+		err := scanDeadLetters(ctx, dlq, f, func(m *jetstream.RawStreamMsg) error {
+			series, samples := "?", "?"
+			req := &pb.UploadRequest{}
+			if proto.Unmarshal(m.Data, req) == nil {
+				series, samples = strconv.Itoa(len(req.List)), strconv.Itoa(countSamples(req.List))
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", m.Sequence, m.Header.Get(hdrFailedAt),
+				m.Header.Get(hdrUserID), m.Header.Get(hdrAttempts), series, samples, m.Header.Get(hdrError))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return w.Flush()
+	case "show":
+		if f.seq == 0 {
+			return errors.New("show needs -seq")
+		}
+		found := false
+		err := scanDeadLetters(ctx, dlq, f, func(m *jetstream.RawStreamMsg) error {
+			found = true
+			printDeadLetter(m)
+			return nil
+		})
+		if err == nil && !found {
+			err = fmt.Errorf("no dead-lettered batch %d matches", f.seq)
+		}
+		return err
+	case "replay":
+		replayed := 0
+		err := scanDeadLetters(ctx, dlq, f, func(m *jetstream.RawStreamMsg) error {
+			if *dryRun {
+				fmt.Printf("would replay %d (user %s): %s\n", m.Sequence, m.Header.Get(hdrUserID), m.Header.Get(hdrError))
+				return nil
+			}
+			msg := nats.NewMsg(stream.ReplaySubject)
+			msg.Data = m.Data
+			msg.Header.Set(hdrReplayedOf, strconv.FormatUint(m.Sequence, 10))
+			if _, err := js.PublishMsg(ctx, msg); err != nil {
+				return fmt.Errorf("replay %d: %w", m.Sequence, err)
+			}
+			if err := dlq.DeleteMsg(ctx, m.Sequence); err != nil {
+				return fmt.Errorf("replayed %d but could not remove it: %w", m.Sequence, err)
+			}
+			replayed++
+			return nil
+		})
+		if !*dryRun {
+			fmt.Printf("replayed %d batches\n", replayed)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown deadletter command %q: expected list, show or replay", args[0])
+	}
+}
+
+// printDeadLetter writes one dead-lettered batch's headers and contents.
+func printDeadLetter(m *jetstream.RawStreamMsg) {
+	fmt.Printf("sequence:   %d\n", m.Sequence)
+	fmt.Printf("failed at:  %s\n", m.Header.Get(hdrFailedAt))
+	fmt.Printf("user:       %s\n", m.Header.Get(hdrUserID))
+	fmt.Printf("attempts:   %s\n", m.Header.Get(hdrAttempts))
+	fmt.Printf("subject:    %s (sequence %s)\n", m.Header.Get(hdrSubject), m.Header.Get(hdrSequence))
+	fmt.Printf("error:      %s\n", m.Header.Get(hdrError))
+
+	req := &pb.UploadRequest{}
+	if err := proto.Unmarshal(m.Data, req); err != nil {
+		fmt.Printf("body:       %d bytes, undecodable: %v\n", len(m.Data), err)
+		return
+	}
+	fmt.Printf("metadata:   %d entries\n", len(req.Metadata))
+	for _, ts := range req.List {
+		from, to := int64(0), int64(0)
+		for _, s := range ts.Samples {
+			from, to = minNonZero(from, s.Timestamp), max(to, s.Timestamp)
+		}
+		for _, h := range ts.Histograms {
+			from, to = minNonZero(from, h.Timestamp), max(to, h.Timestamp)
+		}
+		fmt.Printf("  %s%s: %d samples, %d histograms, %d..%d\n", ts.Metric.GetName(), formatLabels(ts.Metric.GetLabels()),
+			len(ts.Samples), len(ts.Histograms), from, to)
+	}
+}
+
+// formatLabels renders labels as {a="1",b="2"}, or nothing when empty.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, k+"="+strconv.Quote(labels[k]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func minNonZero(a, b int64) int64 {
+	if a == 0 {
+		return b
+	}
+	return min(a, b)
+}
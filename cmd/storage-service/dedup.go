@@ -0,0 +1,175 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "pmts/proto"
+)
+
+// conflictPolicy decides what happens to a sample whose series already has
+// one at its timestamp, whether stored before or earlier in the same batch.
+// Retries and NATS redeliveries make such duplicates routine.
+type conflictPolicy int
+
+const (
+	// keepFirst drops the new sample.
+	keepFirst conflictPolicy = iota
+	// overwrite replaces the old sample with the new one.
+	overwrite
+	// rejectConflicts fails the whole batch.
+	rejectConflicts
+)
+
+// conflictPolicyFromEnv reads DUPLICATE_POLICY: keep_first (the default),
+// overwrite or reject.
+func conflictPolicyFromEnv() (conflictPolicy, error) {
+	switch v := os.Getenv("DUPLICATE_POLICY"); v {
+	case "", "keep_first":
+		return keepFirst, nil
+	case "overwrite":
+		return overwrite, nil
+	case "reject":
+		return rejectConflicts, nil
+	default:
+		return 0, fmt.Errorf("unknown DUPLICATE_POLICY %q: expected keep_first, overwrite or reject", v)
+	}
+}
+
+// AppendResult is what became of a batch passed to AppendSamples.
+type AppendResult struct {
+	// Stored counts the samples written, including those that overwrote
+	// an old sample.
+	Stored int
+	// Duplicates counts the samples that had the timestamp of one already
+	// stored for their series or earlier in the batch.
+	Duplicates int
+}
+
+// duplicatesError is returned, along with the duplicate count, for a batch
+// the reject policy refused. Nothing in the batch was stored.
+type duplicatesError struct {
+	count int
+}
+
+func (e *duplicatesError) Error() string {
+	return fmt.Sprintf("batch rejected: %d samples duplicate existing ones", e.count)
+}
+
+// timestamped is a float or histogram sample.
+type timestamped interface {
+	*pb.Sample | *pb.HistogramSample
+	GetTimestamp() int64
+}
+
+func byTimestamp[T timestamped](a, b T) int {
+	return cmp.Compare(a.GetTimestamp(), b.GetTimestamp())
+}
+
+// mergeSamples merges copies of src, in any order, into dst, which is
+// sorted by timestamp, and returns the result along with the copies that
+// made it in and the number of duplicates in src. Of samples with the same
+// timestamp the first is kept unless the policy is overwrite; rejecting is
+// left to the caller. dst's samples are never modified, nor is anything
+// within its length, so it stays valid if the result is discarded.
+//
+// Batches usually arrive in order and after what is stored, in which case
+// they are just appended; otherwise the stored samples from the earliest
+// new timestamp on are copied to merge them.
+func mergeSamples[T timestamped](dst, src []T, policy conflictPolicy) (merged, added []T, dups int) {
+	if len(src) == 0 {
+		return dst, nil, 0
+	}
+	sorted := make([]T, len(src))
+	for i, x := range src {
+		sorted[i] = cloneSample(x)
+	}
+	slices.SortStableFunc(sorted, byTimestamp[T])
+
+	lo := sort.Search(len(dst), func(i int) bool { return dst[i].GetTimestamp() >= sorted[0].GetTimestamp() })
+	tail := dst[lo:]
+	if len(tail) == 0 {
+		merged = dst
+	} else {
+		merged = make([]T, lo, len(dst)+len(src))
+		copy(merged, dst[:lo])
+	}
+	lastAdded := false
+	for i, j := 0, 0; i < len(tail) || j < len(sorted); {
+		// Stored samples go first, so they count as the earlier ones.
+		fromSrc := i == len(tail) || (j < len(sorted) && sorted[j].GetTimestamp() < tail[i].GetTimestamp())
+		var next T
+		if fromSrc {
+			next, j = sorted[j], j+1
+		} else {
+			next, i = tail[i], i+1
+		}
+		if n := len(merged); n > lo && merged[n-1].GetTimestamp() == next.GetTimestamp() {
+			dups++
+			if policy == overwrite {
+				merged[n-1] = next
+				if lastAdded {
+					added[len(added)-1] = next
+				} else {
+					added = append(added, next)
+				}
+				lastAdded = true
+			}
+			continue
+		}
+		merged = append(merged, next)
+		if fromSrc {
+			added = append(added, next)
+		}
+		lastAdded = fromSrc
+	}
+	return merged, added, dups
+}
+
+func cloneSample[T timestamped](x T) T {
+	if s, ok := any(x).(*pb.Sample); ok {
+		return any(&pb.Sample{Timestamp: s.Timestamp, Value: s.Value}).(T)
+	}
+	return proto.Clone(any(x).(proto.Message)).(T)
+}
+
+// dropStored handles the samples in src whose timestamps are in stored,
+// which the caller found outside the slice mergeSamples works on. It
+// returns src without them unless the policy is overwrite, and how many
+// there were.
+func dropStored[T timestamped](src []T, stored map[int64]bool, policy conflictPolicy) ([]T, int) {
+	if len(stored) == 0 {
+		return src, 0
+	}
+	dups := 0
+	kept := make([]T, 0, len(src))
+	for _, x := range src {
+		if stored[x.GetTimestamp()] {
+			dups++
+			if policy != overwrite {
+				continue
+			}
+		}
+		kept = append(kept, x)
+	}
+	return kept, dups
+}
+
+// keepLast drops all but the last of each run of samples with the same
+// timestamp in sorted, for merging sources ordered oldest write first.
+func keepLast[T timestamped](sorted []T) []T {
+	out := sorted[:0]
+	for _, x := range sorted {
+		if n := len(out); n > 0 && out[n-1].GetTimestamp() == x.GetTimestamp() {
+			out[n-1] = x
+			continue
+		}
+		out = append(out, x)
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	pb "pmts/proto"
+)
+
+func TestConflictPolicies(t *testing.T) {
+	sample := func(ts int64, v float64) *pb.Sample { return &pb.Sample{Timestamp: ts, Value: v} }
+	stored := []*pb.Sample{sample(10, 1), sample(20, 2), sample(30, 3)}
+	tests := []struct {
+		name   string
+		policy conflictPolicy
+		batch  []*pb.Sample
+		want   AppendResult
+		// values are the series' values afterwards, in timestamp order.
+		values  []float64
+		wantErr bool
+	}{
+		{"keep first, no conflict", keepFirst, []*pb.Sample{sample(40, 4)},
+			AppendResult{Stored: 1}, []float64{1, 2, 3, 4}, false},
+		{"keep first, conflict", keepFirst, []*pb.Sample{sample(20, 9), sample(25, 5)},
+			AppendResult{Stored: 1, Duplicates: 1}, []float64{1, 2, 5, 3}, false},
+		{"keep first, conflict within batch", keepFirst, []*pb.Sample{sample(40, 4), sample(40, 8)},
+			AppendResult{Stored: 1, Duplicates: 1}, []float64{1, 2, 3, 4}, false},
+		{"overwrite, conflict", overwrite, []*pb.Sample{sample(20, 9), sample(5, 0)},
+			AppendResult{Stored: 2, Duplicates: 1}, []float64{0, 1, 9, 3}, false},
+		{"overwrite, conflict within batch", overwrite, []*pb.Sample{sample(40, 4), sample(40, 8)},
+			AppendResult{Stored: 1, Duplicates: 1}, []float64{1, 2, 3, 8}, false},
+		{"reject, no conflict", rejectConflicts, []*pb.Sample{sample(15, 5), sample(40, 4)},
+			AppendResult{Stored: 2}, []float64{1, 5, 2, 3, 4}, false},
+		{"reject, conflict", rejectConflicts, []*pb.Sample{sample(40, 4), sample(30, 9)},
+			AppendResult{Duplicates: 1}, []float64{1, 2, 3}, true},
+		{"reject, conflict within batch", rejectConflicts, []*pb.Sample{sample(40, 4), sample(40, 4)},
+			AppendResult{Duplicates: 1}, []float64{1, 2, 3}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newMemStorage(30, tt.policy)
+			labels := map[string]string{"host": "a"}
+			if _, err := s.AppendSamples(ctx, 1, []*pb.TimeSeries{testSeries("m", labels, stored...)}); err != nil {
+				t.Fatal(err)
+			}
+			res, err := s.AppendSamples(ctx, 1, []*pb.TimeSeries{testSeries("m", labels, tt.batch...)})
+			var dupErr *duplicatesError
+			if tt.wantErr != errors.As(err, &dupErr) {
+				t.Fatalf("error = %v, want duplicatesError %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+			if res != tt.want {
+				t.Errorf("result = %+v, want %+v", res, tt.want)
+			}
+			var values []float64
+			for _, smp := range querySamples(t, s, "m") {
+				values = append(values, smp.Value)
+			}
+			if !slices.Equal(values, tt.values) {
+				t.Errorf("values = %v, want %v", values, tt.values)
+			}
+		})
+	}
+}
+
+func TestConflictPolicyFromEnv(t *testing.T) {
+	for _, tt := range []struct {
+		value   string
+		want    conflictPolicy
+		wantErr bool
+	}{
+		{"", keepFirst, false},
+		{"keep_first", keepFirst, false},
+		{"overwrite", overwrite, false},
+		{"reject", rejectConflicts, false},
+		{"latest", 0, true},
+	} {
+		t.Setenv("DUPLICATE_POLICY", tt.value)
+		got, err := conflictPolicyFromEnv()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("DUPLICATE_POLICY=%q: got %v, %v", tt.value, got, err)
+		}
+	}
+}
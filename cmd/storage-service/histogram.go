@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// quantileLabel is added to the series QueryQuantiles returns.
+const quantileLabel = "quantile"
+
+var defaultQuantiles = []float64{0.5, 0.95, 0.99}
+
+// maxQuantileWindows bounds the points per series a step may produce.
+const maxQuantileWindows = 11_000
+
+// QueryQuantiles estimates quantiles from histogram series. Each window's
+// observations are the increase of the bucket counts across it. With a
+// step, the samples in the step before the range are read as well to serve
+// as the first window's baseline.
+func (s *Server) QueryQuantiles(ctx context.Context, req *pb.QuantileRequest) (*pb.GetMetricsResponse, error) {
+	quantiles := req.Quantiles
+	if len(quantiles) == 0 {
+		quantiles = defaultQuantiles
+	}
+	for _, q := range quantiles {
+		if !(q >= 0 && q <= 1) {
+			return nil, fmt.Errorf("quantile %v is not between 0 and 1", q)
+		}
+	}
+	if req.Step < 0 {
+		return nil, fmt.Errorf("negative step")
+	}
+	uid := req.UserId
+	if uid == 0 {
+		uid = 1
+	}
+	end := req.EndTime
+	if end <= 0 {
+		end = time.Now().Unix()
+	}
+	start := req.StartTime
+	if req.Step > 0 {
+		if start <= 0 {
+			return nil, fmt.Errorf("a step needs a start time")
+		}
+		if (end-start)/req.Step > maxQuantileWindows {
+			return nil, fmt.Errorf("step too small: more than %d windows", maxQuantileWindows)
+		}
+		start = alignDown(start, req.Step)
+	}
+	q := &SeriesQuery{UserID: uid, Name: req.MatchName, Matchers: req.Matchers, Start: start - req.Step, End: end}
+
+	resp := &pb.GetMetricsResponse{}
+	var (
+		metric *pb.Metric
+		hs     []*pb.HistogramSample
+	)
+	flush := func() {
+		if metric != nil {
+			resp.List = append(resp.List, quantileSeries(metric, hs, quantiles, start, end, req.Step)...)
+		}
+		metric, hs = nil, nil
+	}
+	err := s.store.QueryHistograms(ctx, q, func(_ int64, chunk *pb.TimeSeries) error {
+		if chunk.Metric != metric {
+			flush()
+			metric = chunk.Metric
+		}
+		hs = append(hs, chunk.Histograms...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	flush()
+	return resp, nil
+}
+
+// quantileSeries turns one histogram series into a series per quantile.
+// Without a step there is a single point, at the last sample in range.
+// Otherwise there is one per step-wide window, at the window's end.
+func quantileSeries(metric *pb.Metric, hs []*pb.HistogramSample, quantiles []float64, start, end, step int64) []*pb.TimeSeries {
+	type window struct {
+		t        int64
+		observed *pb.HistogramSample
+	}
+	var windows []window
+	if step == 0 {
+		windows = append(windows, window{hs[len(hs)-1].Timestamp, histogramIncrease(hs)})
+	} else {
+		start = max(start, alignDown(hs[0].Timestamp, step))
+		for t := start + step; t-step <= end; t += step {
+			// Each window runs from the last sample at or before its start
+			// to the last sample at or before its end.
+			lo := sort.Search(len(hs), func(i int) bool { return hs[i].Timestamp > t-step })
+			hi := sort.Search(len(hs), func(i int) bool { return hs[i].Timestamp > t })
+			if hi > lo {
+				windows = append(windows, window{t, histogramIncrease(hs[max(lo-1, 0):hi])})
+			}
+		}
+	}
+
+	out := make([]*pb.TimeSeries, 0, len(quantiles))
+	for _, q := range quantiles {
+		labels := make(map[string]string, len(metric.Labels)+1)
+		for k, v := range metric.Labels {
+			labels[k] = v
+		}
+		labels[quantileLabel] = strconv.FormatFloat(q, 'g', -1, 64)
+		ts := &pb.TimeSeries{Metric: &pb.Metric{Name: metric.Name, Labels: labels}}
+		for _, w := range windows {
+			if v, ok := histogramQuantile(q, w.observed); ok {
+				ts.Samples = append(ts.Samples, &pb.Sample{Timestamp: w.t, Value: v})
+			}
+		}
+		if len(ts.Samples) > 0 {
+			out = append(out, ts)
+		}
+	}
+	return out
+}
+
+// histogramIncrease returns the observations recorded from the first to
+// the last of hs, which are sorted by time. A drop in count is a counter
+// reset, after which the counts start over from zero; a change of bucket
+// layout also starts over, discarding what came before. A single sample
+// has no baseline and counts as everything it has recorded.
+func histogramIncrease(hs []*pb.HistogramSample) *pb.HistogramSample {
+	last := hs[len(hs)-1]
+	total := &pb.HistogramSample{Timestamp: last.Timestamp, Bounds: last.Bounds, Counts: make([]uint64, len(last.Bounds))}
+	if len(hs) == 1 {
+		copy(total.Counts, last.Counts)
+		total.Sum, total.Count = last.Sum, last.Count
+		return total
+	}
+	for i := 1; i < len(hs); i++ {
+		prev, cur := hs[i-1], hs[i]
+		if !slices.Equal(cur.Bounds, last.Bounds) {
+			continue
+		}
+		if !slices.Equal(prev.Bounds, cur.Bounds) {
+			// The layout changed: nothing before this sample is comparable.
+			clear(total.Counts)
+			total.Sum, total.Count = 0, 0
+			continue
+		}
+		if cur.Count < prev.Count {
+			prev = &pb.HistogramSample{Counts: make([]uint64, len(cur.Counts))}
+		}
+		for b := range cur.Counts {
+			if b < len(prev.Counts) && cur.Counts[b] >= prev.Counts[b] {
+				total.Counts[b] += cur.Counts[b] - prev.Counts[b]
+			} else {
+				total.Counts[b] += cur.Counts[b]
+			}
+		}
+		total.Sum += cur.Sum - prev.Sum
+		total.Count += cur.Count - prev.Count
+	}
+	return total
+}
+
+// histogramQuantile estimates the q-quantile of the observations in h by
+// interpolating linearly within the bucket it falls in, the way
+// Prometheus' histogram_quantile does. The first bucket is taken to start
+// at 0 unless its bound is negative, and a quantile beyond the last bound
+// is reported as that bound. It returns false when h is empty.
+func histogramQuantile(q float64, h *pb.HistogramSample) (float64, bool) {
+	if h.Count == 0 || len(h.Bounds) == 0 {
+		return 0, false
+	}
+	rank := q * float64(h.Count)
+	var seen float64
+	for i, bound := range h.Bounds {
+		n := float64(h.Counts[i])
+		if seen+n < rank || n == 0 {
+			seen += n
+			continue
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = h.Bounds[i-1]
+		} else if bound <= 0 {
+			return bound, true
+		}
+		return lower + (bound-lower)*(rank-seen)/n, true
+	}
+	return h.Bounds[len(h.Bounds)-1], true
+}
+
+// emitHistogramChunks is emitChunks for histogram samples.
+func emitHistogramChunks(emit emitFunc, metric *pb.Metric, hs []*pb.HistogramSample) error {
+	for start := 0; start < len(hs); start += queryChunkSamples {
+		end := min(start+queryChunkSamples, len(hs))
+		chunk := &pb.TimeSeries{Metric: metric, Histograms: hs[start:end:end]}
+		if err := emit(0, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// histogramRange returns the sorted histogram samples within [start, end];
+// end <= 0 means no upper bound. Stored histograms are never modified, so
+// they are shared rather than copied.
+func histogramRange(hs []*pb.HistogramSample, start, end int64) []*pb.HistogramSample {
+	lo := sort.Search(len(hs), func(i int) bool { return hs[i].Timestamp >= start })
+	hi := len(hs)
+	if end > 0 {
+		hi = sort.Search(len(hs), func(i int) bool { return hs[i].Timestamp > end })
+	}
+	if lo >= hi {
+		return nil
+	}
+	return slices.Clone(hs[lo:hi])
+}
+
+// hasHistograms is hasSamples for histogram samples.
+func hasHistograms(hs []*pb.HistogramSample, start, end int64) bool {
+	i := sort.Search(len(hs), func(i int) bool { return hs[i].Timestamp >= start })
+	return i < len(hs) && (end <= 0 || hs[i].Timestamp <= end)
+}
+
+// validHistogram checks the invariants the quantile estimation relies on.
+func validHistogram(h *pb.HistogramSample) error {
+	if len(h.Bounds) != len(h.Counts) {
+		return fmt.Errorf("histogram has %d bounds but %d counts", len(h.Bounds), len(h.Counts))
+	}
+	var n uint64
+	for i, b := range h.Bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) || (i > 0 && b <= h.Bounds[i-1]) {
+			return fmt.Errorf("histogram bounds must be finite and increasing")
+		}
+		n += h.Counts[i]
+	}
+	if n > h.Count {
+		return fmt.Errorf("histogram buckets hold %d observations but count is %d", n, h.Count)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import "sort"
+
+// labelCollector gathers the distinct label names of the series passed to
+// add, or the values of one label when it is set. Values of __name__ are the
+// metric names.
+type labelCollector struct {
+	label  string
+	seen   map[string]bool
+	result []string
+}
+
+func newLabelCollector(label string) *labelCollector {
+	return &labelCollector{label: label, seen: make(map[string]bool)}
+}
+
+func (c *labelCollector) add(name string, labels map[string]string) {
+	switch c.label {
+	case "":
+		for k := range labels {
+			c.put(k)
+		}
+	case metricNameLabel:
+		c.put(name)
+	default:
+		c.put(labels[c.label])
+	}
+}
+
+func (c *labelCollector) put(v string) {
+	if v != "" && !c.seen[v] {
+		c.seen[v] = true
+		c.result = append(c.result, v)
+	}
+}
+
+func (c *labelCollector) sorted() []string {
+	sort.Strings(c.result)
+	return c.result
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	pb "pmts/proto"
+)
+
+// metricNameLabel is the pseudo-label that matches against the metric name
+// column instead of the labels JSONB, as in Prometheus selectors.
+const metricNameLabel = "__name__"
+
+// appendMatchersSQL adds one AND clause per matcher to query, numbering the
+// placeholders after the existing args. The clauses refer to the series
+// table's metric_name and labels columns. Equality on a non-empty value is
+// written as a JSONB containment test so it can use the GIN index on labels;
+// everything else compares the extracted text value, treating a missing label
+// as the empty string. Regexes are translated for Postgres by postgresRegex.
+func appendMatchersSQL(query string, args []interface{}, matchers []*pb.LabelMatcher) (string, []interface{}, error) {
+	for _, m := range matchers {
+		if err := validateMatcher(m); err != nil {
+			return "", nil, err
+		}
+		value := m.Value
+		if m.Type == pb.LabelMatcher_RE || m.Type == pb.LabelMatcher_NRE {
+			re, _ := parseRegex(value)
+			value = "^(?:" + postgresRegex(re) + ")$"
+		}
+
+		var column string
+		if m.Name == metricNameLabel {
+			column = "metric_name"
+		} else {
+			if m.Type == pb.LabelMatcher_EQ && m.Value != "" {
+				contains, _ := json.Marshal(map[string]string{m.Name: m.Value})
+				args = append(args, string(contains))
+				query += " AND labels @> $" + itoa(len(args)) + "::jsonb"
+				continue
+			}
+			args = append(args, m.Name)
+			column = "COALESCE(labels->>$" + itoa(len(args)) + ", '')"
+		}
+
+		var op string
+		switch m.Type {
+		case pb.LabelMatcher_EQ:
+			op = "="
+		case pb.LabelMatcher_NEQ:
+			op = "<>"
+		case pb.LabelMatcher_RE:
+			op = "~"
+		case pb.LabelMatcher_NRE:
+			op = "!~"
+		}
+		args = append(args, value)
+		query += " AND " + column + " " + op + " $" + itoa(len(args))
+	}
+	return query, args, nil
+}
+
+// labelMatcher is a compiled matcher for evaluating label sets in Go, used
+// where the set of series is already in memory.
+type labelMatcher struct {
+	*pb.LabelMatcher
+	re *regexp.Regexp
+}
+
+func compileMatchers(matchers []*pb.LabelMatcher) ([]labelMatcher, error) {
+	compiled := make([]labelMatcher, 0, len(matchers))
+	for _, m := range matchers {
+		if err := validateMatcher(m); err != nil {
+			return nil, err
+		}
+		lm := labelMatcher{LabelMatcher: m}
+		if m.Type == pb.LabelMatcher_RE || m.Type == pb.LabelMatcher_NRE {
+			re, err := regexp.Compile("^(?:" + m.Value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex for label %q: %w", m.Name, err)
+			}
+			lm.re = re
+		}
+		compiled = append(compiled, lm)
+	}
+	return compiled, nil
+}
+
+// validateMatcher is the check every backend makes of a matcher, so that
+// one refused by Postgres is refused by the others too.
+func validateMatcher(m *pb.LabelMatcher) error {
+	if m.Name == "" {
+		return fmt.Errorf("label matcher with empty name")
+	}
+	switch m.Type {
+	case pb.LabelMatcher_EQ, pb.LabelMatcher_NEQ:
+	case pb.LabelMatcher_RE, pb.LabelMatcher_NRE:
+		if _, err := parseRegex(m.Value); err != nil {
+			return fmt.Errorf("invalid regex for label %q: %w", m.Name, err)
+		}
+	default:
+		return fmt.Errorf("unknown matcher type %v", m.Type)
+	}
+	return nil
+}
+
+// parseRegex parses a matcher's regex with Go's syntax, refusing what
+// postgresRegex cannot translate: word boundaries, whose idea of a word
+// differs between the two engines, and anchors made to match at line
+// breaks with the m flag.
+func parseRegex(value string) (*syntax.Regexp, error) {
+	re, err := syntax.Parse(value, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	var check func(re *syntax.Regexp) error
+	check = func(re *syntax.Regexp) error {
+		switch re.Op {
+		case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+			return fmt.Errorf("word boundaries are not supported")
+		case syntax.OpBeginLine, syntax.OpEndLine:
+			return fmt.Errorf("multi-line anchors are not supported")
+		}
+		for _, sub := range re.Sub {
+			if err := check(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := check(re); err != nil {
+		return nil, err
+	}
+	return re.Simplify(), nil
+}
+
+// postgresRegex writes a parsed regex as a Postgres advanced regular
+// expression matching the same strings. Go's RE2 syntax and Postgres
+// disagree on escapes inside brackets, flags, backreferences and more, so
+// rather than pass the pattern through, it is rebuilt from the parse tree
+// out of constructs both read alike: classes, \d and case folding become
+// explicit bracket expressions, every character but ASCII letters and
+// digits is written as a \u or \U escape, and repeats are already
+// expanded by Simplify. Greediness is dropped, since it cannot change
+// whether a string matches.
+func postgresRegex(re *syntax.Regexp) string {
+	var b strings.Builder
+	writeRegex(&b, re)
+	return b.String()
+}
+
+func writeRegex(b *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		// Nothing follows the end of the text.
+		b.WriteString("$a")
+	case syntax.OpEmptyMatch:
+		b.WriteString("(?:)")
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase == 0 {
+				writeRegexRune(b, r)
+				continue
+			}
+			folds := []rune{r}
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				folds = append(folds, f)
+			}
+			slices.Sort(folds)
+			var ranges []rune
+			for _, f := range folds {
+				ranges = append(ranges, f, f)
+			}
+			writeRegexClass(b, ranges)
+		}
+	case syntax.OpCharClass:
+		writeRegexClass(b, re.Rune)
+	case syntax.OpAnyCharNotNL:
+		writeRegexClass(b, []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune})
+	case syntax.OpAnyChar:
+		writeRegexClass(b, []rune{0, unicode.MaxRune})
+	case syntax.OpBeginText:
+		b.WriteString("^")
+	case syntax.OpEndText:
+		b.WriteString("$")
+	case syntax.OpCapture:
+		writeRegexGroup(b, re.Sub[0], "")
+	case syntax.OpStar:
+		writeRegexGroup(b, re.Sub[0], "*")
+	case syntax.OpPlus:
+		writeRegexGroup(b, re.Sub[0], "+")
+	case syntax.OpQuest:
+		writeRegexGroup(b, re.Sub[0], "?")
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			writeRegex(b, sub)
+		}
+	case syntax.OpAlternate:
+		b.WriteString("(?:")
+		for i, sub := range re.Sub {
+			if i > 0 {
+				b.WriteString("|")
+			}
+			writeRegex(b, sub)
+		}
+		b.WriteString(")")
+	}
+}
+
+// writeRegexGroup writes re as a non-capturing group followed by op.
+func writeRegexGroup(b *strings.Builder, re *syntax.Regexp, op string) {
+	b.WriteString("(?:")
+	writeRegex(b, re)
+	b.WriteString(")" + op)
+}
+
+// writeRegexClass writes ranges, pairs of inclusive bounds as in
+// syntax.Regexp.Rune, as a bracket expression. Postgres text never holds
+// NUL, so ranges start at 1 rather than write it into the pattern.
+func writeRegexClass(b *strings.Builder, ranges []rune) {
+	var kept []rune
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := max(ranges[i], 1), ranges[i+1]
+		if lo <= hi {
+			kept = append(kept, lo, hi)
+		}
+	}
+	if len(kept) == 0 {
+		writeRegex(b, &syntax.Regexp{Op: syntax.OpNoMatch})
+		return
+	}
+	b.WriteString("[")
+	for i := 0; i < len(kept); i += 2 {
+		writeRegexRune(b, kept[i])
+		if kept[i+1] != kept[i] {
+			b.WriteString("-")
+			writeRegexRune(b, kept[i+1])
+		}
+	}
+	b.WriteString("]")
+}
+
+func writeRegexRune(b *strings.Builder, r rune) {
+	switch {
+	case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+		b.WriteRune(r)
+	case r <= 0xFFFF:
+		fmt.Fprintf(b, `\u%04X`, r)
+	default:
+		fmt.Fprintf(b, `\U%08X`, r)
+	}
+}
+
+// matchSeries reports whether a series satisfies every matcher, with the
+// same missing-label-is-empty semantics as appendMatchersSQL.
+func matchSeries(matchers []labelMatcher, name string, labels map[string]string) bool {
+	for _, m := range matchers {
+		value := labels[m.Name]
+		if m.Name == metricNameLabel {
+			value = name
+		}
+		var ok bool
+		switch m.Type {
+		case pb.LabelMatcher_EQ:
+			ok = value == m.Value
+		case pb.LabelMatcher_NEQ:
+			ok = value != m.Value
+		case pb.LabelMatcher_RE:
+			ok = m.re.MatchString(value)
+		case pb.LabelMatcher_NRE:
+			ok = !m.re.MatchString(value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// storedMatcher is the JSONB representation of a matcher in tables that
+// persist selectors.
+type storedMatcher struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func marshalMatchers(matchers []*pb.LabelMatcher) ([]byte, error) {
+	stored := make([]storedMatcher, 0, len(matchers))
+	for _, m := range matchers {
+		stored = append(stored, storedMatcher{Type: m.Type.String(), Name: m.Name, Value: m.Value})
+	}
+	return json.Marshal(stored)
+}
+
+func unmarshalMatchers(data []byte) ([]*pb.LabelMatcher, error) {
+	var stored []storedMatcher
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	matchers := make([]*pb.LabelMatcher, 0, len(stored))
+	for _, sm := range stored {
+		typ, ok := pb.LabelMatcher_Type_value[sm.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown matcher type %q", sm.Type)
+		}
+		matchers = append(matchers, &pb.LabelMatcher{
+			Type:  pb.LabelMatcher_Type(typ),
+			Name:  sm.Name,
+			Value: sm.Value,
+		})
+	}
+	return matchers, nil
+}
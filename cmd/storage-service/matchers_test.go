@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	pb "pmts/proto"
+)
+
+func TestPostgresRegex(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		// wantErr is part of the error, for patterns that are refused.
+		wantErr string
+	}{
+		{pattern: "api", want: "api"},
+		{pattern: "a.b", want: `a[\u0001-\u0009\u000B-\U0010FFFF]b`},
+		{pattern: "(?s)a.b", want: `a[\u0001-\U0010FFFF]b`},
+		{pattern: `web-\d+`, want: `web\u002D(?:[0-9])+`},
+		// RE2 reads \d inside brackets as the class; Postgres could not.
+		{pattern: `[\d_]`, want: `[0-9\u005F]`},
+		{pattern: `[^a]`, want: `[\u0001-\u0060b-\U0010FFFF]`},
+		// Including the Kelvin sign, which folds to k.
+		{pattern: "(?i)ok", want: `[Oo][Kk\u212A]`},
+		{pattern: "a|b|", want: "(?:[a-b]|(?:))"},
+		{pattern: "(prod|dev)-.*", want: `(?:(?:prod|dev))\u002D(?:[\u0001-\u0009\u000B-\U0010FFFF])*`},
+		{pattern: "x{2,3}", want: "xx(?:x)?"},
+		{pattern: "a+?", want: "(?:a)+"},
+		{pattern: "", want: "(?:)"},
+		{pattern: "é", want: `\u00E9`},
+		{pattern: `\x{1F600}`, want: `\U0001F600`},
+		{pattern: `^a$`, want: "^a$"},
+		{pattern: `\bword`, wantErr: "word boundaries"},
+		{pattern: `(?m)^a`, wantErr: "multi-line"},
+		{pattern: `(a`, wantErr: "missing closing )"},
+		{pattern: `(a)\1`, wantErr: "invalid escape"},
+	}
+	for _, tt := range tests {
+		re, err := parseRegex(tt.pattern)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: error %v, want one about %q", tt.pattern, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.pattern, err)
+			continue
+		}
+		if got := postgresRegex(re); got != tt.want {
+			t.Errorf("%q translated to %s, want %s", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestValidateMatchers(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher *pb.LabelMatcher
+		wantErr bool
+	}{
+		{"equal", &pb.LabelMatcher{Type: pb.LabelMatcher_EQ, Name: "job", Value: "api"}, false},
+		{"empty value", &pb.LabelMatcher{Type: pb.LabelMatcher_NEQ, Name: "job"}, false},
+		{"regex", &pb.LabelMatcher{Type: pb.LabelMatcher_RE, Name: "job", Value: "api|web"}, false},
+		{"empty name", &pb.LabelMatcher{Type: pb.LabelMatcher_EQ, Value: "api"}, true},
+		{"bad regex", &pb.LabelMatcher{Type: pb.LabelMatcher_NRE, Name: "job", Value: "("}, true},
+		{"untranslatable regex", &pb.LabelMatcher{Type: pb.LabelMatcher_RE, Name: "job", Value: `\bapi`}, true},
+		{"unknown type", &pb.LabelMatcher{Type: 9, Name: "job"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := []*pb.LabelMatcher{tt.matcher}
+			_, _, sqlErr := appendMatchersSQL("TRUE", nil, list)
+			_, goErr := compileMatchers(list)
+			if (sqlErr != nil) != tt.wantErr || (goErr != nil) != tt.wantErr {
+				t.Errorf("SQL error %v, Go error %v, want errors %v from both", sqlErr, goErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// memStorage keeps everything in process memory. Nothing survives a
+// restart, so it is meant for tests and local development rather than
+// production.
+type memStorage struct {
+	mu            sync.RWMutex
+	retentionDays int
+	policy        conflictPolicy
+
+	users      []memUser
+	series     map[seriesKey]*memSeries
+	nextSeries int64
+	rules      []*pb.AlertRule
+	nextRule   int64
+	policies   []*pb.RetentionPolicy
+	nextPolicy int64
+	metadata   map[metadataKey]*pb.MetricMetadata
+}
+
+type metadataKey struct {
+	userID int64
+	name   string
+}
+
+type memUser struct {
+	id     int64
+	email  string
+	apiKey string
+}
+
+type memSeries struct {
+	id     int64
+	userID int64
+	metric *pb.Metric
+	// samples and histograms are kept sorted by timestamp.
+	samples    []*pb.Sample
+	histograms []*pb.HistogramSample
+}
+
+// hasDataIn reports whether the series has float or histogram samples
+// within [start, end]; end <= 0 means no upper bound.
+func (s *memSeries) hasDataIn(start, end int64) bool {
+	return hasSamples(s.samples, start, end) || hasHistograms(s.histograms, start, end)
+}
+
+func newMemStorage(retentionDays int, policy conflictPolicy) *memStorage {
+	s := &memStorage{
+		retentionDays: retentionDays,
+		policy:        policy,
+		series:        make(map[seriesKey]*memSeries),
+		metadata:      make(map[metadataKey]*pb.MetricMetadata),
+	}
+	// Same fixture as the Postgres backend's SEED_DATA.
+	if os.Getenv("SEED_DATA") == "true" {
+		s.users = append(s.users, memUser{id: 1, email: "dev@datacat.com", apiKey: "sk_live_12345"})
+		s.nextRule++
+		s.rules = append(s.rules, &pb.AlertRule{RuleId: s.nextRule, UserId: 1, MetricName: "system_cpu_percent", Threshold: 90.0})
+	}
+	return s
+}
+
+func (s *memStorage) Start(logger *slog.Logger) {
+	ticker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range ticker.C {
+			if err := s.applyRetention(time.Now().Unix()); err != nil {
+				logger.Error("Retention cleanup failed", "error", err)
+			}
+		}
+	}()
+}
+
+func (s *memStorage) Close() error {
+	return nil
+}
+
+// applyRetention drops samples older than the retention each series
+// resolves to under its owner's policies.
+func (s *memStorage) applyRetention(now int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resolvers := make(map[int64]*retentionResolver)
+	for _, series := range s.series {
+		resolver, ok := resolvers[series.userID]
+		if !ok {
+			var err error
+			resolver, err = newRetentionResolver(s.userPolicies(series.userID), s.retentionDays)
+			if err != nil {
+				return err
+			}
+			resolvers[series.userID] = resolver
+		}
+		cutoff := now - int64(resolver.days(series.metric.Name, series.metric.Labels)*86400)
+		i := sort.Search(len(series.samples), func(i int) bool { return series.samples[i].Timestamp >= cutoff })
+		series.samples = series.samples[i:]
+		i = sort.Search(len(series.histograms), func(i int) bool { return series.histograms[i].Timestamp >= cutoff })
+		series.histograms = series.histograms[i:]
+	}
+	return nil
+}
+
+func (s *memStorage) CreateUser(ctx context.Context, email, apiKey string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.email == email {
+			return 0, fmt.Errorf("user %q already exists", email)
+		}
+	}
+	id := int64(len(s.users) + 1)
+	s.users = append(s.users, memUser{id: id, email: email, apiKey: apiKey})
+	return id, nil
+}
+
+func (s *memStorage) LookupAPIKey(ctx context.Context, apiKey string) (int64, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if u.apiKey == apiKey {
+			return u.id, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (s *memStorage) AppendBatches(ctx context.Context, batches []userBatch) (AppendResult, int, error) {
+	return appendEach(ctx, batches, s.AppendSamples)
+}
+
+func (s *memStorage) AppendSamples(ctx context.Context, userID int64, list []*pb.TimeSeries) (AppendResult, error) {
+	return s.appendSamples(userID, list, s.policy)
+}
+
+func (s *memStorage) ImportSamples(ctx context.Context, userID int64, list []*pb.TimeSeries, policy conflictPolicy) (AppendResult, error) {
+	return s.appendSamples(userID, list, policy)
+}
+
+func (s *memStorage) appendSamples(userID int64, list []*pb.TimeSeries, policy conflictPolicy) (AppendResult, error) {
+	if err := validateBatch(list); err != nil {
+		return AppendResult{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Merge everything first so a rejected batch changes nothing. A series
+	// may appear more than once in a batch, so merges build on each other.
+	type pending struct {
+		samples    []*pb.Sample
+		histograms []*pb.HistogramSample
+	}
+	batch := make(map[*memSeries]*pending)
+	var res AppendResult
+	for _, ts := range list {
+		if len(ts.Samples) == 0 && len(ts.Histograms) == 0 {
+			continue
+		}
+		series := s.getOrCreateSeries(userID, ts.Metric)
+		p, ok := batch[series]
+		if !ok {
+			p = &pending{series.samples, series.histograms}
+			batch[series] = p
+		}
+		var (
+			samples        []*pb.Sample
+			hists          []*pb.HistogramSample
+			dups, histDups int
+		)
+		p.samples, samples, dups = mergeSamples(p.samples, ts.Samples, policy)
+		p.histograms, hists, histDups = mergeSamples(p.histograms, ts.Histograms, policy)
+		res.Stored += len(samples) + len(hists)
+		res.Duplicates += dups + histDups
+	}
+	if policy == rejectConflicts && res.Duplicates > 0 {
+		return AppendResult{Duplicates: res.Duplicates}, &duplicatesError{res.Duplicates}
+	}
+	for series, p := range batch {
+		series.samples, series.histograms = p.samples, p.histograms
+	}
+	return res, nil
+}
+
+func (s *memStorage) getOrCreateSeries(userID int64, metric *pb.Metric) *memSeries {
+	key := seriesKey{userID: userID, name: metric.Name, hash: labelsHash(metric.Labels)}
+	series, ok := s.series[key]
+	if !ok {
+		labels := make(map[string]string, len(metric.Labels))
+		for k, v := range metric.Labels {
+			labels[k] = v
+		}
+		s.nextSeries++
+		series = &memSeries{id: s.nextSeries, userID: userID, metric: &pb.Metric{Name: metric.Name, Labels: labels}}
+		s.series[key] = series
+	}
+	return series
+}
+
+// userSeries returns a user's series in creation order.
+func (s *memStorage) userSeries(userID int64) []*memSeries {
+	var result []*memSeries
+	for _, series := range s.series {
+		if series.userID == userID {
+			result = append(result, series)
+		}
+	}
+	slices.SortFunc(result, func(a, b *memSeries) int { return int(a.id - b.id) })
+	return result
+}
+
+func (s *memStorage) QuerySeries(ctx context.Context, q *SeriesQuery, emit emitFunc) error {
+	matchers, err := compileMatchers(q.Matchers)
+	if err != nil {
+		return err
+	}
+	step := queryStep(q, time.Now().Unix())
+	start := q.Start
+	if step > 0 {
+		start = alignDown(start, step)
+	}
+
+	// Copy the matches out so emit, which may be writing to a slow
+	// client, never runs with the lock held.
+	s.mu.RLock()
+	var result []*pb.TimeSeries
+	for _, series := range s.userSeries(q.UserID) {
+		if q.Name != "" && series.metric.Name != q.Name {
+			continue
+		}
+		if !matchSeries(matchers, series.metric.Name, series.metric.Labels) {
+			continue
+		}
+		if samples := sampleRange(series.samples, start, q.End); len(samples) > 0 {
+			result = append(result, &pb.TimeSeries{Metric: series.metric, Samples: samples})
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, ts := range result {
+		if step > 0 {
+			if ts.Samples, err = aggregateSamples(ts.Samples, step, q.Aggregation); err != nil {
+				return err
+			}
+		}
+		if err := emitChunks(emit, step, ts.Metric, ts.Samples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memStorage) QueryHistograms(ctx context.Context, q *SeriesQuery, emit emitFunc) error {
+	matchers, err := compileMatchers(q.Matchers)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	var result []*pb.TimeSeries
+	for _, series := range s.userSeries(q.UserID) {
+		if q.Name != "" && series.metric.Name != q.Name {
+			continue
+		}
+		if !matchSeries(matchers, series.metric.Name, series.metric.Labels) {
+			continue
+		}
+		if hs := histogramRange(series.histograms, q.Start, q.End); len(hs) > 0 {
+			result = append(result, &pb.TimeSeries{Metric: series.metric, Histograms: hs})
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, ts := range result {
+		if err := emitHistogramChunks(emit, ts.Metric, ts.Histograms); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampleRange returns copies of the sorted samples within [start, end];
+// end <= 0 means no upper bound.
+func sampleRange(samples []*pb.Sample, start, end int64) []*pb.Sample {
+	lo := sort.Search(len(samples), func(i int) bool { return samples[i].Timestamp >= start })
+	hi := len(samples)
+	if end > 0 {
+		hi = sort.Search(len(samples), func(i int) bool { return samples[i].Timestamp > end })
+	}
+	if lo >= hi {
+		return nil
+	}
+	out := make([]*pb.Sample, 0, hi-lo)
+	for _, sample := range samples[lo:hi] {
+		out = append(out, &pb.Sample{Timestamp: sample.Timestamp, Value: sample.Value})
+	}
+	return out
+}
+
+// hasSamples reports whether any of the sorted samples fall within [start,
+// end]; end <= 0 means no upper bound.
+func hasSamples(samples []*pb.Sample, start, end int64) bool {
+	i := sort.Search(len(samples), func(i int) bool { return samples[i].Timestamp >= start })
+	return i < len(samples) && (end <= 0 || samples[i].Timestamp <= end)
+}
+
+func (s *memStorage) ListMetricNames(ctx context.Context, userID int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, series := range s.series {
+		if series.userID != userID || (len(series.samples) == 0 && len(series.histograms) == 0) {
+			continue
+		}
+		if !seen[series.metric.Name] {
+			seen[series.metric.Name] = true
+			names = append(names, series.metric.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *memStorage) LabelNames(ctx context.Context, q *SeriesQuery) ([]string, error) {
+	return s.collectLabels(q, newLabelCollector(""))
+}
+
+func (s *memStorage) LabelValues(ctx context.Context, q *SeriesQuery, label string) ([]string, error) {
+	return s.collectLabels(q, newLabelCollector(label))
+}
+
+func (s *memStorage) collectLabels(q *SeriesQuery, c *labelCollector) ([]string, error) {
+	matchers, err := compileMatchers(q.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, series := range s.series {
+		if series.userID != q.UserID || (q.Name != "" && series.metric.Name != q.Name) {
+			continue
+		}
+		if matchSeries(matchers, series.metric.Name, series.metric.Labels) && series.hasDataIn(q.Start, q.End) {
+			c.add(series.metric.Name, series.metric.Labels)
+		}
+	}
+	return c.sorted(), nil
+}
+
+func (s *memStorage) UsageStats(ctx context.Context, userID, since int64) ([]*pb.MetricUsage, []*pb.LabelUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u := newUsageCollector()
+	for _, series := range s.series {
+		if series.userID != userID || len(series.samples)+len(series.histograms) == 0 {
+			continue
+		}
+		first, last := int64(math.MaxInt64), int64(math.MinInt64)
+		var recent, bytes int64
+		if n := len(series.samples); n > 0 {
+			first, last = series.samples[0].Timestamp, series.samples[n-1].Timestamp
+			i := sort.Search(n, func(i int) bool { return series.samples[i].Timestamp >= since })
+			recent, bytes = int64(n-i), int64(n-i)*rawSampleBytes
+		}
+		if n := len(series.histograms); n > 0 {
+			first, last = min(first, series.histograms[0].Timestamp), max(last, series.histograms[n-1].Timestamp)
+			i := sort.Search(n, func(i int) bool { return series.histograms[i].Timestamp >= since })
+			for _, h := range series.histograms[i:] {
+				recent++
+				bytes += rawHistogramBytes(h)
+			}
+		}
+		u.add(series.metric, first, last, recent, bytes)
+	}
+	metrics, labels := u.result()
+	return metrics, labels, nil
+}
+
+func (s *memStorage) CountWritten(ctx context.Context, userID, since int64) (WrittenCounts, error) {
+	return countWritten(ctx, s, userID, since)
+}
+
+func (s *memStorage) DeleteMetric(ctx context.Context, userID int64, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.series {
+		if key.userID == userID && key.name == name {
+			delete(s.series, key)
+		}
+	}
+	delete(s.metadata, metadataKey{userID, name})
+	s.rules = slices.DeleteFunc(s.rules, func(r *pb.AlertRule) bool {
+		return r.UserId == userID && r.MetricName == name
+	})
+	return nil
+}
+
+func (s *memStorage) DeleteSeries(ctx context.Context, q *SeriesQuery, dryRun bool) (DeleteResult, error) {
+	matchers, err := compileMatchers(q.Matchers)
+	if err != nil {
+		return DeleteResult{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res DeleteResult
+	for key, series := range s.series {
+		if series.userID != q.UserID || (q.Name != "" && series.metric.Name != q.Name) {
+			continue
+		}
+		if !matchSeries(matchers, series.metric.Name, series.metric.Labels) || !series.hasDataIn(q.Start, q.End) {
+			continue
+		}
+		samples := slices.DeleteFunc(slices.Clone(series.samples), func(s *pb.Sample) bool {
+			return inRange(s.Timestamp, q.Start, q.End)
+		})
+		histograms := slices.DeleteFunc(slices.Clone(series.histograms), func(h *pb.HistogramSample) bool {
+			return inRange(h.Timestamp, q.Start, q.End)
+		})
+		res.Series++
+		res.Samples += int64(len(series.samples) - len(samples) + len(series.histograms) - len(histograms))
+		if dryRun {
+			continue
+		}
+		if len(samples) == 0 && len(histograms) == 0 {
+			delete(s.series, key)
+			continue
+		}
+		series.samples, series.histograms = samples, histograms
+	}
+	return res, nil
+}
+
+func (s *memStorage) SetMetadata(ctx context.Context, userID int64, list []*pb.MetricMetadata) error {
+	s.mergeMetadata(userID, list)
+	return nil
+}
+
+// mergeMetadata applies SetMetadata and reports whether anything changed.
+func (s *memStorage) mergeMetadata(userID int64, list []*pb.MetricMetadata) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for _, md := range list {
+		if md.MetricName == "" {
+			continue
+		}
+		key := metadataKey{userID, md.MetricName}
+		stored, ok := s.metadata[key]
+		if !ok {
+			stored = &pb.MetricMetadata{MetricName: md.MetricName}
+			s.metadata[key] = stored
+			changed = true
+		}
+		if mergeMetadata(stored, md) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (s *memStorage) GetMetadata(ctx context.Context, userID int64, name string) ([]*pb.MetricMetadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var list []*pb.MetricMetadata
+	for key, md := range s.metadata {
+		if key.userID == userID && (name == "" || key.name == name) {
+			list = append(list, &pb.MetricMetadata{MetricName: md.MetricName, Type: md.Type, Unit: md.Unit, Help: md.Help})
+		}
+	}
+	slices.SortFunc(list, func(a, b *pb.MetricMetadata) int { return strings.Compare(a.MetricName, b.MetricName) })
+	return list, nil
+}
+
+func (s *memStorage) CreateAlertRule(ctx context.Context, rule *pb.AlertRule) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRule++
+	stored := &pb.AlertRule{
+		RuleId:     s.nextRule,
+		UserId:     rule.UserId,
+		MetricName: rule.MetricName,
+		Threshold:  rule.Threshold,
+		WebhookUrl: rule.WebhookUrl,
+	}
+	s.rules = append(s.rules, stored)
+	return stored.RuleId, nil
+}
+
+func (s *memStorage) GetAlertRules(ctx context.Context, userID int64) ([]*pb.AlertRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var rules []*pb.AlertRule
+	for _, r := range s.rules {
+		if userID == 0 || r.UserId == userID {
+			rules = append(rules, &pb.AlertRule{
+				RuleId:     r.RuleId,
+				UserId:     r.UserId,
+				MetricName: r.MetricName,
+				Threshold:  r.Threshold,
+				WebhookUrl: r.WebhookUrl,
+			})
+		}
+	}
+	return rules, nil
+}
+
+func (s *memStorage) DeleteAlertRule(ctx context.Context, userID, ruleID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.rules)
+	s.rules = slices.DeleteFunc(s.rules, func(r *pb.AlertRule) bool {
+		return r.RuleId == ruleID && r.UserId == userID
+	})
+	return len(s.rules) < n, nil
+}
+
+func (s *memStorage) CreateRetentionPolicy(ctx context.Context, policy *pb.RetentionPolicy) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextPolicy++
+	s.policies = append(s.policies, &pb.RetentionPolicy{
+		PolicyId:      s.nextPolicy,
+		UserId:        policy.UserId,
+		MetricName:    policy.MetricName,
+		Matchers:      policy.Matchers,
+		RetentionDays: policy.RetentionDays,
+	})
+	return s.nextPolicy, nil
+}
+
+func (s *memStorage) GetRetentionPolicies(ctx context.Context, userID int64) ([]*pb.RetentionPolicy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.userPolicies(userID), nil
+}
+
+// userPolicies returns one user's policies, or everyone's when userID is 0.
+// The caller must hold s.mu.
+func (s *memStorage) userPolicies(userID int64) []*pb.RetentionPolicy {
+	var policies []*pb.RetentionPolicy
+	for _, p := range s.policies {
+		if userID == 0 || p.UserId == userID {
+			policies = append(policies, p)
+		}
+	}
+	return policies
+}
+
+func (s *memStorage) DeleteRetentionPolicy(ctx context.Context, userID, policyID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.policies)
+	s.policies = slices.DeleteFunc(s.policies, func(p *pb.RetentionPolicy) bool {
+		return p.PolicyId == policyID && p.UserId == userID
+	})
+	return len(s.policies) < n, nil
+}
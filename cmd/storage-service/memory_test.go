@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	pb "pmts/proto"
+)
+
+// collect runs q and returns each series' values, keyed by its name and
+// labels.
+func collect(t *testing.T, s Storage, q *SeriesQuery) map[string][]float64 {
+	t.Helper()
+	out := make(map[string][]float64)
+	err := s.QuerySeries(context.Background(), q, func(_ int64, chunk *pb.TimeSeries) error {
+		key := chunk.Metric.Name + fmt.Sprint(chunk.Metric.Labels)
+		for _, smp := range chunk.Samples {
+			out[key] = append(out[key], smp.Value)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// memFixture holds cpu for two hosts and mem for one, for user 1, with
+// samples at base+0, +60, +120 and +180, and a cpu series for user 2.
+func memFixture(t *testing.T, base int64) *memStorage {
+	t.Helper()
+	s := newMemStorage(30, keepFirst)
+	at := func(values ...float64) []*pb.Sample {
+		out := make([]*pb.Sample, len(values))
+		for i, v := range values {
+			out[i] = &pb.Sample{Timestamp: base + int64(i)*60, Value: v}
+		}
+		return out
+	}
+	mustAppend(t, s,
+		testSeries("cpu", map[string]string{"host": "web-1", "env": "prod"}, at(1, 2, 3, 4)...),
+		testSeries("cpu", map[string]string{"host": "web-2", "env": "staging"}, at(10, 20, 30, 40)...),
+		testSeries("mem", map[string]string{"host": "web-1"}, at(5, 5, 5, 5)...),
+	)
+	if _, err := s.AppendSamples(context.Background(), 2, []*pb.TimeSeries{
+		testSeries("cpu", map[string]string{"host": "web-1", "env": "prod"}, at(100)...),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestMemStorageQuerySeries(t *testing.T) {
+	base := int64(1_700_000_160) // aligned to every step below
+	s := memFixture(t, base)
+	web1 := "cpu" + fmt.Sprint(map[string]string{"host": "web-1", "env": "prod"})
+	web2 := "cpu" + fmt.Sprint(map[string]string{"host": "web-2", "env": "staging"})
+	mem := "mem" + fmt.Sprint(map[string]string{"host": "web-1"})
+	matcher := func(typ pb.LabelMatcher_Type, name, value string) []*pb.LabelMatcher {
+		return []*pb.LabelMatcher{{Type: typ, Name: name, Value: value}}
+	}
+	tests := []struct {
+		name string
+		q    SeriesQuery
+		want map[string][]float64
+	}{
+		{"by name", SeriesQuery{Name: "cpu"},
+			map[string][]float64{web1: {1, 2, 3, 4}, web2: {10, 20, 30, 40}}},
+		{"everything", SeriesQuery{},
+			map[string][]float64{web1: {1, 2, 3, 4}, web2: {10, 20, 30, 40}, mem: {5, 5, 5, 5}}},
+		{"equal", SeriesQuery{Name: "cpu", Matchers: matcher(pb.LabelMatcher_EQ, "env", "prod")},
+			map[string][]float64{web1: {1, 2, 3, 4}}},
+		{"not equal", SeriesQuery{Name: "cpu", Matchers: matcher(pb.LabelMatcher_NEQ, "env", "prod")},
+			map[string][]float64{web2: {10, 20, 30, 40}}},
+		{"regex anchored", SeriesQuery{Matchers: matcher(pb.LabelMatcher_RE, "host", "web-2|db")},
+			map[string][]float64{web2: {10, 20, 30, 40}}},
+		{"negative regex", SeriesQuery{Matchers: matcher(pb.LabelMatcher_NRE, "host", "web-.*")}, map[string][]float64{}},
+		{"missing label is empty", SeriesQuery{Matchers: matcher(pb.LabelMatcher_EQ, "env", "")},
+			map[string][]float64{mem: {5, 5, 5, 5}}},
+		{"metric name label", SeriesQuery{Matchers: matcher(pb.LabelMatcher_RE, metricNameLabel, "m.*")},
+			map[string][]float64{mem: {5, 5, 5, 5}}},
+		{"time range", SeriesQuery{Name: "cpu", Start: base + 60, End: base + 120},
+			map[string][]float64{web1: {2, 3}, web2: {20, 30}}},
+		{"step sum", SeriesQuery{Name: "cpu", Matchers: matcher(pb.LabelMatcher_EQ, "env", "prod"), Start: base, Step: 120,
+			Aggregation: pb.GetMetricsRequest_SUM}, map[string][]float64{web1: {3, 7}}},
+		{"step max", SeriesQuery{Name: "cpu", Start: base, Step: 240, Aggregation: pb.GetMetricsRequest_MAX},
+			map[string][]float64{web1: {4}, web2: {40}}},
+		{"step count", SeriesQuery{Name: "mem", Start: base, Step: 120, Aggregation: pb.GetMetricsRequest_COUNT},
+			map[string][]float64{mem: {2, 2}}},
+		{"unknown metric", SeriesQuery{Name: "disk"}, map[string][]float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.q
+			q.UserID = 1
+			got := collect(t, s, &q)
+			if !maps.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemStorageDeleteSeries(t *testing.T) {
+	base := int64(1_700_000_160)
+	tests := []struct {
+		name   string
+		q      SeriesQuery
+		dryRun bool
+		want   DeleteResult
+		// left counts user 1's samples afterwards.
+		left int
+	}{
+		{"whole series", SeriesQuery{Name: "cpu", Matchers: []*pb.LabelMatcher{{Name: "host", Value: "web-2"}}},
+			false, DeleteResult{Series: 1, Samples: 4}, 8},
+		{"time range", SeriesQuery{Name: "cpu", Start: base + 60, End: base + 120},
+			false, DeleteResult{Series: 2, Samples: 4}, 8},
+		{"open end", SeriesQuery{Matchers: []*pb.LabelMatcher{{Name: "host", Value: "web-1"}}, Start: base + 120},
+			false, DeleteResult{Series: 2, Samples: 4}, 8},
+		{"dry run", SeriesQuery{Name: "cpu"}, true, DeleteResult{Series: 2, Samples: 8}, 12},
+		{"nothing in range", SeriesQuery{Name: "cpu", Start: base + 1000}, false, DeleteResult{}, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := memFixture(t, base)
+			if _, err := s.CreateAlertRule(ctx, &pb.AlertRule{UserId: 1, MetricName: "cpu", Threshold: 1}); err != nil {
+				t.Fatal(err)
+			}
+			q := tt.q
+			q.UserID = 1
+			res, err := s.DeleteSeries(ctx, &q, tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tt.want {
+				t.Errorf("result = %+v, want %+v", res, tt.want)
+			}
+			left := 0
+			for _, values := range collect(t, s, &SeriesQuery{UserID: 1}) {
+				left += len(values)
+			}
+			if left != tt.left {
+				t.Errorf("%d samples left, want %d", left, tt.left)
+			}
+			if got := collect(t, s, &SeriesQuery{UserID: 2}); len(got) != 1 {
+				t.Errorf("user 2's series = %v, want it untouched", got)
+			}
+			if rules, _ := s.GetAlertRules(ctx, 1); len(rules) != 1 {
+				t.Errorf("alert rules = %v, want them left alone", rules)
+			}
+		})
+	}
+}
+
+func TestMemStorageDeleteMetric(t *testing.T) {
+	ctx := context.Background()
+	s := memFixture(t, 1_700_000_160)
+	for _, rule := range []*pb.AlertRule{
+		{UserId: 1, MetricName: "cpu"}, {UserId: 1, MetricName: "mem"}, {UserId: 2, MetricName: "cpu"},
+	} {
+		if _, err := s.CreateAlertRule(ctx, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SetMetadata(ctx, 1, []*pb.MetricMetadata{{MetricName: "cpu", Unit: "percent"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteMetric(ctx, 1, "cpu"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := collect(t, s, &SeriesQuery{UserID: 1}); len(got) != 1 {
+		t.Errorf("user 1's series = %v, want only mem", got)
+	}
+	if got := collect(t, s, &SeriesQuery{UserID: 2, Name: "cpu"}); len(got) != 1 {
+		t.Errorf("user 2's cpu = %v, want it untouched", got)
+	}
+	if md, _ := s.GetMetadata(ctx, 1, "cpu"); len(md) != 0 {
+		t.Errorf("metadata = %v, want it deleted", md)
+	}
+	rules, _ := s.GetAlertRules(ctx, 0)
+	var left []string
+	for _, r := range rules {
+		left = append(left, fmt.Sprintf("%d/%s", r.UserId, r.MetricName))
+	}
+	if !slices.Equal(left, []string{"1/mem", "2/cpu"}) {
+		t.Errorf("alert rules left = %v, want [1/mem 2/cpu]", left)
+	}
+	names, _ := s.ListMetricNames(ctx, 1)
+	if !slices.Equal(names, []string{"mem"}) {
+		t.Errorf("metric names = %v, want [mem]", names)
+	}
+}
+
+func TestMemStorageLabels(t *testing.T) {
+	ctx := context.Background()
+	s := memFixture(t, 1_700_000_160)
+	tests := []struct {
+		q     SeriesQuery
+		label string
+		want  []string
+	}{
+		{SeriesQuery{}, "", []string{"env", "host"}},
+		{SeriesQuery{Name: "mem"}, "", []string{"host"}},
+		{SeriesQuery{}, "host", []string{"web-1", "web-2"}},
+		{SeriesQuery{Name: "cpu"}, "env", []string{"prod", "staging"}},
+		{SeriesQuery{Matchers: []*pb.LabelMatcher{{Name: "env", Value: "prod"}}}, "host", []string{"web-1"}},
+	}
+	for _, tt := range tests {
+		q := tt.q
+		q.UserID = 1
+		var got []string
+		var err error
+		if tt.label == "" {
+			got, err = s.LabelNames(ctx, &q)
+		} else {
+			got, err = s.LabelValues(ctx, &q, tt.label)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("labels %q of %+v = %v, want %v", tt.label, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestMemStorageUsers(t *testing.T) {
+	ctx := context.Background()
+	s := newMemStorage(30, keepFirst)
+	id, err := s.CreateUser(ctx, "a@example.com", "sk_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateUser(ctx, "a@example.com", "sk_b"); err == nil {
+		t.Error("created a second user with the same email")
+	}
+	for _, tt := range []struct {
+		key    string
+		wantID int64
+		wantOK bool
+	}{
+		{"sk_a", id, true},
+		{"sk_b", 0, false},
+		{"", 0, false},
+	} {
+		gotID, ok, err := s.LookupAPIKey(ctx, tt.key)
+		if err != nil || ok != tt.wantOK || (ok && gotID != tt.wantID) {
+			t.Errorf("LookupAPIKey(%q) = %d, %v, %v", tt.key, gotID, ok, err)
+		}
+	}
+}
+
+func TestMemStorageRetention(t *testing.T) {
+	ctx := context.Background()
+	now := int64(1_700_000_000)
+	s := newMemStorage(30, keepFirst)
+	if _, err := s.CreateRetentionPolicy(ctx, &pb.RetentionPolicy{UserId: 1, MetricName: "debug", RetentionDays: 2}); err != nil {
+		t.Fatal(err)
+	}
+	daysAgo := func(days ...int64) []*pb.Sample {
+		var out []*pb.Sample
+		for _, d := range days {
+			out = append(out, &pb.Sample{Timestamp: now - d*86400, Value: float64(d)})
+		}
+		return out
+	}
+	mustAppend(t, s, testSeries("debug", nil, daysAgo(40, 3, 1)...), testSeries("keep", nil, daysAgo(40, 3, 1)...))
+	if err := s.applyRetention(now); err != nil {
+		t.Fatal(err)
+	}
+	got := collect(t, s, &SeriesQuery{UserID: 1})
+	want := map[string][]float64{"debug" + fmt.Sprint(map[string]string(nil)): {1}, "keep" + fmt.Sprint(map[string]string(nil)): {3, 1}}
+	if !maps.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("after retention %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	pb "pmts/proto"
+)
+
+// GetMetadata returns what is known about a user's metrics.
+func (s *Server) GetMetadata(ctx context.Context, req *pb.GetMetadataRequest) (*pb.GetMetadataResponse, error) {
+	uid := req.UserId
+	if uid == 0 {
+		uid = 1
+	}
+	list, err := s.store.GetMetadata(ctx, uid, req.MetricName)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetMetadataResponse{Metadata: list}, nil
+}
+
+// mergeMetadata copies the fields src sets into dst and reports whether
+// that changed anything.
+func mergeMetadata(dst, src *pb.MetricMetadata) bool {
+	changed := false
+	if src.Type != pb.MetricMetadata_UNKNOWN && src.Type != dst.Type {
+		dst.Type, changed = src.Type, true
+	}
+	if src.Unit != "" && src.Unit != dst.Unit {
+		dst.Unit, changed = src.Unit, true
+	}
+	if src.Help != "" && src.Help != dst.Help {
+		dst.Help, changed = src.Help, true
+	}
+	return changed
+}
+
+// metricTypeName is how a metric type is stored: its lowercased enum name,
+// or "" when unknown.
+func metricTypeName(t pb.MetricMetadata_Type) string {
+	if t == pb.MetricMetadata_UNKNOWN {
+		return ""
+	}
+	return strings.ToLower(t.String())
+}
+
+func parseMetricType(name string) pb.MetricMetadata_Type {
+	return pb.MetricMetadata_Type(pb.MetricMetadata_Type_value[strings.ToUpper(name)])
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// migrationFiles holds the Postgres schema as numbered migrations named
+// NNNN_name.up.sql and NNNN_name.down.sql. Migrations written before
+// versioning existed use IF NOT EXISTS throughout, so a database created by
+// older releases adopts the history without changes.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID keys the advisory lock held while migrating, so replicas
+// starting together apply each migration once.
+const migrationLockID = 0x706d7473
+
+const createSchemaMigrationsSQL = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+
+type migration struct {
+	version  int64
+	name     string
+	up, down string
+}
+
+// migrationHooks run before a migration's up SQL, outside its transaction.
+// Databases from before the samples migration may hold samples in an older
+// layout, which is converted first; both steps are no-ops otherwise.
+var migrationHooks = map[int64]func(ctx context.Context, db *sql.DB) error{
+	2: upgradeLegacySamples,
+}
+
+// loadMigrations parses the embedded migrations, which must be numbered
+// from 1 without gaps and each have both an up and a down file.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*migration)
+	for _, e := range entries {
+		file := e.Name()
+		base, direction, _ := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(num, 10, 64)
+		if !ok || err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.up.sql or NNNN_name.down.sql", file)
+		}
+		body, err := fs.ReadFile(migrationFiles, "migrations/"+file)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		} else if m.name != name {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.name, name)
+		}
+		if direction == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+	list := make([]migration, 0, len(byVersion))
+	for i := int64(1); i <= int64(len(byVersion)); i++ {
+		m, ok := byVersion[i]
+		if !ok {
+			return nil, fmt.Errorf("migration %d is missing", i)
+		}
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d (%s) needs both an up and a down file", m.version, m.name)
+		}
+		list = append(list, *m)
+	}
+	return list, nil
+}
+
+// withMigrationLock runs fn holding the migration advisory lock on a
+// connection of its own, with schema_migrations created. fn gets that
+// connection and the versions applied so far.
+func withMigrationLock(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn, applied map[int64]time.Time) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrationsSQL); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func appliedMigrations(ctx context.Context, q queryer) (map[int64]time.Time, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// migrateUp applies, in order, every pending migration up to and including
+// target, or all of them when target is 0. It returns the versions applied.
+func migrateUp(ctx context.Context, db *sql.DB, target int64) ([]int64, error) {
+	list, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []int64
+	err = withMigrationLock(ctx, db, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		for _, m := range list {
+			if target > 0 && m.version > target {
+				break
+			}
+			if _, ok := applied[m.version]; ok {
+				continue
+			}
+			if hook := migrationHooks[m.version]; hook != nil {
+				if err := hook(ctx, db); err != nil {
+					return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+				}
+			}
+			err := runMigration(ctx, conn, m.up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name)
+			if err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+			}
+			done = append(done, m.version)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// migrateDown reverts the latest steps applied migrations, newest first,
+// and returns their versions.
+func migrateDown(ctx context.Context, db *sql.DB, steps int) ([]int64, error) {
+	list, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []int64
+	err = withMigrationLock(ctx, db, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		for i := len(list) - 1; i >= 0 && len(done) < steps; i-- {
+			m := list[i]
+			if _, ok := applied[m.version]; !ok {
+				continue
+			}
+			err := runMigration(ctx, conn, m.down,
+				"DELETE FROM schema_migrations WHERE version = $1", m.version)
+			if err != nil {
+				return fmt.Errorf("revert migration %d (%s): %w", m.version, m.name, err)
+			}
+			done = append(done, m.version)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// runMigration runs a migration's SQL and the statement recording it in
+// one transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, body, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// pendingMigrations counts the migrations not yet applied to db.
+func pendingMigrations(ctx context.Context, db *sql.DB) (int, error) {
+	list, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return len(list), nil
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, m := range list {
+		if _, ok := applied[m.version]; !ok {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// upgradeLegacySamples converts samples from the layouts that predate
+// versioned migrations: unnormalized rows first, then an unpartitioned
+// table.
+func upgradeLegacySamples(ctx context.Context, db *sql.DB) error {
+	if err := migrateLegacySamples(ctx, db); err != nil {
+		return fmt.Errorf("migrate legacy samples: %w", err)
+	}
+	var kind sql.NullString
+	err := db.QueryRowContext(ctx, "SELECT relkind::text FROM pg_class WHERE relname = 'samples' AND relnamespace = 'public'::regnamespace").Scan(&kind)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if kind.String == "r" {
+		return convertToPartitioned(db)
+	}
+	return nil
+}
+
+// runMigrate implements `storage migrate status|up|down` against the
+// database in DB_CONN.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: storage migrate status|up|down [flags]")
+	}
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	to := flags.Int64("to", 0, "up: stop after this version (default: latest)")
+	steps := flags.Int("steps", 1, "down: number of migrations to revert")
+	flags.Parse(args[1:])
+
+	db, err := connectPostgres()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "status":
+		return printMigrationStatus(ctx, db)
+	case "up":
+		done, err := migrateUp(ctx, db, *to)
+		for _, v := range done {
+			fmt.Printf("applied %04d\n", v)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		if *steps < 1 {
+			return errors.New("-steps must be at least 1")
+		}
+		done, err := migrateDown(ctx, db, *steps)
+		for _, v := range done {
+			fmt.Printf("reverted %04d\n", v)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("no migrations applied")
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q: expected status, up or down", args[0])
+	}
+}
+
+// printMigrationStatus lists every known migration with when it was
+// applied, plus any applied by a newer build.
+func printMigrationStatus(ctx context.Context, db *sql.DB) error {
+	list, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	var applied map[int64]time.Time
+	err = withMigrationLock(ctx, db, func(_ *sql.Conn, a map[int64]time.Time) error {
+		applied = a
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, m := range list {
+		state := "pending"
+		if at, ok := applied[m.version]; ok {
+			state = at.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", m.version, m.name, state)
+		delete(applied, m.version)
+	}
+	unknown := make([]int64, 0, len(applied))
+	for v := range applied {
+		unknown = append(unknown, v)
+	}
+	slices.Sort(unknown)
+	for _, v := range unknown {
+		fmt.Fprintf(w, "%04d\t?\t%s (unknown to this build)\n", v, applied[v].UTC().Format(time.RFC3339))
+	}
+	return w.Flush()
+}
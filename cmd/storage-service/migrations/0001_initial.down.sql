@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS alert_rules;
+DROP TABLE IF EXISTS series;
+DROP TABLE IF EXISTS users;
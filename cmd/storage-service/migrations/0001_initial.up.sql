@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	api_key TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS series (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	metric_name TEXT NOT NULL,
+	labels_hash TEXT NOT NULL,
+	labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+	UNIQUE (user_id, metric_name, labels_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_series_labels ON series USING GIN (labels jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER REFERENCES users(id),
+	metric_name TEXT NOT NULL,
+	threshold DOUBLE PRECISION NOT NULL
+);
@@ -0,0 +1,14 @@
+-- Samples only reference their series; there is deliberately no foreign
+-- key since checking it would slow down every insert. Daily partitions are
+-- created at runtime; rows outside the partition horizon land in the
+-- default one.
+CREATE TABLE IF NOT EXISTS samples (
+	series_id BIGINT NOT NULL,
+	timestamp BIGINT NOT NULL,
+	value DOUBLE PRECISION NOT NULL
+) PARTITION BY RANGE (timestamp);
+
+CREATE TABLE IF NOT EXISTS samples_default PARTITION OF samples DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_samples_ts ON samples(timestamp);
+CREATE INDEX IF NOT EXISTS idx_samples_series_ts ON samples(series_id, timestamp);
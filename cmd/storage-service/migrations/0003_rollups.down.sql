@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rollup_state;
+DROP TABLE IF EXISTS samples_1h;
+DROP TABLE IF EXISTS samples_1m;
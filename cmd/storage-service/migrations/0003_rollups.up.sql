@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS samples_1m (
+	series_id BIGINT NOT NULL,
+	bucket BIGINT NOT NULL,
+	min DOUBLE PRECISION NOT NULL,
+	max DOUBLE PRECISION NOT NULL,
+	sum DOUBLE PRECISION NOT NULL,
+	count BIGINT NOT NULL,
+	PRIMARY KEY (series_id, bucket)
+);
+CREATE INDEX IF NOT EXISTS idx_samples_1m_bucket ON samples_1m(bucket);
+
+CREATE TABLE IF NOT EXISTS samples_1h (
+	series_id BIGINT NOT NULL,
+	bucket BIGINT NOT NULL,
+	min DOUBLE PRECISION NOT NULL,
+	max DOUBLE PRECISION NOT NULL,
+	sum DOUBLE PRECISION NOT NULL,
+	count BIGINT NOT NULL,
+	PRIMARY KEY (series_id, bucket)
+);
+CREATE INDEX IF NOT EXISTS idx_samples_1h_bucket ON samples_1h(bucket);
+
+CREATE TABLE IF NOT EXISTS rollup_state (
+	resolution TEXT PRIMARY KEY,
+	watermark BIGINT NOT NULL
+);
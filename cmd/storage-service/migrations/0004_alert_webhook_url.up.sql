@@ -0,0 +1 @@
+ALTER TABLE alert_rules ADD COLUMN IF NOT EXISTS webhook_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS retention_policies (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	metric_name TEXT NOT NULL DEFAULT '',
+	matchers JSONB NOT NULL DEFAULT '[]'::jsonb,
+	retention_days INTEGER NOT NULL CHECK (retention_days > 0)
+);
+CREATE INDEX IF NOT EXISTS idx_retention_policies_user ON retention_policies(user_id);
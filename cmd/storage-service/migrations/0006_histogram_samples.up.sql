@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS histogram_samples (
+	series_id BIGINT NOT NULL,
+	timestamp BIGINT NOT NULL,
+	bounds DOUBLE PRECISION[] NOT NULL,
+	counts BIGINT[] NOT NULL,
+	sum DOUBLE PRECISION NOT NULL,
+	count BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_histogram_samples_ts ON histogram_samples(timestamp);
+CREATE INDEX IF NOT EXISTS idx_histogram_samples_series_ts ON histogram_samples(series_id, timestamp);
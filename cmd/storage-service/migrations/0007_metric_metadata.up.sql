@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS metric_metadata (
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	metric_name TEXT NOT NULL,
+	type TEXT NOT NULL DEFAULT '',
+	unit TEXT NOT NULL DEFAULT '',
+	help TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (user_id, metric_name)
+);
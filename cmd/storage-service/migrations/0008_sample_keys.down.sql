@@ -0,0 +1,6 @@
+-- Back to the lookup indexes 0002 and 0006 create.
+DROP INDEX IF EXISTS histogram_samples_series_ts_key;
+CREATE INDEX IF NOT EXISTS idx_histogram_samples_series_ts ON histogram_samples (series_id, timestamp);
+
+DROP INDEX IF EXISTS samples_series_ts_key;
+CREATE INDEX IF NOT EXISTS idx_samples_series_ts ON samples (series_id, timestamp);
@@ -0,0 +1,14 @@
+-- AppendSamples relies on (series_id, timestamp) being unique to resolve
+-- duplicates. Tables written before the key may hold duplicates already, so
+-- all but one of each are deleted first. Rows with the same timestamp share
+-- a partition, so ctid tells them apart. The unique key takes over from the
+-- plain (series_id, timestamp) lookup index.
+DELETE FROM samples a USING samples b
+WHERE a.series_id = b.series_id AND a.timestamp = b.timestamp AND a.ctid < b.ctid;
+CREATE UNIQUE INDEX IF NOT EXISTS samples_series_ts_key ON samples (series_id, timestamp);
+DROP INDEX IF EXISTS idx_samples_series_ts;
+
+DELETE FROM histogram_samples a USING histogram_samples b
+WHERE a.series_id = b.series_id AND a.timestamp = b.timestamp AND a.ctid < b.ctid;
+CREATE UNIQUE INDEX IF NOT EXISTS histogram_samples_series_ts_key ON histogram_samples (series_id, timestamp);
+DROP INDEX IF EXISTS idx_histogram_samples_series_ts;
@@ -0,0 +1,7 @@
+-- Hours holding samples written after the rollup worker had passed them,
+-- waiting to be rolled up again.
+CREATE TABLE IF NOT EXISTS rollup_dirty (
+	bucket BIGINT NOT NULL,
+	series_id BIGINT NOT NULL,
+	PRIMARY KEY (bucket, series_id)
+);
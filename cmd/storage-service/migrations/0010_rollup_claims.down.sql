@@ -0,0 +1 @@
+ALTER TABLE rollup_state DROP COLUMN IF EXISTS claimed;
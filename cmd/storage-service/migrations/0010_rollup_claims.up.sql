@@ -0,0 +1,5 @@
+-- How far the rollup worker has claimed a level for its next pass. Writes
+-- mark late samples against the claim rather than the watermark, so the
+-- worker only locks the state row to move one or the other.
+ALTER TABLE rollup_state ADD COLUMN IF NOT EXISTS claimed BIGINT NOT NULL DEFAULT 0;
+UPDATE rollup_state SET claimed = watermark;
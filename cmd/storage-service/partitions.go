@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	pb "pmts/proto"
+)
+
+const (
+	// partitionAheadDays is how many future days get partitions ahead of
+	// time, so the ingest path rarely has to create one itself.
+	partitionAheadDays = 3
+	// maxPartitionAgeDays bounds how far back a write may create a daily
+	// partition. Older (or far-future) samples land in samples_default
+	// instead of littering the schema with one-off partitions.
+	maxPartitionAgeDays = 400
+
+	partitionPrefix  = "samples_p"
+	partitionLayout  = "20060102"
+	defaultPartition = "samples_default"
+)
+
+// createSamplesSQL matches the samples migration, for converting tables
+// that predate it.
+const createSamplesSQL = `
+	CREATE TABLE samples (
+		series_id BIGINT NOT NULL,
+		timestamp BIGINT NOT NULL,
+		value DOUBLE PRECISION NOT NULL
+	) PARTITION BY RANGE (timestamp);
+	CREATE TABLE ` + defaultPartition + ` PARTITION OF samples DEFAULT;
+`
+
+// convertToPartitioned moves rows from an unpartitioned samples table into
+// a new partitioned one, with daily partitions for every day that has data
+// within the partition horizon.
+func convertToPartitioned(db *sql.DB) error {
+	slog.Info("Converting samples to a partitioned table")
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The old table's indexes go with it, so their names are free again
+	// once it is dropped below.
+	if _, err := tx.Exec("ALTER TABLE samples RENAME TO samples_unpartitioned"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(createSamplesSQL); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT DISTINCT timestamp - timestamp % 86400 FROM samples_unpartitioned")
+	if err != nil {
+		return err
+	}
+	var days []int64
+	for rows.Next() {
+		var day int64
+		if err := rows.Scan(&day); err != nil {
+			rows.Close()
+			return err
+		}
+		days = append(days, day)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, day := range days {
+		if inPartitionHorizon(day, now) {
+			if err := createDayPartition(tx, day); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO samples (series_id, timestamp, value)
+		SELECT series_id, timestamp, value FROM samples_unpartitioned;
+		DROP TABLE samples_unpartitioned;
+	`)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.Info("Samples table partitioned", "partitions", len(days))
+	return nil
+}
+
+func partitionName(day int64) string {
+	return partitionPrefix + time.Unix(day, 0).UTC().Format(partitionLayout)
+}
+
+func inPartitionHorizon(day, now int64) bool {
+	return day >= now-maxPartitionAgeDays*86400 && day <= now+partitionAheadDays*86400
+}
+
+// createDayPartition adds the partition for the day starting at day. Rows
+// for that day already sitting in the default partition are moved over
+// first, since Postgres refuses to attach a range the default still holds.
+func createDayPartition(tx *sql.Tx, day int64) error {
+	name := partitionName(day)
+	var exists bool
+	if err := tx.QueryRow("SELECT to_regclass($1) IS NOT NULL", name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if _, err := tx.Exec("CREATE TABLE " + name + " (LIKE samples INCLUDING DEFAULTS INCLUDING CONSTRAINTS)"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		WITH moved AS (
+			DELETE FROM `+defaultPartition+` WHERE timestamp >= $1 AND timestamp < $2
+			RETURNING series_id, timestamp, value
+		)
+		INSERT INTO `+name+` (series_id, timestamp, value) SELECT series_id, timestamp, value FROM moved`,
+		day, day+86400)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE samples ATTACH PARTITION %s FOR VALUES FROM (%d) TO (%d)", name, day, day+86400))
+	return err
+}
+
+// partitionManager remembers which daily partitions exist so the ingest
+// path only touches the catalog for days it hasn't seen yet.
+type partitionManager struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	known map[int64]bool
+}
+
+func newPartitionManager(db *sql.DB) *partitionManager {
+	return &partitionManager{db: db, known: make(map[int64]bool)}
+}
+
+// ensure makes sure every given day inside the horizon has its partition.
+// Creation is serialized across storage replicas with an advisory lock.
+func (pm *partitionManager) ensure(ctx context.Context, days []int64) error {
+	now := time.Now().Unix()
+	pm.mu.Lock()
+	var missing []int64
+	for _, day := range days {
+		if !pm.known[day] && inPartitionHorizon(day, now) {
+			missing = append(missing, day)
+		}
+	}
+	pm.mu.Unlock()
+	if len(missing) == 0 {
+		return nil
+	}
+
+	tx, err := pm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('samples_partitions'))"); err != nil {
+		return err
+	}
+	for _, day := range missing {
+		if err := createDayPartition(tx, day); err != nil {
+			return fmt.Errorf("create partition %s: %w", partitionName(day), err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	for _, day := range missing {
+		pm.known[day] = true
+	}
+	pm.mu.Unlock()
+	return nil
+}
+
+// ensureForBatch creates partitions for every day the batch writes to.
+func (pm *partitionManager) ensureForBatch(ctx context.Context, list []*pb.TimeSeries) error {
+	seen := make(map[int64]bool)
+	var days []int64
+	for _, series := range list {
+		for _, sample := range series.Samples {
+			day := sample.Timestamp - sample.Timestamp%86400
+			if !seen[day] {
+				seen[day] = true
+				days = append(days, day)
+			}
+		}
+	}
+	return pm.ensure(ctx, days)
+}
+
+// dayPartitions lists the attached daily partitions by their start day.
+func (pm *partitionManager) dayPartitions(ctx context.Context) (map[int64]string, error) {
+	rows, err := pm.db.QueryContext(ctx, `
+		SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'samples'::regclass`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := make(map[int64]string)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		suffix, ok := strings.CutPrefix(name, partitionPrefix)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(partitionLayout, suffix)
+		if err != nil {
+			continue
+		}
+		parts[t.Unix()] = name
+	}
+	return parts, rows.Err()
+}
+
+// dropBefore drops every daily partition that ends at or before cutoff.
+// Dropping a partition is a cheap catalog change, unlike deleting its rows.
+func (pm *partitionManager) dropBefore(ctx context.Context, cutoff int64) ([]string, error) {
+	parts, err := pm.dayPartitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var dropped []string
+	for day, name := range parts {
+		if day+86400 > cutoff {
+			continue
+		}
+		if _, err := pm.db.ExecContext(ctx, "DROP TABLE IF EXISTS "+name); err != nil {
+			return dropped, err
+		}
+		pm.mu.Lock()
+		delete(pm.known, day)
+		pm.mu.Unlock()
+		dropped = append(dropped, name)
+	}
+	return dropped, nil
+}
+
+// startPartitionWorker keeps partitions for today and the next few days in
+// place ahead of the data that will need them.
+func startPartitionWorker(pm *partitionManager, logger *slog.Logger) {
+	premake := func() {
+		today := alignDown(time.Now().Unix(), 86400)
+		var days []int64
+		for i := int64(0); i <= partitionAheadDays; i++ {
+			days = append(days, today+i*86400)
+		}
+		if err := pm.ensure(context.Background(), days); err != nil {
+			logger.Error("Partition maintenance failed", "error", err)
+		}
+	}
+	premake()
+
+	ticker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range ticker.C {
+			premake()
+		}
+	}()
+}
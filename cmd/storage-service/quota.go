@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// Why a sample was dropped for a limit. The gateway reports the same
+// reasons to clients.
+const (
+	limitTooManyLabels    = "too_many_labels"
+	limitLabelValueLength = "label_value_too_long"
+	limitSeries           = "series_limit_exceeded"
+	limitSamplesPerDay    = "daily_sample_limit_exceeded"
+	limitIngestRate       = "ingest_rate_exceeded"
+)
+
+// rateBurstSeconds is how many seconds' worth of max_samples_per_second a
+// user may send at once, so agents pushing every few seconds are not cut
+// off by their own batching.
+const rateBurstSeconds = 10
+
+// tenantLimits caps what one user may write. Zero means no limit.
+type tenantLimits struct {
+	MaxSeries           int64 `json:"max_series"`
+	MaxSamplesPerDay    int64 `json:"max_samples_per_day"`
+	MaxLabelsPerSeries  int   `json:"max_labels_per_series"`
+	MaxLabelValueLength int   `json:"max_label_value_length"`
+	MaxSamplesPerSecond int64 `json:"max_samples_per_second"`
+}
+
+// checkLabels returns which limit metric's labels break, or "".
+func (l tenantLimits) checkLabels(metric *pb.Metric) string {
+	if l.MaxLabelsPerSeries > 0 && len(metric.Labels) > l.MaxLabelsPerSeries {
+		return limitTooManyLabels
+	}
+	if l.MaxLabelValueLength > 0 {
+		for _, v := range metric.Labels {
+			if len(v) > l.MaxLabelValueLength {
+				return limitLabelValueLength
+			}
+		}
+	}
+	return ""
+}
+
+func (l tenantLimits) proto() *pb.TenantLimits {
+	return &pb.TenantLimits{
+		MaxSeries:           l.MaxSeries,
+		MaxSamplesPerDay:    l.MaxSamplesPerDay,
+		MaxLabelsPerSeries:  int32(l.MaxLabelsPerSeries),
+		MaxLabelValueLength: int32(l.MaxLabelValueLength),
+		MaxSamplesPerSecond: l.MaxSamplesPerSecond,
+	}
+}
+
+// quotaConfig holds everyone's limits: LIMIT_MAX_SERIES (default 100000),
+// LIMIT_MAX_SAMPLES_PER_DAY (default unlimited), LIMIT_MAX_LABELS_PER_SERIES
+// (default 30), LIMIT_MAX_LABEL_VALUE_LENGTH (default 2048) and
+// LIMIT_MAX_SAMPLES_PER_SECOND (default unlimited) for all users,
+// overridden per user by LIMITS_FILE, a JSON object keyed by user ID such
+// as {"2": {"max_series": 500000, "max_samples_per_day": 0}}. Fields a
+// user's entry leaves out keep the defaults. Moving a large tenant in with
+// an import may need its limits raised first.
+type quotaConfig struct {
+	defaults tenantLimits
+	users    map[int64]tenantLimits
+}
+
+func quotaConfigFromEnv() (quotaConfig, error) {
+	cfg := quotaConfig{
+		defaults: tenantLimits{
+			MaxSeries:           envLimit("LIMIT_MAX_SERIES", 100_000),
+			MaxSamplesPerDay:    envLimit("LIMIT_MAX_SAMPLES_PER_DAY", 0),
+			MaxLabelsPerSeries:  int(envLimit("LIMIT_MAX_LABELS_PER_SERIES", 30)),
+			MaxLabelValueLength: int(envLimit("LIMIT_MAX_LABEL_VALUE_LENGTH", 2048)),
+			MaxSamplesPerSecond: envLimit("LIMIT_MAX_SAMPLES_PER_SECOND", 0),
+		},
+		users: make(map[int64]tenantLimits),
+	}
+	path := os.Getenv("LIMITS_FILE")
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	// Each entry is decoded over the defaults, so missing fields keep them.
+	var overrides map[string]json.RawMessage
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	for key, raw := range overrides {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("%s: invalid user ID %q", path, key)
+		}
+		limits := cfg.defaults
+		if err := json.Unmarshal(raw, &limits); err != nil {
+			return cfg, fmt.Errorf("%s: user %d: %w", path, id, err)
+		}
+		cfg.users[id] = limits
+	}
+	return cfg, nil
+}
+
+func (c quotaConfig) limits(userID int64) tenantLimits {
+	if l, ok := c.users[userID]; ok {
+		return l
+	}
+	return c.defaults
+}
+
+// envLimit is envInt for limits, where 0 turns the limit off.
+func envLimit(name string, def int64) int64 {
+	if v, err := strconv.ParseInt(os.Getenv(name), 10, 64); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
+
+// quotaTracker enforces the limits on every write path, counting each
+// user's series and samples for the current UTC day. The counts live in
+// the process: they are read back from storage the first time a user
+// writes after a restart, and several storage replicas each enforce the
+// limits on what they write, so a user's total can overshoot them.
+// Samples are counted as they are admitted, before duplicates of stored
+// ones are dropped, so a resent batch counts again until the next restart
+// reads back what was actually stored.
+type quotaTracker struct {
+	cfg   quotaConfig
+	store Storage
+
+	mu    sync.Mutex
+	users map[int64]*tenantUsage
+	// loading holds the loads in progress, so that writes arriving while a
+	// user's usage is read back wait for it rather than read it again.
+	loading map[int64]*usageLoad
+}
+
+type usageLoad struct {
+	done chan struct{}
+	err  error
+}
+
+type tenantUsage struct {
+	// day is the start of the UTC day counted.
+	day     int64
+	series  map[seriesKey]bool
+	samples int64
+	limited int64
+	// rate meters live writes against max_samples_per_second.
+	rate tokenBucket
+}
+
+// tokenBucket meters a rate: it holds up to a burst of tokens, refilled
+// continuously, and each sample takes one.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take removes up to n tokens from a bucket refilled at rate a second up
+// to burst, returning how many it got. A new bucket starts full.
+func (b *tokenBucket) take(n int64, rate, burst float64, now time.Time) int64 {
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(burst, b.tokens+elapsed*rate)
+	}
+	b.last = now
+	got := min(n, int64(b.tokens))
+	b.tokens -= float64(got)
+	return got
+}
+
+func newQuotaTracker(store Storage, cfg quotaConfig) *quotaTracker {
+	return &quotaTracker{
+		cfg:     cfg,
+		store:   store,
+		users:   make(map[int64]*tenantUsage),
+		loading: make(map[int64]*usageLoad),
+	}
+}
+
+// usage returns userID's counts for today, loading them from storage on
+// first use. The caller must hold t.mu, which is released while loading or
+// waiting for another write's load.
+func (t *quotaTracker) usage(ctx context.Context, userID int64, now time.Time) (*tenantUsage, error) {
+	day := alignDown(now.Unix(), 86400)
+	for {
+		if u, ok := t.users[userID]; ok {
+			if u.day != day {
+				u.day, u.series, u.samples, u.limited = day, make(map[seriesKey]bool), 0, 0
+			}
+			return u, nil
+		}
+		l, ok := t.loading[userID]
+		if !ok {
+			break
+		}
+		t.mu.Unlock()
+		select {
+		case <-l.done:
+		case <-ctx.Done():
+		}
+		t.mu.Lock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if l.err != nil {
+			return nil, fmt.Errorf("load usage: %w", l.err)
+		}
+	}
+
+	l := &usageLoad{done: make(chan struct{})}
+	t.loading[userID] = l
+	t.mu.Unlock()
+	loaded, err := t.load(ctx, userID, day)
+	t.mu.Lock()
+	delete(t.loading, userID)
+	l.err = err
+	close(l.done)
+	if err != nil {
+		return nil, fmt.Errorf("load usage: %w", err)
+	}
+	t.users[userID] = loaded
+	return loaded, nil
+}
+
+// load reads back the series and samples userID has stored since day
+// began.
+func (t *quotaTracker) load(ctx context.Context, userID, day int64) (*tenantUsage, error) {
+	counts, err := t.store.CountWritten(ctx, userID, day)
+	if err != nil {
+		return nil, err
+	}
+	u := &tenantUsage{day: day, series: make(map[seriesKey]bool, len(counts.Series)), samples: counts.Samples}
+	for _, key := range counts.Series {
+		u.series[key] = true
+	}
+	return u, nil
+}
+
+// admission is the part of a batch within the limits, reserved against
+// them until the caller stores it or releases it.
+type admission struct {
+	day  int64
+	list []*pb.TimeSeries
+	// limited counts the samples dropped, and reason gives the first limit
+	// they broke.
+	limited int
+	reason  string
+	series  []seriesKey
+	samples int64
+}
+
+func (a *admission) drop(n int, reason string) {
+	a.limited += n
+	if a.reason == "" {
+		a.reason = reason
+	}
+}
+
+// admit drops what in list would take userID over its limits: series
+// whose labels break them, series new today beyond max_series, and samples
+// beyond the day's allowance, in list order. Live writes are also held to
+// max_samples_per_second; batches queued by the gateway were held to it
+// when it accepted them, and backfills are exempt, as they are from the
+// acceptance window. What is kept is counted at once, so concurrent writes
+// cannot both take the last of a limit; a caller that then fails to store
+// it must release it.
+func (t *quotaTracker) admit(ctx context.Context, userID int64, list []*pb.TimeSeries, now time.Time, live bool) (*admission, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, err := t.usage(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	limits := t.cfg.limits(userID)
+	a := &admission{day: u.day}
+	budget := int64(-1)
+	if limits.MaxSamplesPerDay > 0 {
+		budget = max(limits.MaxSamplesPerDay-u.samples, 0)
+	}
+	rated := live && limits.MaxSamplesPerSecond > 0
+	for _, ts := range list {
+		n := len(ts.Samples) + len(ts.Histograms)
+		if n == 0 {
+			continue
+		}
+		if reason := limits.checkLabels(ts.Metric); reason != "" {
+			a.drop(n, reason)
+			continue
+		}
+		key := seriesKey{userID: userID, name: ts.Metric.Name, hash: labelsHash(ts.Metric.Labels)}
+		isNew := !u.series[key]
+		if isNew && limits.MaxSeries > 0 && int64(len(u.series)) >= limits.MaxSeries {
+			a.drop(n, limitSeries)
+			continue
+		}
+		keep := n
+		if budget >= 0 && int64(keep) > budget {
+			a.drop(keep-int(budget), limitSamplesPerDay)
+			keep = int(budget)
+		}
+		if rated && keep > 0 {
+			rate := float64(limits.MaxSamplesPerSecond)
+			if got := int(u.rate.take(int64(keep), rate, rate*rateBurstSeconds, now)); got < keep {
+				a.drop(keep-got, limitIngestRate)
+				keep = got
+			}
+		}
+		if keep == 0 {
+			continue
+		}
+		if keep < n {
+			ts = truncateSeries(ts, keep)
+		}
+		if budget >= 0 {
+			budget -= int64(keep)
+		}
+		if isNew {
+			u.series[key] = true
+			a.series = append(a.series, key)
+		}
+		a.list = append(a.list, ts)
+		a.samples += int64(keep)
+	}
+	u.samples += a.samples
+	u.limited += int64(a.limited)
+	return a, nil
+}
+
+// release hands back what an admission reserved when its samples could
+// not be stored, and forgets what it dropped, since a retry drops it
+// again. Ingest rate it used is not given back: the write was attempted.
+// A series it added is forgotten even if another write has since stored
+// samples of it; that write's next sample counts it again.
+func (t *quotaTracker) release(userID int64, a *admission) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.users[userID]
+	if !ok || u.day != a.day {
+		return
+	}
+	for _, key := range a.series {
+		delete(u.series, key)
+	}
+	u.samples -= a.samples
+	u.limited -= int64(a.limited)
+}
+
+// truncateSeries keeps the first n samples of ts, floats before histograms.
+func truncateSeries(ts *pb.TimeSeries, n int) *pb.TimeSeries {
+	out := &pb.TimeSeries{Metric: ts.Metric}
+	k := min(n, len(ts.Samples))
+	out.Samples = ts.Samples[:k:k]
+	if n > k {
+		h := n - k
+		out.Histograms = ts.Histograms[:h:h]
+	}
+	return out
+}
+
+// GetQuota reports a user's limits and today's usage, and which of the
+// given series would be refused as new ones beyond max_series, so the
+// gateway can turn them away up front.
+func (s *Server) GetQuota(ctx context.Context, req *pb.QuotaRequest) (*pb.QuotaResponse, error) {
+	uid := req.UserId
+	if uid == 0 {
+		uid = 1
+	}
+	t := s.quotas
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	u, err := t.usage(ctx, uid, now)
+	if err != nil {
+		return nil, err
+	}
+	limits := t.cfg.limits(uid)
+	resp := &pb.QuotaResponse{
+		Limits:       limits.proto(),
+		Series:       int64(len(u.series)),
+		SamplesToday: u.samples,
+		LimitedToday: u.limited,
+		ResetsAt:     u.day + 86400,
+	}
+	if limits.MaxSeries > 0 {
+		added := make(map[seriesKey]bool)
+		for i, m := range req.Series {
+			key := seriesKey{userID: uid, name: m.GetName(), hash: labelsHash(m.GetLabels())}
+			if u.series[key] || added[key] {
+				continue
+			}
+			if int64(len(u.series)+len(added)) >= limits.MaxSeries {
+				resp.OverSeries = append(resp.OverSeries, int32(i))
+				continue
+			}
+			added[key] = true
+		}
+	}
+	return resp, nil
+}
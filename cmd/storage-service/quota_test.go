@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// quotaSeries returns n series of name with one sample each at ts, told
+// apart by an "i" label.
+func quotaSeries(name string, n int, ts int64) []*pb.TimeSeries {
+	list := make([]*pb.TimeSeries, n)
+	for i := range list {
+		list[i] = testSeries(name, map[string]string{"i": strconv.Itoa(i)}, &pb.Sample{Timestamp: ts, Value: 1})
+	}
+	return list
+}
+
+func TestQuotaAdmit(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ts := now.Unix()
+	samples := func(n int) []*pb.Sample {
+		out := make([]*pb.Sample, n)
+		for i := range out {
+			out[i] = &pb.Sample{Timestamp: ts + int64(i), Value: 1}
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		limits tenantLimits
+		// stored is written to storage before the tracker loads usage.
+		stored []*pb.TimeSeries
+		list   []*pb.TimeSeries
+		live   bool
+
+		wantSamples int64
+		wantLimited int
+		wantReason  string
+	}{
+		{
+			name:        "unlimited",
+			list:        []*pb.TimeSeries{testSeries("a", nil, samples(5)...)},
+			wantSamples: 5,
+		},
+		{
+			name:   "too many labels",
+			limits: tenantLimits{MaxLabelsPerSeries: 1},
+			list: []*pb.TimeSeries{
+				testSeries("a", map[string]string{"x": "1", "y": "2"}, samples(2)...),
+				testSeries("b", map[string]string{"x": "1"}, samples(1)...),
+			},
+			wantSamples: 1, wantLimited: 2, wantReason: limitTooManyLabels,
+		},
+		{
+			name:        "label value too long",
+			limits:      tenantLimits{MaxLabelValueLength: 3},
+			list:        []*pb.TimeSeries{testSeries("a", map[string]string{"x": "long"}, samples(1)...)},
+			wantLimited: 1, wantReason: limitLabelValueLength,
+		},
+		{
+			name:        "new series beyond the limit",
+			limits:      tenantLimits{MaxSeries: 2},
+			list:        quotaSeries("a", 3, ts),
+			wantSamples: 2, wantLimited: 1, wantReason: limitSeries,
+		},
+		{
+			name:   "known series past the limit",
+			limits: tenantLimits{MaxSeries: 2},
+			stored: quotaSeries("a", 2, ts-60),
+			list:   append(quotaSeries("a", 2, ts), quotaSeries("b", 1, ts)...),
+			// Only b is new.
+			wantSamples: 2, wantLimited: 1, wantReason: limitSeries,
+		},
+		{
+			name:   "daily samples",
+			limits: tenantLimits{MaxSamplesPerDay: 10},
+			stored: []*pb.TimeSeries{testSeries("a", nil, &pb.Sample{Timestamp: ts - 60, Value: 1},
+				&pb.Sample{Timestamp: ts - 30, Value: 1})},
+			list:        []*pb.TimeSeries{testSeries("a", nil, samples(5)...), testSeries("b", nil, samples(5)...)},
+			wantSamples: 8, wantLimited: 2, wantReason: limitSamplesPerDay,
+		},
+		{
+			name:   "yesterday does not count",
+			limits: tenantLimits{MaxSamplesPerDay: 5},
+			stored: []*pb.TimeSeries{testSeries("a", nil, &pb.Sample{Timestamp: ts - 86400, Value: 1})},
+			list:   []*pb.TimeSeries{testSeries("a", nil, samples(5)...)},
+			// The sample stored yesterday is not counted.
+			wantSamples: 5,
+		},
+		{
+			name:        "ingest rate, live",
+			limits:      tenantLimits{MaxSamplesPerSecond: 2},
+			list:        []*pb.TimeSeries{testSeries("a", nil, samples(30)...)},
+			live:        true,
+			wantSamples: 2 * rateBurstSeconds, wantLimited: 30 - 2*rateBurstSeconds, wantReason: limitIngestRate,
+		},
+		{
+			name:        "ingest rate, queued",
+			limits:      tenantLimits{MaxSamplesPerSecond: 2},
+			list:        []*pb.TimeSeries{testSeries("a", nil, samples(30)...)},
+			wantSamples: 30,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newMemStorage(30, keepFirst)
+			if len(tt.stored) > 0 {
+				mustAppend(t, store, tt.stored...)
+			}
+			quotas := newQuotaTracker(store, quotaConfig{defaults: tt.limits})
+			adm, err := quotas.admit(ctx, 1, tt.list, now, tt.live)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if adm.samples != tt.wantSamples || countSamples(adm.list) != int(tt.wantSamples) {
+				t.Errorf("admitted %d samples (%d in list), want %d", adm.samples, countSamples(adm.list), tt.wantSamples)
+			}
+			if adm.limited != tt.wantLimited || adm.reason != tt.wantReason {
+				t.Errorf("limited %d for %q, want %d for %q", adm.limited, adm.reason, tt.wantLimited, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestQuotaReservation(t *testing.T) {
+	ctx := context.Background()
+	// GetQuota reports on the current day.
+	now := time.Now()
+	store := newMemStorage(30, keepFirst)
+	quotas := newQuotaTracker(store, quotaConfig{defaults: tenantLimits{MaxSeries: 3, MaxSamplesPerDay: 4}})
+	usage := func() *pb.QuotaResponse {
+		t.Helper()
+		srv := &Server{store: store, quotas: quotas}
+		resp, err := srv.GetQuota(ctx, &pb.QuotaRequest{UserId: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Two writes admitted before either is stored cannot both have the
+	// last of the allowance.
+	first, err := quotas.admit(ctx, 1, quotaSeries("a", 3, now.Unix()), now, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := quotas.admit(ctx, 1, quotaSeries("b", 3, now.Unix()), now, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.samples != 3 || second.samples != 0 || second.reason != limitSeries {
+		t.Fatalf("admitted %d then %d (%q), want 3 then 0 for the series limit", first.samples, second.samples, second.reason)
+	}
+	if u := usage(); u.Series != 3 || u.SamplesToday != 3 || u.LimitedToday != 3 {
+		t.Errorf("usage after admitting = %d series, %d samples, %d limited, want 3, 3, 3", u.Series, u.SamplesToday, u.LimitedToday)
+	}
+
+	// A failed write gives its reservation back.
+	quotas.release(1, first)
+	quotas.release(1, second)
+	if u := usage(); u.Series != 0 || u.SamplesToday != 0 || u.LimitedToday != 0 {
+		t.Errorf("usage after releasing = %d series, %d samples, %d limited, want none", u.Series, u.SamplesToday, u.LimitedToday)
+	}
+	third, err := quotas.admit(ctx, 1, quotaSeries("b", 3, now.Unix()), now, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.samples != 3 {
+		t.Errorf("admitted %d after release, want 3", third.samples)
+	}
+
+	// The day's counts start over: yesterday's series count again only
+	// once written to.
+	mustAppend(t, store, third.list...)
+	tomorrow := now.Add(24 * time.Hour)
+	next, err := quotas.admit(ctx, 1, quotaSeries("c", 4, tomorrow.Unix()), tomorrow, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.samples != 3 || next.reason != limitSeries {
+		t.Errorf("admitted %d the next day (%q), want 3 until the series limit", next.samples, next.reason)
+	}
+}
+
+func TestQuotaLoadCountsHistograms(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := newMemStorage(30, keepFirst)
+	h := &pb.HistogramSample{Timestamp: now.Unix() - 1, Bounds: []float64{1}, Counts: []uint64{1}, Sum: 1, Count: 1}
+	mustAppend(t, store,
+		&pb.TimeSeries{Metric: &pb.Metric{Name: "latency"}, Histograms: []*pb.HistogramSample{h}},
+		testSeries("up", nil, &pb.Sample{Timestamp: now.Unix() - 1, Value: 1}))
+	quotas := newQuotaTracker(store, quotaConfig{})
+	quotas.mu.Lock()
+	u, err := quotas.usage(ctx, 1, now)
+	quotas.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.series) != 2 || u.samples != 2 {
+		t.Errorf("loaded %d series and %d samples, want 2 and 2", len(u.series), u.samples)
+	}
+}
+
+// slowCounts is a Storage whose CountWritten counts its calls and holds
+// them until release is closed.
+type slowCounts struct {
+	Storage
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *slowCounts) CountWritten(ctx context.Context, userID, since int64) (WrittenCounts, error) {
+	s.calls.Add(1)
+	<-s.release
+	return s.Storage.CountWritten(ctx, userID, since)
+}
+
+func TestQuotaLoadShared(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := &slowCounts{Storage: newMemStorage(30, keepFirst), release: make(chan struct{})}
+	quotas := newQuotaTracker(store, quotaConfig{defaults: tenantLimits{MaxSamplesPerDay: 5}})
+
+	var wg sync.WaitGroup
+	admitted := make([]int64, 8)
+	for i := range admitted {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a, err := quotas.admit(ctx, 1, quotaSeries("a", 1, now.Unix()+int64(i)), now, false)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			admitted[i] = a.samples
+		}()
+	}
+	for store.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(store.release)
+	wg.Wait()
+
+	if n := store.calls.Load(); n != 1 {
+		t.Errorf("usage loaded %d times, want once", n)
+	}
+	var total int64
+	for _, n := range admitted {
+		total += n
+	}
+	if total != 5 {
+		t.Errorf("admitted %d samples in all, want the daily 5", total)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	var b tokenBucket
+	steps := []struct {
+		after time.Duration
+		take  int64
+		want  int64
+	}{
+		{0, 15, 10},                    // starts full
+		{0, 1, 0},                      // empty
+		{500 * time.Millisecond, 5, 1}, // half a second at 2/s
+		{10 * time.Second, 50, 10},     // refills only up to the burst
+		{250 * time.Millisecond, 1, 0}, // half a token
+		{250 * time.Millisecond, 2, 1}, // which adds up
+		{-time.Second, 1, 0},           // a clock going backwards adds nothing
+	}
+	now := start
+	for i, s := range steps {
+		now = now.Add(s.after)
+		if got := b.take(s.take, 2, 10, now); got != s.want {
+			t.Errorf("step %d: took %d, want %d", i, got, s.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// sampleTables hold per-series data that retention and deletes must clear.
+// Only samples is partitioned; histogram_samples is small enough to be
+// trimmed row by row.
+var sampleTables = []string{"samples", "histogram_samples"}
+
+// loadRetentionPolicies returns one user's policies, or everyone's when
+// userID is 0.
+func loadRetentionPolicies(ctx context.Context, db *sql.DB, userID int64) ([]*pb.RetentionPolicy, error) {
+	query := "SELECT id, user_id, metric_name, matchers, retention_days FROM retention_policies"
+	var args []interface{}
+	if userID != 0 {
+		query += " WHERE user_id = $1"
+		args = append(args, userID)
+	}
+	query += " ORDER BY id ASC"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []*pb.RetentionPolicy
+	for rows.Next() {
+		p := &pb.RetentionPolicy{}
+		var matchersJSON []byte
+		if err := rows.Scan(&p.PolicyId, &p.UserId, &p.MetricName, &matchersJSON, &p.RetentionDays); err != nil {
+			return nil, err
+		}
+		if p.Matchers, err = unmarshalMatchers(matchersJSON); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+// retentionResolver picks the retention for a series from one user's
+// policies. The most specific matching policy wins: one with label matchers
+// beats one that only names a metric, which beats a user-wide policy. Among
+// equally specific matches the longest retention wins, so overlapping
+// policies never delete data one of them means to keep.
+type retentionResolver struct {
+	policies []compiledPolicy
+	fallback int
+}
+
+type compiledPolicy struct {
+	*pb.RetentionPolicy
+	matchers    []labelMatcher
+	specificity int
+}
+
+func newRetentionResolver(policies []*pb.RetentionPolicy, fallback int) (*retentionResolver, error) {
+	r := &retentionResolver{fallback: fallback}
+	for _, p := range policies {
+		matchers, err := compileMatchers(p.Matchers)
+		if err != nil {
+			return nil, fmt.Errorf("policy %d: %w", p.PolicyId, err)
+		}
+		cp := compiledPolicy{RetentionPolicy: p, matchers: matchers}
+		if p.MetricName != "" {
+			cp.specificity++
+		}
+		if len(p.Matchers) > 0 {
+			cp.specificity += 2
+		}
+		r.policies = append(r.policies, cp)
+	}
+	return r, nil
+}
+
+func (r *retentionResolver) days(name string, labels map[string]string) int {
+	days, _ := r.resolve(name, labels)
+	return days
+}
+
+// resolve is days, also reporting whether a policy matched rather than the
+// fallback applying.
+func (r *retentionResolver) resolve(name string, labels map[string]string) (int, bool) {
+	best, bestSpecificity := r.fallback, -1
+	for _, p := range r.policies {
+		if p.MetricName != "" && p.MetricName != name {
+			continue
+		}
+		if !matchSeries(p.matchers, name, labels) {
+			continue
+		}
+		days := int(p.RetentionDays)
+		if p.specificity > bestSpecificity || (p.specificity == bestSpecificity && days > best) {
+			best, bestSpecificity = days, p.specificity
+		}
+	}
+	return best, bestSpecificity >= 0
+}
+
+func startRetentionWorker(db *sql.DB, pm *partitionManager, logger *slog.Logger) {
+	retentionDays := envInt("RETENTION_DAYS", 30)
+
+	ticker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range ticker.C {
+			if err := applyRetention(context.Background(), db, pm, retentionDays, logger); err != nil {
+				logger.Error("Retention cleanup failed", "error", err)
+			}
+
+			// Rollups have their own, typically much longer, retention.
+			for _, level := range rollupLevels {
+				days := envInt(level.retentionEnv, level.defaultRetention)
+				cutoff := time.Now().Unix() - int64(days*86400)
+				result, err := db.Exec("DELETE FROM "+level.table+" WHERE bucket < $1", cutoff)
+				if err != nil {
+					logger.Error("Rollup retention cleanup failed", "resolution", level.name, "error", err)
+					continue
+				}
+				rows, _ := result.RowsAffected()
+				if rows > 0 {
+					logger.Info("Rollup retention cleanup", "resolution", level.name, "deleted", rows, "cutoff_days", days)
+				}
+			}
+		}
+	}()
+}
+
+// applyRetention deletes expired raw samples. Daily partitions older than
+// the longest retention anyone is entitled to are dropped whole; what's left
+// to expire inside live partitions is deleted row by row. Users without
+// policies get the global retention in one statement; for users with
+// policies each of their series is resolved to a retention and deleted in
+// groups. A series a policy applies to expires from the rollups at the same
+// cutoff, so stepped queries served from them stop showing it too; others
+// keep the rollups' own retention.
+func applyRetention(ctx context.Context, db *sql.DB, pm *partitionManager, retentionDays int, logger *slog.Logger) error {
+	policies, err := loadRetentionPolicies(ctx, db, 0)
+	if err != nil {
+		return err
+	}
+	byUser := make(map[int64][]*pb.RetentionPolicy)
+	var policyUsers []int64
+	longest := retentionDays
+	for _, p := range policies {
+		if _, seen := byUser[p.UserId]; !seen {
+			policyUsers = append(policyUsers, p.UserId)
+		}
+		byUser[p.UserId] = append(byUser[p.UserId], p)
+		longest = max(longest, int(p.RetentionDays))
+	}
+
+	now := time.Now().Unix()
+	dropped, err := pm.dropBefore(ctx, now-int64(longest*86400))
+	if len(dropped) > 0 {
+		logger.Info("Dropped expired partitions", "partitions", dropped, "cutoff_days", longest)
+	}
+	if err != nil {
+		return err
+	}
+
+	cutoff := now - int64(retentionDays*86400)
+	for _, table := range sampleTables {
+		result, err := db.ExecContext(ctx, `
+			DELETE FROM `+table+` WHERE timestamp < $1
+			AND series_id NOT IN (SELECT id FROM series WHERE user_id = ANY($2))`,
+			cutoff, policyUsers)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows > 0 {
+			logger.Info("Retention cleanup", "table", table, "deleted", rows, "cutoff_days", retentionDays)
+		}
+	}
+
+	for _, userID := range policyUsers {
+		resolver, err := newRetentionResolver(byUser[userID], retentionDays)
+		if err != nil {
+			logger.Error("Skipping retention for user", "user_id", userID, "error", err)
+			continue
+		}
+		groups, err := seriesByRetention(ctx, db, userID, resolver)
+		if err != nil {
+			return err
+		}
+		for group, ids := range groups {
+			deletes := make(map[string]string)
+			for _, table := range sampleTables {
+				deletes[table] = "DELETE FROM " + table + " WHERE series_id = ANY($1) AND timestamp < $2"
+			}
+			if group.policy {
+				for _, table := range rollupTables() {
+					deletes[table] = "DELETE FROM " + table + " WHERE series_id = ANY($1) AND bucket < $2"
+				}
+			}
+			for table, query := range deletes {
+				result, err := db.ExecContext(ctx, query, ids, now-int64(group.days*86400))
+				if err != nil {
+					return err
+				}
+				if rows, _ := result.RowsAffected(); rows > 0 {
+					logger.Info("Retention cleanup", "user_id", userID, "table", table, "deleted", rows, "cutoff_days", group.days)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// retentionGroup keys the series sharing a retention, and whether it came
+// from a policy.
+type retentionGroup struct {
+	days   int
+	policy bool
+}
+
+func seriesByRetention(ctx context.Context, db *sql.DB, userID int64, resolver *retentionResolver) (map[retentionGroup][]int64, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, metric_name, labels FROM series WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make(map[retentionGroup][]int64)
+	for rows.Next() {
+		var id int64
+		var name string
+		var labelsJSON []byte
+		if err := rows.Scan(&id, &name, &labelsJSON); err != nil {
+			return nil, err
+		}
+		var labels map[string]string
+		if err := json.Unmarshal(labelsJSON, &labels); err != nil {
+			return nil, err
+		}
+		days, policy := resolver.resolve(name, labels)
+		group := retentionGroup{days: days, policy: policy}
+		groups[group] = append(groups[group], id)
+	}
+	return groups, rows.Err()
+}
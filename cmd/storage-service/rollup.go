@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	pb "pmts/proto"
+)
+
+// rollupGrace is how long after a bucket closes the worker waits before
+// rolling it up, to give in-flight batches from agents time to land.
+// Samples arriving later than that, which the acceptance window allows
+// for a day, are marked in rollup_dirty by the write and their hour is
+// rolled up again on the worker's next pass.
+const rollupGrace = 2 * time.Minute
+
+// rollupLevel describes one downsampled table. Each level is built from the
+// level before it (the first from raw samples), so sourceSQL must yield
+// (series_id, bucket, min, max, sum, count) for source rows in [$1, $2).
+// Adding a level takes a migration creating its table.
+type rollupLevel struct {
+	name      string
+	table     string
+	step      int64
+	sourceSQL string
+	// Source table and time column, used to find where to start on first run.
+	sourceTable  string
+	sourceColumn string
+
+	retentionEnv     string
+	defaultRetention int
+}
+
+var rollupLevels = []rollupLevel{
+	{
+		name:  "1m",
+		table: "samples_1m",
+		step:  60,
+		sourceSQL: `SELECT series_id, (timestamp / 60) * 60, min(value), max(value), sum(value), count(*)
+			FROM samples WHERE timestamp >= $1 AND timestamp < $2 GROUP BY 1, 2`,
+		sourceTable:      "samples",
+		sourceColumn:     "timestamp",
+		retentionEnv:     "ROLLUP_1M_RETENTION_DAYS",
+		defaultRetention: 90,
+	},
+	{
+		name:  "1h",
+		table: "samples_1h",
+		step:  3600,
+		sourceSQL: `SELECT series_id, (bucket / 3600) * 3600, min(min), max(max), sum(sum), sum(count)
+			FROM samples_1m WHERE bucket >= $1 AND bucket < $2 GROUP BY 1, 2`,
+		sourceTable:      "samples_1m",
+		sourceColumn:     "bucket",
+		retentionEnv:     "ROLLUP_1H_RETENTION_DAYS",
+		defaultRetention: 365,
+	},
+}
+
+// rollupTables lists every level's table.
+func rollupTables() []string {
+	tables := make([]string, len(rollupLevels))
+	for i, level := range rollupLevels {
+		tables[i] = level.table
+	}
+	return tables
+}
+
+// maxRollupBuckets caps how many buckets one pass rolls up, so catching up
+// on a large backlog happens in bounded transactions.
+const maxRollupBuckets = 1440
+
+func startRollupWorker(db *sql.DB, logger *slog.Logger) {
+	ticker := time.NewTicker(1 * time.Minute)
+	go func() {
+		for range ticker.C {
+			for i := range rollupLevels {
+				for {
+					progressed, err := rollupOnce(context.Background(), db, i)
+					if err != nil {
+						logger.Error("Rollup failed", "resolution", rollupLevels[i].name, "error", err)
+						break
+					}
+					if !progressed {
+						break
+					}
+				}
+			}
+			for {
+				series, err := rerollOnce(context.Background(), db)
+				if err != nil {
+					logger.Error("Rollup of late samples failed", "error", err)
+					break
+				}
+				if series == 0 {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// rollupOnce advances one level's watermark by at most maxRollupBuckets.
+// The range is first claimed in a short transaction of its own (see
+// claimRollup), so that writes marking late samples never wait on the
+// rollup itself. The range is then rolled up holding the rollup lock, so
+// concurrent storage replicas never roll up the same range twice, and the
+// state row is only locked for the final move of the watermark.
+func rollupOnce(ctx context.Context, db *sql.DB, idx int) (bool, error) {
+	level := rollupLevels[idx]
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO rollup_state (resolution, watermark) VALUES ($1, 0) ON CONFLICT DO NOTHING", level.name)
+	if err != nil {
+		return false, err
+	}
+	if err := claimRollup(ctx, db, idx); err != nil {
+		return false, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if err := lockRollups(ctx, tx); err != nil {
+		return false, err
+	}
+
+	var watermark, claimed int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT watermark, claimed FROM rollup_state WHERE resolution = $1", level.name).Scan(&watermark, &claimed)
+	if err != nil {
+		return false, err
+	}
+	if claimed <= watermark {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO `+level.table+` (series_id, bucket, min, max, sum, count)
+		`+level.sourceSQL+`
+		ON CONFLICT (series_id, bucket) DO UPDATE SET
+			min = EXCLUDED.min, max = EXCLUDED.max, sum = EXCLUDED.sum, count = EXCLUDED.count`,
+		watermark, claimed)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE rollup_state SET watermark = $1 WHERE resolution = $2", claimed, level.name)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// claimRollup moves a level's claim up to the end of the next range to roll
+// up. Writes read the first level's claim FOR SHARE, so locking the row
+// waits for writes that saw the old claim, and any write reading it after
+// the commit marks what it stages below the new one. A pass rolling up to
+// the claim therefore either sees a write or leaves it to rerollOnce.
+func claimRollup(ctx context.Context, db *sql.DB, idx int) error {
+	level := rollupLevels[idx]
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var watermark, claimed int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT watermark, claimed FROM rollup_state WHERE resolution = $1 FOR UPDATE", level.name).Scan(&watermark, &claimed)
+	if err != nil {
+		return err
+	}
+
+	upto := alignDown(time.Now().Add(-rollupGrace).Unix(), level.step)
+	if idx > 0 {
+		var parent int64
+		err := tx.QueryRowContext(ctx,
+			"SELECT watermark FROM rollup_state WHERE resolution = $1", rollupLevels[idx-1].name).Scan(&parent)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		upto = min(upto, alignDown(parent, level.step))
+	}
+
+	if watermark == 0 {
+		var first sql.NullInt64
+		err := tx.QueryRowContext(ctx,
+			"SELECT min("+level.sourceColumn+") FROM "+level.sourceTable).Scan(&first)
+		if err != nil {
+			return err
+		}
+		if !first.Valid {
+			return nil
+		}
+		watermark = alignDown(first.Int64, level.step)
+		claimed = max(claimed, watermark)
+	}
+
+	end := min(upto, watermark+maxRollupBuckets*level.step)
+	_, err = tx.ExecContext(ctx,
+		"UPDATE rollup_state SET watermark = $1, claimed = $2 WHERE resolution = $3", watermark, max(claimed, end), level.name)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockRollups takes the transaction-scoped advisory lock serializing
+// everything that writes rollup tables or moves a watermark, across
+// storage replicas. Ingest never takes it.
+func lockRollups(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('rollups'))")
+	return err
+}
+
+// markLateSamples records in rollup_dirty the hours (the coarsest level's
+// buckets) of samples staged for the write in tx that the worker has
+// already claimed. The claim is read FOR SHARE, which only ever waits on
+// the worker's short claimRollup transaction or on the commit of a pass
+// moving its watermark, never on a rollup being computed.
+func markLateSamples(ctx context.Context, tx pgx.Tx) error {
+	if len(rollupLevels) == 0 {
+		return nil
+	}
+	span := itoa(int(rollupLevels[len(rollupLevels)-1].step))
+	_, err := tx.Exec(ctx, `
+		INSERT INTO rollup_dirty (bucket, series_id)
+		SELECT DISTINCT (timestamp / `+span+`) * `+span+`, series_id FROM staged_samples
+		WHERE timestamp < (SELECT claimed FROM rollup_state WHERE resolution = $1 FOR SHARE)
+		ON CONFLICT DO NOTHING`, rollupLevels[0].name)
+	return err
+}
+
+// rerollOnce rolls up again the oldest hour in rollup_dirty for the series
+// marked in it, returning how many there were. Hours are marked against
+// the claim, which can be ahead of the watermark; rebuildRollups checks
+// them against the watermark and leaves whatever the worker has not
+// rolled up yet to its next pass, which sees the samples since their
+// marks committed with them.
+func rerollOnce(ctx context.Context, db *sql.DB) (int, error) {
+	if len(rollupLevels) == 0 {
+		return 0, nil
+	}
+	span := rollupLevels[len(rollupLevels)-1].step
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var bucket sql.NullInt64
+	if err := tx.QueryRowContext(ctx, "SELECT min(bucket) FROM rollup_dirty").Scan(&bucket); err != nil {
+		return 0, err
+	}
+	if !bucket.Valid {
+		return 0, nil
+	}
+	rows, err := tx.QueryContext(ctx, "DELETE FROM rollup_dirty WHERE bucket = $1 RETURNING series_id", bucket.Int64)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := rebuildRollups(ctx, tx, ids, bucket.Int64, bucket.Int64+span-1); err != nil {
+		return 0, err
+	}
+	return len(ids), tx.Commit()
+}
+
+// rebuildRollups recomputes, from their sources, the buckets of the given
+// series overlapping [start, end] (end <= 0 meaning no upper bound) that
+// the worker has already rolled up, after samples in that range were
+// deleted or written late. Buckets past a level's watermark are left for
+// the worker. It takes the rollup lock, so it waits for a pass in progress
+// and then sees the watermark that pass moved.
+func rebuildRollups(ctx context.Context, tx *sql.Tx, ids []int64, start, end int64) error {
+	if err := lockRollups(ctx, tx); err != nil {
+		return err
+	}
+	for _, level := range rollupLevels {
+		var watermark int64
+		err := tx.QueryRowContext(ctx,
+			"SELECT watermark FROM rollup_state WHERE resolution = $1", level.name).Scan(&watermark)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		from, to := alignDown(start, level.step), watermark
+		if end > 0 {
+			to = min(to, alignDown(end, level.step)+level.step)
+		}
+		if to <= from {
+			continue
+		}
+		_, err = tx.ExecContext(ctx,
+			"DELETE FROM "+level.table+" WHERE series_id = ANY($1) AND bucket >= $2 AND bucket < $3", ids, from, to)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO `+level.table+` (series_id, bucket, min, max, sum, count)
+			SELECT * FROM (`+level.sourceSQL+`) r (series_id, bucket, min, max, sum, count)
+			WHERE r.series_id = ANY($3)`,
+			from, to, ids)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aggregationSQL gives each aggregation as an expression over raw samples
+// and over rollup rows. LAST has no rollup form since rollups don't keep the
+// latest value, so it is always served from raw samples.
+var aggregationSQL = map[pb.GetMetricsRequest_Aggregation]struct{ raw, rollup string }{
+	pb.GetMetricsRequest_AVG:   {"avg(value)", "sum(sum) / sum(count)::float8"},
+	pb.GetMetricsRequest_MIN:   {"min(value)", "min(min)"},
+	pb.GetMetricsRequest_MAX:   {"max(value)", "max(max)"},
+	pb.GetMetricsRequest_SUM:   {"sum(value)", "sum(sum)"},
+	pb.GetMetricsRequest_COUNT: {"count(*)::float8", "sum(count)::float8"},
+	pb.GetMetricsRequest_LAST:  {"(array_agg(value ORDER BY timestamp DESC))[1]", ""},
+}
+
+// pickResolution works out the bucket width for a query and the coarsest
+// rollup level that can serve it, or nil for raw samples. The requested step
+// is widened if needed so the range fits in max_points; a widened step is
+// rounded up to a multiple of the rollup it lands on so buckets line up.
+// A step of 0 means raw, unaggregated samples.
+func pickResolution(q *SeriesQuery, now int64) (int64, *rollupLevel) {
+	step := queryStep(q, now)
+	if step > q.Step {
+		for i := len(rollupLevels) - 1; i >= 0; i-- {
+			if l := rollupLevels[i].step; l <= step {
+				step = (step + l - 1) / l * l
+				break
+			}
+		}
+	}
+	if step == 0 || aggregationSQL[q.Aggregation].rollup == "" {
+		return step, nil
+	}
+
+	var picked *rollupLevel
+	for i := range rollupLevels {
+		if step%rollupLevels[i].step == 0 {
+			picked = &rollupLevels[i]
+		}
+	}
+	return step, picked
+}
+
+// queryBuckets runs a series query that returns one aggregated point per
+// step-wide bucket. With a rollup level, buckets the worker hasn't reached
+// yet are aggregated on the fly from raw samples, so the most recent points
+// are never missing.
+func (s *pgStorage) queryBuckets(ctx context.Context, level *rollupLevel, step int64, agg pb.GetMetricsRequest_Aggregation, filter string, args []interface{}, start, end int64) (*sql.Rows, error) {
+	exprs, ok := aggregationSQL[agg]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregation %v", agg)
+	}
+	start = alignDown(start, step)
+	stepSQL := itoa(int(step))
+
+	var inner string
+	if level == nil {
+		args = append(args, start)
+		timeRange := " AND timestamp >= $" + itoa(len(args))
+		if end > 0 {
+			args = append(args, end)
+			timeRange += " AND timestamp <= $" + itoa(len(args))
+		}
+		inner = `
+			SELECT series_id, (timestamp / ` + stepSQL + `) * ` + stepSQL + ` AS bucket, ` + exprs.raw + ` AS value
+			FROM samples WHERE series_id IN (SELECT id FROM sel)` + timeRange + `
+			GROUP BY 1, 2`
+	} else {
+		var watermark int64
+		err := s.db.QueryRowContext(ctx,
+			"SELECT watermark FROM rollup_state WHERE resolution = $1", level.name).Scan(&watermark)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		args = append(args, start, watermark, max(watermark, start))
+		startArg, watermarkArg, tailArg := itoa(len(args)-2), itoa(len(args)-1), itoa(len(args))
+		rollupRange := " AND bucket >= $" + startArg + " AND bucket < $" + watermarkArg
+		tailRange := " AND timestamp >= $" + tailArg
+		if end > 0 {
+			args = append(args, end)
+			rollupRange += " AND bucket <= $" + itoa(len(args))
+			tailRange += " AND timestamp <= $" + itoa(len(args))
+		}
+
+		levelStep := itoa(int(level.step))
+		inner = `
+			SELECT series_id, (bucket / ` + stepSQL + `) * ` + stepSQL + ` AS bucket, ` + exprs.rollup + ` AS value
+			FROM (
+				SELECT series_id, bucket, min, max, sum, count FROM ` + level.table + `
+				WHERE series_id IN (SELECT id FROM sel)` + rollupRange + `
+				UNION ALL
+				SELECT series_id, (timestamp / ` + levelStep + `) * ` + levelStep + `,
+					min(value), max(value), sum(value), count(*)
+				FROM samples WHERE series_id IN (SELECT id FROM sel)` + tailRange + `
+				GROUP BY 1, 2
+			) r
+			GROUP BY 1, 2`
+	}
+
+	query := `
+		WITH sel AS (SELECT se.id, se.metric_name, se.labels FROM series se WHERE ` + filter + `)
+		SELECT sel.id, sel.metric_name, sel.labels, b.bucket, b.value
+		FROM (` + inner + `
+		) b JOIN sel ON sel.id = b.series_id
+		ORDER BY sel.id, b.bucket ASC`
+	return s.db.QueryContext(ctx, query, args...)
+}
+
+func alignDown(ts, step int64) int64 {
+	return ts - ts%step
+}
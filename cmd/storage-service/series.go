@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"sync"
+
+	pb "pmts/proto"
+)
+
+// maxCachedSeries bounds the series-ID cache. When it fills up the cache is
+// simply cleared; hot series are re-resolved on their next write.
+const maxCachedSeries = 1_000_000
+
+// labelsHash returns the canonical identity of a label set: the hex SHA-256
+// of its pairs sorted by name, each name and value terminated by a NUL byte.
+func labelsHash(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(labels[name]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type seriesKey struct {
+	userID int64
+	name   string
+	hash   string
+}
+
+// seriesCache maps (user, name, label hash) to the series row ID so the hot
+// ingest path doesn't need a lookup per series per batch. Series rows are
+// never deleted, so a cached ID stays valid for the life of the process,
+// including across storage replicas.
+type seriesCache struct {
+	mu  sync.RWMutex
+	ids map[seriesKey]int64
+}
+
+func newSeriesCache() *seriesCache {
+	return &seriesCache{ids: make(map[seriesKey]int64)}
+}
+
+func (c *seriesCache) get(key seriesKey) (int64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id, ok := c.ids[key]
+	return id, ok
+}
+
+func (c *seriesCache) put(key seriesKey, id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.ids) >= maxCachedSeries {
+		c.ids = make(map[seriesKey]int64)
+	}
+	c.ids[key] = id
+}
+
+// seriesID returns the ID of the series for the given metric, creating the
+// series row on first sight.
+func (s *pgStorage) seriesID(ctx context.Context, userID int64, metric *pb.Metric) (int64, error) {
+	labels := metric.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	key := seriesKey{userID: userID, name: metric.Name, hash: labelsHash(labels)}
+	if id, ok := s.series.get(key); ok {
+		return id, nil
+	}
+
+	const lookup = "SELECT id FROM series WHERE user_id = $1 AND metric_name = $2 AND labels_hash = $3"
+	var id int64
+	err := s.db.QueryRowContext(ctx, lookup, key.userID, key.name, key.hash).Scan(&id)
+	if err == sql.ErrNoRows {
+		labelsJSON, _ := json.Marshal(labels)
+		err = s.db.QueryRowContext(ctx,
+			`INSERT INTO series (user_id, metric_name, labels_hash, labels) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, metric_name, labels_hash) DO NOTHING RETURNING id`,
+			key.userID, key.name, key.hash, labelsJSON).Scan(&id)
+		if err == sql.ErrNoRows {
+			// Another writer created it between our lookup and insert.
+			err = s.db.QueryRowContext(ctx, lookup, key.userID, key.name, key.hash).Scan(&id)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.series.put(key, id)
+	return id, nil
+}
+
+// migrateLegacySamples converts a samples table from the old layout, where
+// every row carried user_id, metric_name and a labels JSONB, to the
+// normalized layout referencing series rows. It runs in one transaction and
+// is a no-op once the old columns are gone.
+func migrateLegacySamples(ctx context.Context, db *sql.DB) error {
+	var legacy bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'samples' AND column_name = 'metric_name'
+		)`).Scan(&legacy)
+	if err != nil || !legacy {
+		return err
+	}
+	slog.Info("Migrating legacy samples to series table")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Rows written before the gateway always set a user ID are attributed
+	// to user 1, the same default UploadSamples applies.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT COALESCE(user_id, 1), metric_name, COALESCE(NULLIF(labels, 'null'::jsonb), '{}'::jsonb)
+		FROM samples`)
+	if err != nil {
+		return err
+	}
+	type legacySeries struct {
+		userID     int64
+		name       string
+		labelsJSON []byte
+	}
+	var found []legacySeries
+	for rows.Next() {
+		var ls legacySeries
+		if err := rows.Scan(&ls.userID, &ls.name, &ls.labelsJSON); err != nil {
+			rows.Close()
+			return err
+		}
+		found = append(found, ls)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, ls := range found {
+		var labels map[string]string
+		if err := json.Unmarshal(ls.labelsJSON, &labels); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO series (user_id, metric_name, labels_hash, labels) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, metric_name, labels_hash) DO NOTHING`,
+			ls.userID, ls.name, labelsHash(labels), ls.labelsJSON)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Copy into a fresh table rather than updating in place so the old
+	// per-row name and labels actually give their disk space back.
+	_, err = tx.ExecContext(ctx, `
+		CREATE TABLE samples_migrated (
+			series_id BIGINT NOT NULL,
+			timestamp BIGINT NOT NULL,
+			value DOUBLE PRECISION NOT NULL
+		);
+		INSERT INTO samples_migrated (series_id, timestamp, value)
+		SELECT se.id, sm.timestamp, sm.value
+		FROM samples sm
+		JOIN series se
+			ON se.user_id = COALESCE(sm.user_id, 1)
+			AND se.metric_name = sm.metric_name
+			AND se.labels = COALESCE(NULLIF(sm.labels, 'null'::jsonb), '{}'::jsonb);
+		DROP TABLE samples;
+		ALTER TABLE samples_migrated RENAME TO samples;
+	`)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.Info("Legacy samples migrated", "series", len(found))
+	return nil
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	pb "pmts/proto"
+)
+
+type Server struct {
+	pb.UnimplementedMonitoringServiceServer
+	store Storage
+	// uploadTxSamples is the default StreamUpload transaction size.
+	uploadTxSamples int
+	// window applies to live ingest. StreamUpload is for backfills, whose
+	// samples are old by design, so it is exempt, as are replays of
+	// dead-lettered batches.
+	window acceptanceWindow
+	// quotas apply to every write, whatever its path.
+	quotas *quotaTracker
+}
+
+func NewServer(store Storage) (*Server, error) {
+	cfg, err := quotaConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("load limits: %w", err)
+	}
+	return &Server{
+		store:           store,
+		uploadTxSamples: envInt("UPLOAD_TX_SAMPLES", 50_000),
+		window:          windowFromEnv(),
+		quotas:          newQuotaTracker(store, cfg),
+	}, nil
+}
+
+func generateAPIKey() string {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "fallback_key_" + time.Now().String()
+	}
+	return "sk_" + hex.EncodeToString(bytes)
+}
+
+func (s *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	newKey := generateAPIKey()
+	id, err := s.store.CreateUser(ctx, req.Email, newKey)
+	if err != nil {
+		slog.Error("Failed to create user", "error", err)
+		return &pb.CreateUserResponse{Error: "Email likely already exists"}, nil
+	}
+	slog.Info("Created new user", "id", id, "email", req.Email)
+	return &pb.CreateUserResponse{UserId: id, ApiKey: newKey}, nil
+}
+
+func (s *Server) VerifyKey(ctx context.Context, req *pb.VerifyKeyRequest) (*pb.VerifyKeyResponse, error) {
+	userID, ok, err := s.store.LookupAPIKey(ctx, req.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &pb.VerifyKeyResponse{Valid: false}, nil
+	}
+	return &pb.VerifyKeyResponse{Valid: true, UserId: userID}, nil
+}
+
+func (s *Server) UploadSamples(ctx context.Context, req *pb.UploadRequest) (*pb.UploadResponse, error) {
+	uid := req.UserId
+	if uid == 0 {
+		uid = 1
+	}
+	res, err := s.storeUpload(ctx, uid, req, s.window, time.Now(), true)
+	var dupErr *duplicatesError
+	if errors.As(err, &dupErr) {
+		return &pb.UploadResponse{Error: err.Error(), DuplicateCount: int32(res.Duplicates), RejectedCount: int32(res.Rejected),
+			LimitedCount: int32(res.Limited)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UploadResponse{
+		StoredCount:    int32(res.Stored),
+		DuplicateCount: int32(res.Duplicates),
+		RejectedCount:  int32(res.Rejected),
+		LimitedCount:   int32(res.Limited),
+	}, nil
+}
+
+// uploadResult is an AppendResult plus the samples dropped beforehand for
+// falling outside the acceptance window or exceeding the user's limits.
+type uploadResult struct {
+	AppendResult
+	Rejected int
+	Limited  int
+}
+
+// errMetadataNotSaved marks a storeUpload failure that came after the
+// samples were written, so that only the metadata is left to retry.
+var errMetadataNotSaved = errors.New("metadata not saved")
+
+// storeUpload writes an upload's samples within window, as it stood when
+// the upload was received, and the user's limits, and then any metadata
+// sent along. live holds the upload to the ingest rate too.
+func (s *Server) storeUpload(ctx context.Context, userID int64, req *pb.UploadRequest, window acceptanceWindow, received time.Time, live bool) (uploadResult, error) {
+	list, rejected := window.filter(req.List, received)
+	res, err := s.appendLimited(ctx, userID, list, live)
+	res.Rejected = rejected
+	if err != nil {
+		return res, err
+	}
+	if len(req.Metadata) > 0 {
+		if err := s.store.SetMetadata(ctx, userID, req.Metadata); err != nil {
+			return res, fmt.Errorf("%w: %w", errMetadataNotSaved, err)
+		}
+	}
+	return res, nil
+}
+
+// appendLimited is AppendSamples within the user's limits, and with live
+// within the ingest rate.
+func (s *Server) appendLimited(ctx context.Context, userID int64, list []*pb.TimeSeries, live bool) (uploadResult, error) {
+	adm, err := s.quotas.admit(ctx, userID, list, time.Now(), live)
+	if err != nil {
+		return uploadResult{}, err
+	}
+	res := uploadResult{Limited: adm.limited}
+	res.AppendResult, err = s.store.AppendSamples(ctx, userID, adm.list)
+	if err != nil {
+		s.quotas.release(userID, adm)
+		return res, err
+	}
+	return res, nil
+}
+
+func (s *Server) GetMetrics(ctx context.Context, req *pb.GetMetricsRequest) (*pb.GetMetricsResponse, error) {
+	resp := &pb.GetMetricsResponse{}
+	err := s.store.QuerySeries(ctx, seriesQuery(req), func(resolution int64, chunk *pb.TimeSeries) error {
+		resp.Resolution = resolution
+		if n := len(resp.List); n > 0 && resp.List[n-1].Metric == chunk.Metric {
+			resp.List[n-1].Samples = append(resp.List[n-1].Samples, chunk.Samples...)
+			return nil
+		}
+		resp.List = append(resp.List, &pb.TimeSeries{Metric: chunk.Metric, Samples: chunk.Samples})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// StreamMetrics answers the same queries as GetMetrics without building the
+// whole result in memory, sending each chunk as soon as it is read.
+func (s *Server) StreamMetrics(req *pb.GetMetricsRequest, stream pb.MonitoringService_StreamMetricsServer) error {
+	return s.store.QuerySeries(stream.Context(), seriesQuery(req), func(resolution int64, chunk *pb.TimeSeries) error {
+		return stream.Send(&pb.GetMetricsResponse{List: []*pb.TimeSeries{chunk}, Resolution: resolution})
+	})
+}
+
+func seriesQuery(req *pb.GetMetricsRequest) *SeriesQuery {
+	uid := req.UserId
+	if uid == 0 {
+		uid = 1
+	}
+	return &SeriesQuery{
+		UserID:      uid,
+		Name:        req.MatchName,
+		Matchers:    req.Matchers,
+		Start:       req.StartTime,
+		End:         req.EndTime,
+		Step:        req.Step,
+		MaxPoints:   req.MaxPoints,
+		Aggregation: req.Aggregation,
+	}
+}
+
+func (s *Server) ListMetricNames(ctx context.Context, req *pb.ListNamesRequest) (*pb.ListNamesResponse, error) {
+	uid := req.UserId
+	if uid == 0 {
+		uid = 1
+	}
+	names, err := s.store.ListMetricNames(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListNamesResponse{Names: names}, nil
+}
+
+func (s *Server) ListLabelNames(ctx context.Context, req *pb.LabelNamesRequest) (*pb.LabelNamesResponse, error) {
+	q := labelQuery(req.UserId, req.MatchName, req.Matchers, req.StartTime, req.EndTime)
+	names, err := s.store.LabelNames(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LabelNamesResponse{Names: names}, nil
+}
+
+func (s *Server) ListLabelValues(ctx context.Context, req *pb.LabelValuesRequest) (*pb.LabelValuesResponse, error) {
+	if req.LabelName == "" {
+		return nil, fmt.Errorf("label_name is required")
+	}
+	q := labelQuery(req.UserId, req.MatchName, req.Matchers, req.StartTime, req.EndTime)
+	values, err := s.store.LabelValues(ctx, q, req.LabelName)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LabelValuesResponse{Values: values}, nil
+}
+
+func labelQuery(uid int64, name string, matchers []*pb.LabelMatcher, start, end int64) *SeriesQuery {
+	if uid == 0 {
+		uid = 1
+	}
+	return &SeriesQuery{UserID: uid, Name: name, Matchers: matchers, Start: start, End: end}
+}
+
+func (s *Server) DeleteMetric(ctx context.Context, req *pb.DeleteMetricRequest) (*pb.DeleteMetricResponse, error) {
+	if err := s.store.DeleteMetric(ctx, req.UserId, req.MetricName); err != nil {
+		slog.Error("Failed to delete metric", "error", err)
+		return nil, fmt.Errorf("DB error")
+	}
+	return &pb.DeleteMetricResponse{Ok: true}, nil
+}
+
+func (s *Server) DeleteSeries(ctx context.Context, req *pb.DeleteSeriesRequest) (*pb.DeleteSeriesResponse, error) {
+	q := labelQuery(req.UserId, req.MatchName, req.Matchers, req.StartTime, req.EndTime)
+	if q.Name == "" && len(q.Matchers) == 0 {
+		return nil, fmt.Errorf("match_name or matchers is required")
+	}
+	res, err := s.store.DeleteSeries(ctx, q, req.DryRun)
+	if err != nil {
+		slog.Error("Failed to delete series", "error", err)
+		return nil, err
+	}
+	if !req.DryRun {
+		slog.Info("Deleted series", "user_id", q.UserID, "series", res.Series, "samples", res.Samples,
+			"start", q.Start, "end", q.End)
+	}
+	return &pb.DeleteSeriesResponse{DeletedSamples: res.Samples, Series: res.Series}, nil
+}
+
+func (s *Server) CreateAlertRule(ctx context.Context, req *pb.CreateRuleRequest) (*pb.CreateRuleResponse, error) {
+	id, err := s.store.CreateAlertRule(ctx, &pb.AlertRule{
+		UserId:     req.UserId,
+		MetricName: req.MetricName,
+		Threshold:  req.Threshold,
+		WebhookUrl: req.WebhookUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateRuleResponse{RuleId: id}, nil
+}
+
+func (s *Server) GetAlertRules(ctx context.Context, req *pb.GetRulesRequest) (*pb.GetRulesResponse, error) {
+	rules, err := s.store.GetAlertRules(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetRulesResponse{Rules: rules}, nil
+}
+
+func (s *Server) DeleteAlertRule(ctx context.Context, req *pb.DeleteRuleRequest) (*pb.DeleteRuleResponse, error) {
+	ok, err := s.store.DeleteAlertRule(ctx, req.UserId, req.RuleId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DeleteRuleResponse{Ok: ok}, nil
+}
+
+func (s *Server) CreateRetentionPolicy(ctx context.Context, req *pb.CreatePolicyRequest) (*pb.CreatePolicyResponse, error) {
+	if req.RetentionDays <= 0 {
+		return nil, fmt.Errorf("retention_days must be positive")
+	}
+	if _, err := compileMatchers(req.Matchers); err != nil {
+		return nil, err
+	}
+	id, err := s.store.CreateRetentionPolicy(ctx, &pb.RetentionPolicy{
+		UserId:        req.UserId,
+		MetricName:    req.MetricName,
+		Matchers:      req.Matchers,
+		RetentionDays: req.RetentionDays,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreatePolicyResponse{PolicyId: id}, nil
+}
+
+func (s *Server) GetRetentionPolicies(ctx context.Context, req *pb.GetPoliciesRequest) (*pb.GetPoliciesResponse, error) {
+	policies, err := s.store.GetRetentionPolicies(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetPoliciesResponse{Policies: policies}, nil
+}
+
+func (s *Server) DeleteRetentionPolicy(ctx context.Context, req *pb.DeletePolicyRequest) (*pb.DeletePolicyResponse, error) {
+	ok, err := s.store.DeleteRetentionPolicy(ctx, req.UserId, req.PolicyId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DeletePolicyResponse{Ok: ok}, nil
+}
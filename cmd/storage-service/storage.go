@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	pb "pmts/proto"
+)
+
+// Storage is everything the storage service persists. The gRPC Server is a
+// thin layer over it, so backends only deal in plain values and protos.
+type Storage interface {
+	// AppendSamples writes a batch for one user, float and histogram
+	// samples alike, resolving samples that duplicate a timestamp of their
+	// series by the backend's conflict policy.
+	AppendSamples(ctx context.Context, userID int64, list []*pb.TimeSeries) (AppendResult, error)
+	// AppendBatches is AppendSamples for several users at once, for the
+	// coalescing NATS consumer. Postgres writes them in one transaction,
+	// the other backends one after another. written counts the batches
+	// stored, in order, before any error; res sums their results.
+	AppendBatches(ctx context.Context, batches []userBatch) (res AppendResult, written int, err error)
+	// ImportSamples is AppendSamples resolving duplicates by policy rather
+	// than the backend's own, for restoring an archive.
+	ImportSamples(ctx context.Context, userID int64, list []*pb.TimeSeries, policy conflictPolicy) (AppendResult, error)
+	// QuerySeries passes the matching series to emit as they are read, in
+	// chunks of at most queryChunkSamples points, along with the bucket
+	// width the points were aggregated to (0 for raw samples). Chunks of
+	// one series are emitted back to back and share the same Metric.
+	QuerySeries(ctx context.Context, q *SeriesQuery, emit emitFunc) error
+	// QueryHistograms is QuerySeries for histogram samples: chunks carry
+	// Histograms instead of Samples and are always raw, whatever q's step.
+	QueryHistograms(ctx context.Context, q *SeriesQuery, emit emitFunc) error
+	ListMetricNames(ctx context.Context, userID int64) ([]string, error)
+	// LabelNames and LabelValues list, sorted, the label names or the
+	// non-empty values of one label across the series q selects that have
+	// samples in its time range. Only q's selection fields are used.
+	LabelNames(ctx context.Context, q *SeriesQuery) ([]string, error)
+	LabelValues(ctx context.Context, q *SeriesQuery, label string) ([]string, error)
+	// UsageStats summarizes a user's series that have samples, per metric
+	// and per label name, in no particular order. Sample and byte counts
+	// only cover samples at or after since.
+	UsageStats(ctx context.Context, userID, since int64) ([]*pb.MetricUsage, []*pb.LabelUsage, error)
+	// CountWritten finds a user's series with float or histogram samples at
+	// or after since and counts those samples, for the quota tracker to
+	// pick up the day's usage after a restart.
+	CountWritten(ctx context.Context, userID, since int64) (WrittenCounts, error)
+	// SetMetadata records metric metadata. Fields an entry leaves empty keep
+	// their stored value.
+	SetMetadata(ctx context.Context, userID int64, list []*pb.MetricMetadata) error
+	// GetMetadata returns a user's metadata sorted by metric name, or only
+	// that of name when it is set.
+	GetMetadata(ctx context.Context, userID int64, name string) ([]*pb.MetricMetadata, error)
+	// DeleteMetric forgets a metric: every sample of it along with its
+	// metadata and the alert rules that watch it, which are keyed by its
+	// name and would otherwise outlive it.
+	DeleteMetric(ctx context.Context, userID int64, name string) error
+	// DeleteSeries removes the float and histogram samples of the series q
+	// selects within its time range. It corrects data rather than forgetting
+	// a metric, which keeps being sent, so its metadata and alert rules are
+	// left alone even when every sample goes. Only q's selection fields are
+	// used. With dryRun nothing is removed and the result counts what would
+	// have been.
+	DeleteSeries(ctx context.Context, q *SeriesQuery, dryRun bool) (DeleteResult, error)
+
+	CreateUser(ctx context.Context, email, apiKey string) (int64, error)
+	// LookupAPIKey returns the owner of an API key, or false if it is unknown.
+	LookupAPIKey(ctx context.Context, apiKey string) (int64, bool, error)
+
+	CreateAlertRule(ctx context.Context, rule *pb.AlertRule) (int64, error)
+	// GetAlertRules returns one user's rules, or everyone's when userID is 0.
+	GetAlertRules(ctx context.Context, userID int64) ([]*pb.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, userID, ruleID int64) (bool, error)
+
+	CreateRetentionPolicy(ctx context.Context, policy *pb.RetentionPolicy) (int64, error)
+	// GetRetentionPolicies returns one user's policies, or everyone's when
+	// userID is 0.
+	GetRetentionPolicies(ctx context.Context, userID int64) ([]*pb.RetentionPolicy, error)
+	DeleteRetentionPolicy(ctx context.Context, userID, policyID int64) (bool, error)
+
+	// Start launches the backend's background maintenance: retention,
+	// rollups and whatever else it needs.
+	Start(logger *slog.Logger)
+	Close() error
+}
+
+// userBatch is one user's share of a coalesced write.
+type userBatch struct {
+	userID int64
+	list   []*pb.TimeSeries
+}
+
+// appendEach implements AppendBatches for backends without transactions
+// spanning users, one AppendSamples call per batch.
+func appendEach(ctx context.Context, batches []userBatch, appendFn func(context.Context, int64, []*pb.TimeSeries) (AppendResult, error)) (AppendResult, int, error) {
+	var total AppendResult
+	for i, b := range batches {
+		res, err := appendFn(ctx, b.userID, b.list)
+		if err != nil {
+			return total, i, err
+		}
+		total.Stored += res.Stored
+		total.Duplicates += res.Duplicates
+	}
+	return total, len(batches), nil
+}
+
+// WrittenCounts is what CountWritten found: the series written to and how
+// many samples, float and histogram alike, they hold.
+type WrittenCounts struct {
+	Series  []seriesKey
+	Samples int64
+}
+
+// countWritten implements CountWritten for backends holding their samples
+// in process or on local disk, where reading them is cheap enough.
+func countWritten(ctx context.Context, s Storage, userID, since int64) (WrittenCounts, error) {
+	var counts WrittenCounts
+	seen := make(map[seriesKey]bool)
+	count := func(metric *pb.Metric, n int) {
+		key := seriesKey{userID: userID, name: metric.Name, hash: labelsHash(metric.Labels)}
+		if !seen[key] {
+			seen[key] = true
+			counts.Series = append(counts.Series, key)
+		}
+		counts.Samples += int64(n)
+	}
+	err := s.QuerySeries(ctx, &SeriesQuery{UserID: userID, Start: since}, func(_ int64, chunk *pb.TimeSeries) error {
+		count(chunk.Metric, len(chunk.Samples))
+		return nil
+	})
+	if err != nil {
+		return WrittenCounts{}, err
+	}
+	err = s.QueryHistograms(ctx, &SeriesQuery{UserID: userID, Start: since}, func(_ int64, chunk *pb.TimeSeries) error {
+		count(chunk.Metric, len(chunk.Histograms))
+		return nil
+	})
+	if err != nil {
+		return WrittenCounts{}, err
+	}
+	return counts, nil
+}
+
+type emitFunc func(resolution int64, chunk *pb.TimeSeries) error
+
+// queryChunkSamples bounds the points per chunk handed to an emitFunc, and
+// so the size of each StreamMetrics message.
+const queryChunkSamples = 1000
+
+// emitChunks splits one series' samples into chunks and emits them.
+func emitChunks(emit emitFunc, resolution int64, metric *pb.Metric, samples []*pb.Sample) error {
+	for start := 0; start < len(samples); start += queryChunkSamples {
+		end := min(start+queryChunkSamples, len(samples))
+		chunk := &pb.TimeSeries{Metric: metric, Samples: samples[start:end:end]}
+		if err := emit(resolution, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateBatch rejects a batch holding a malformed histogram before any of
+// it is written.
+func validateBatch(list []*pb.TimeSeries) error {
+	for _, ts := range list {
+		for _, h := range ts.Histograms {
+			if err := validHistogram(h); err != nil {
+				return fmt.Errorf("%s: %w", ts.Metric.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SeriesQuery selects series by name and label matchers over a time range.
+// A non-zero Step (possibly widened to honor MaxPoints) asks for samples to
+// be combined into Step-wide buckets with Aggregation.
+type SeriesQuery struct {
+	UserID      int64
+	Name        string
+	Matchers    []*pb.LabelMatcher
+	Start       int64
+	End         int64
+	Step        int64
+	MaxPoints   int32
+	Aggregation pb.GetMetricsRequest_Aggregation
+}
+
+// DeleteResult is what DeleteSeries removed: the samples, float and
+// histogram alike, and the number of series they came from.
+type DeleteResult struct {
+	Series  int64
+	Samples int64
+}
+
+// inRange reports whether ts lies within [start, end]; end <= 0 means no
+// upper bound.
+func inRange(ts, start, end int64) bool {
+	return ts >= start && (end <= 0 || ts <= end)
+}
+
+// openStorage builds the backend named by STORAGE_BACKEND: "postgres" (the
+// default), "tsdb", the embedded engine storing under TSDB_DIR, or
+// "memory", which keeps everything in process and is meant for tests and
+// local development. DUPLICATE_POLICY sets the conflict policy for any of
+// them.
+func openStorage() (Storage, error) {
+	policy, err := conflictPolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "postgres":
+		store, err := openPostgres(policy)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "tsdb":
+		dir := os.Getenv("TSDB_DIR")
+		if dir == "" {
+			dir = "data"
+		}
+		store, err := openTSDB(dir, envInt("RETENTION_DAYS", 30), policy)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "memory":
+		return newMemStorage(envInt("RETENTION_DAYS", 30), policy), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+// queryStep returns the bucket width a query asks for: its step, widened
+// if needed so the range fits in max_points. 0 means raw samples.
+func queryStep(q *SeriesQuery, now int64) int64 {
+	step := q.Step
+	if q.MaxPoints > 0 && q.Start > 0 {
+		end := q.End
+		if end == 0 {
+			end = now
+		}
+		if span := end - q.Start; span > 0 {
+			step = max(step, (span+int64(q.MaxPoints)-1)/int64(q.MaxPoints))
+		}
+	}
+	return step
+}
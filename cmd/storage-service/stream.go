@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"pmts/stream"
+)
+
+func natsAddr() string {
+	if addr := os.Getenv("NATS_ADDR"); addr != "" {
+		return addr
+	}
+	return "nats://localhost:4222"
+}
+
+// consumerRetryDelay is how long the listener waits before reopening a
+// consumer that closed under it, and between attempts that fail.
+const consumerRetryDelay = 5 * time.Second
+
+// startNatsListener stores batches from the stream as they arrive,
+// coalescing them into bulk writes, and acks each only once it is
+// committed. Should the consumer close under it, say because it was
+// deleted, it is recreated. The returned stop function drains the
+// consumer, waiting for buffered batches to be written.
+func startNatsListener(ctx context.Context, js jetstream.JetStream, srv *Server, logger *slog.Logger) (stop func(), err error) {
+	rd, err := stream.RedeliveryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ensureDeadLetterStream(ctx, js); err != nil {
+		return nil, fmt.Errorf("create dead-letter stream: %w", err)
+	}
+	w := &ingestWorker{ctx: ctx, js: js, srv: srv, rd: rd, limits: batchLimitsFromEnv(), logger: logger}
+	open := func() (jetstream.MessagesContext, error) {
+		str, err := stream.Ensure(ctx, js)
+		if err != nil {
+			return nil, fmt.Errorf("create stream: %w", err)
+		}
+		cons, err := str.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+			Durable:        "storage-workers",
+			FilterSubjects: []string{stream.UploadSubject, stream.ReplaySubject},
+			DeliverPolicy:  jetstream.DeliverAllPolicy,
+			AckPolicy:      jetstream.AckExplicitPolicy,
+			AckWait:        rd.AckWait,
+			MaxDeliver:     rd.MaxDeliver,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create consumer: %w", err)
+		}
+		return cons.Messages(jetstream.PullMaxMessages(w.limits.messages))
+	}
+	it, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	stopping := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		current := it
+		for {
+			w.run(current)
+			select {
+			case <-stopping:
+				return
+			default:
+			}
+			logger.Error("NATS consumer stopped unexpectedly, reopening it")
+			for current = nil; current == nil; {
+				select {
+				case <-stopping:
+					return
+				case <-time.After(consumerRetryDelay):
+				}
+				next, err := open()
+				if err != nil {
+					logger.Error("Reopening NATS consumer failed", "error", err)
+					continue
+				}
+				mu.Lock()
+				select {
+				case <-stopping:
+					mu.Unlock()
+					next.Stop()
+					return
+				default:
+				}
+				it, current = next, next
+				mu.Unlock()
+			}
+			logger.Info("NATS consumer reopened")
+		}
+	}()
+	return func() {
+		mu.Lock()
+		close(stopping)
+		current := it
+		mu.Unlock()
+		current.Drain()
+		<-done
+	}, nil
+}
@@ -0,0 +1,1400 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// tsdbStorage is an embedded time series database for self-hosted installs
+// that don't want to run Postgres. Writes go to a write-ahead log and an
+// in-memory head; once head data is old enough it is cut into immutable
+// two-hour blocks of Gorilla-compressed chunks, which are compacted into
+// larger blocks over time and deleted whole when they age out.
+//
+// Users, alert rules and retention policies are small and rarely written,
+// so they live in a memStorage that is saved to metadata.json after every
+// change.
+type tsdbStorage struct {
+	dir           string
+	retentionDays int
+	policy        conflictPolicy
+
+	meta   *memStorage
+	metaMu sync.Mutex // serializes metadata.json writes
+
+	// maintMu serializes everything that replaces blocks: head cuts,
+	// compaction, retention and deletes.
+	maintMu sync.Mutex
+
+	mu      sync.RWMutex
+	head    map[seriesKey]*headSeries
+	refs    map[uint64]*headSeries
+	nextRef uint64
+	wal     *wal
+	blocks  []*block
+
+	// replayFrom drops replayed samples older than it; see recoverHeadCut.
+	replayFrom int64
+}
+
+type headSeries struct {
+	ref    uint64
+	userID int64
+	metric *pb.Metric
+	// samples and histograms are kept sorted by timestamp.
+	samples    []*pb.Sample
+	histograms []*pb.HistogramSample
+}
+
+// hasDataIn is memSeries.hasDataIn for head series.
+func (hs *headSeries) hasDataIn(start, end int64) bool {
+	return hasSamples(hs.samples, start, end) || hasHistograms(hs.histograms, start, end)
+}
+
+const (
+	tsdbMetadataFile = "metadata.json"
+	tsdbWALFile      = "wal"
+	tsdbHeadCutFile  = "head_cut.json"
+)
+
+// compactionRanges are the block sizes in seconds, smallest first. Head
+// data is cut into blocks of the first size; blocks that together fill an
+// aligned window of the next size are merged into one.
+var compactionRanges = []int64{2 * 3600, 6 * 3600, 18 * 3600, 54 * 3600}
+
+func openTSDB(dir string, retentionDays int, policy conflictPolicy) (*tsdbStorage, error) {
+	if err := os.MkdirAll(filepath.Join(dir, blocksDir), 0o755); err != nil {
+		return nil, err
+	}
+	s := &tsdbStorage{
+		dir:           dir,
+		retentionDays: retentionDays,
+		policy:        policy,
+		meta:          newMemStorage(retentionDays, keepFirst),
+		head:          make(map[seriesKey]*headSeries),
+		refs:          make(map[uint64]*headSeries),
+		replayFrom:    math.MinInt64,
+	}
+	if err := s.loadMetadata(); err != nil {
+		return nil, fmt.Errorf("load metadata: %w", err)
+	}
+	blocks, err := openBlocks(filepath.Join(dir, blocksDir))
+	if err != nil {
+		return nil, err
+	}
+	s.blocks = blocks
+
+	var cut headCutMarker
+	markerPath := filepath.Join(dir, tsdbHeadCutFile)
+	err = readJSON(markerPath, &cut)
+	interrupted := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if interrupted {
+		if err := s.recoverHeadCut(cut); err != nil {
+			return nil, err
+		}
+	}
+
+	walPath := filepath.Join(dir, tsdbWALFile)
+	if err := replayWAL(walPath, s); err != nil {
+		return nil, fmt.Errorf("replay WAL: %w", err)
+	}
+	s.replayFrom = math.MinInt64
+	if s.wal, err = openWAL(walPath); err != nil {
+		return nil, err
+	}
+	if interrupted {
+		if err := s.checkpointWAL(); err != nil {
+			return nil, err
+		}
+		if err := os.Remove(markerPath); err != nil {
+			return nil, err
+		}
+	}
+	slog.Info("TSDB opened", "dir", dir, "blocks", len(s.blocks), "head_series", len(s.head))
+	return s, nil
+}
+
+func (s *tsdbStorage) replaySeries(ref uint64, userID int64, metric *pb.Metric) {
+	hs := &headSeries{ref: ref, userID: userID, metric: metric}
+	s.head[seriesKey{userID: userID, name: metric.Name, hash: labelsHash(metric.Labels)}] = hs
+	s.refs[ref] = hs
+	s.nextRef = max(s.nextRef, ref)
+}
+
+func (s *tsdbStorage) replaySamples(ref uint64, samples []*pb.Sample) {
+	// Samples for a series deleted later in the log have no entry.
+	hs, ok := s.refs[ref]
+	if !ok {
+		return
+	}
+	if s.replayFrom != math.MinInt64 {
+		samples = slices.DeleteFunc(samples, func(sample *pb.Sample) bool { return sample.Timestamp < s.replayFrom })
+	}
+	// Only accepted samples were logged, so a later record for the same
+	// timestamp can only be an overwrite.
+	hs.samples, _, _ = mergeSamples(hs.samples, samples, overwrite)
+}
+
+func (s *tsdbStorage) replayHistograms(ref uint64, hists []*pb.HistogramSample) {
+	hs, ok := s.refs[ref]
+	if !ok {
+		return
+	}
+	if s.replayFrom != math.MinInt64 {
+		hists = slices.DeleteFunc(hists, func(h *pb.HistogramSample) bool { return h.Timestamp < s.replayFrom })
+	}
+	hs.histograms, _, _ = mergeSamples(hs.histograms, hists, overwrite)
+}
+
+func (s *tsdbStorage) replayDelete(userID int64, name string) {
+	s.deleteHeadSeries(userID, name)
+}
+
+func (s *tsdbStorage) replayDeleteRange(ref uint64, start, end int64) {
+	if hs, ok := s.refs[ref]; ok {
+		hs.deleteRange(start, end)
+	}
+}
+
+// deleteRange drops the series' samples within [start, end] and returns
+// how many there were.
+func (hs *headSeries) deleteRange(start, end int64) int {
+	n := len(hs.samples) + len(hs.histograms)
+	hs.samples = slices.DeleteFunc(hs.samples, func(s *pb.Sample) bool { return inRange(s.Timestamp, start, end) })
+	hs.histograms = slices.DeleteFunc(hs.histograms, func(h *pb.HistogramSample) bool { return inRange(h.Timestamp, start, end) })
+	return n - len(hs.samples) - len(hs.histograms)
+}
+
+func (s *tsdbStorage) deleteHeadSeries(userID int64, name string) {
+	for key, hs := range s.head {
+		if key.userID == userID && key.name == name {
+			delete(s.head, key)
+			delete(s.refs, hs.ref)
+		}
+	}
+}
+
+func (s *tsdbStorage) Start(logger *slog.Logger) {
+	ticker := time.NewTicker(1 * time.Minute)
+	go func() {
+		for range ticker.C {
+			if err := s.maintain(time.Now().Unix(), logger); err != nil {
+				logger.Error("TSDB maintenance failed", "error", err)
+			}
+		}
+	}()
+}
+
+func (s *tsdbStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.wal.close()
+	for _, b := range s.blocks {
+		b.close()
+	}
+	return err
+}
+
+func (s *tsdbStorage) AppendBatches(ctx context.Context, batches []userBatch) (AppendResult, int, error) {
+	return appendEach(ctx, batches, s.AppendSamples)
+}
+
+func (s *tsdbStorage) AppendSamples(ctx context.Context, userID int64, list []*pb.TimeSeries) (AppendResult, error) {
+	return s.appendSamples(userID, list, s.policy)
+}
+
+func (s *tsdbStorage) ImportSamples(ctx context.Context, userID int64, list []*pb.TimeSeries, policy conflictPolicy) (AppendResult, error) {
+	return s.appendSamples(userID, list, policy)
+}
+
+func (s *tsdbStorage) appendSamples(userID int64, list []*pb.TimeSeries, policy conflictPolicy) (AppendResult, error) {
+	if err := validateBatch(list); err != nil {
+		return AppendResult{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Duplicates are looked for in the head and, for samples old enough
+	// to fall in a block's range, in the blocks. An overwrite of a block's
+	// sample goes to the head and shadows it from then on.
+	blocksEnd := int64(math.MinInt64)
+	for _, b := range s.blocks {
+		blocksEnd = max(blocksEnd, b.meta.MaxTime)
+	}
+
+	// Everything is merged, logged and synced before the head changes, so
+	// a write that fails or is rejected here leaves nothing half-applied.
+	// A series may appear more than once in a batch, so merges build on
+	// each other.
+	type pending struct {
+		samples    []*pb.Sample
+		histograms []*pb.HistogramSample
+	}
+	type record struct {
+		ref        uint64
+		samples    []*pb.Sample
+		histograms []*pb.HistogramSample
+	}
+	batch := make(map[*headSeries]*pending)
+	var (
+		records []record
+		res     AppendResult
+	)
+	for _, ts := range list {
+		if len(ts.Samples) == 0 && len(ts.Histograms) == 0 {
+			continue
+		}
+		key := seriesKey{userID: userID, name: ts.Metric.Name, hash: labelsHash(ts.Metric.Labels)}
+		hs, ok := s.head[key]
+		if !ok {
+			labels := make(map[string]string, len(ts.Metric.Labels))
+			for k, v := range ts.Metric.Labels {
+				labels[k] = v
+			}
+			s.nextRef++
+			hs = &headSeries{ref: s.nextRef, userID: userID, metric: &pb.Metric{Name: ts.Metric.Name, Labels: labels}}
+			if err := s.wal.logSeries(hs); err != nil {
+				return AppendResult{}, err
+			}
+			s.head[key] = hs
+			s.refs[hs.ref] = hs
+		}
+		p, ok := batch[hs]
+		if !ok {
+			p = &pending{hs.samples, hs.histograms}
+			batch[hs] = p
+		}
+
+		samples, hists := ts.Samples, ts.Histograms
+		if from := minTimestamp(samples); from < blocksEnd {
+			stored, err := s.storedInBlocks(hs, from, blocksEnd, false)
+			if err != nil {
+				return AppendResult{}, err
+			}
+			var dups int
+			samples, dups = dropStored(samples, stored, policy)
+			res.Duplicates += dups
+		}
+		if from := minTimestamp(hists); from < blocksEnd {
+			stored, err := s.storedInBlocks(hs, from, blocksEnd, true)
+			if err != nil {
+				return AppendResult{}, err
+			}
+			var dups int
+			hists, dups = dropStored(hists, stored, policy)
+			res.Duplicates += dups
+		}
+
+		r := record{ref: hs.ref}
+		var dups, histDups int
+		p.samples, r.samples, dups = mergeSamples(p.samples, samples, policy)
+		p.histograms, r.histograms, histDups = mergeSamples(p.histograms, hists, policy)
+		res.Stored += len(r.samples) + len(r.histograms)
+		res.Duplicates += dups + histDups
+		records = append(records, r)
+	}
+	if policy == rejectConflicts && res.Duplicates > 0 {
+		return AppendResult{Duplicates: res.Duplicates}, &duplicatesError{res.Duplicates}
+	}
+
+	for _, r := range records {
+		if len(r.samples) > 0 {
+			if err := s.wal.logSamples(r.ref, r.samples); err != nil {
+				return AppendResult{}, err
+			}
+		}
+		if len(r.histograms) > 0 {
+			if err := s.wal.logHistograms(r.ref, r.histograms); err != nil {
+				return AppendResult{}, err
+			}
+		}
+	}
+	if err := s.wal.sync(); err != nil {
+		return AppendResult{}, err
+	}
+	for hs, p := range batch {
+		hs.samples, hs.histograms = p.samples, p.histograms
+	}
+	return res, nil
+}
+
+func minTimestamp[T timestamped](xs []T) int64 {
+	m := int64(math.MaxInt64)
+	for _, x := range xs {
+		m = min(m, x.GetTimestamp())
+	}
+	return m
+}
+
+// storedInBlocks returns the timestamps of a series' float or histogram
+// samples in blocks within [from, to]. The caller must hold s.mu.
+func (s *tsdbStorage) storedInBlocks(hs *headSeries, from, to int64, histograms bool) (map[int64]bool, error) {
+	stored := make(map[int64]bool)
+	for _, b := range s.blocks {
+		if !b.overlaps(from, to) {
+			continue
+		}
+		for i := range b.series {
+			bs := &b.series[i]
+			if bs.UserID != hs.userID || bs.Name != hs.metric.Name || !maps.Equal(bs.Labels, hs.metric.Labels) {
+				continue
+			}
+			if histograms {
+				part, err := b.readHistograms(bs, from, to)
+				if err != nil {
+					return nil, err
+				}
+				for _, h := range part {
+					stored[h.Timestamp] = true
+				}
+			} else {
+				part, err := b.readSeries(bs, from, to)
+				if err != nil {
+					return nil, err
+				}
+				for _, sample := range part {
+					stored[sample.Timestamp] = true
+				}
+			}
+		}
+	}
+	return stored, nil
+}
+
+// blockRef is a series' entry in a block.
+type blockRef struct {
+	b      *block
+	series *blockSeries
+}
+
+// seriesMatch is a series a query selected: its entries in the blocks
+// overlapping the query range, oldest written first, and its head data
+// within the range.
+type seriesMatch struct {
+	metric         *pb.Metric
+	blocks         []blockRef
+	head           []*pb.Sample
+	headHistograms []*pb.HistogramSample
+}
+
+// selectSeries finds the series q selects over [start, q.End], sorted by
+// key. Under the lock, it only finds them and copies their head data.
+// Blocks are read afterwards, one series at a time, so the blocks found are
+// pinned to keep compaction from deleting them in the meantime; the caller
+// must call release once done reading.
+func (s *tsdbStorage) selectSeries(q *SeriesQuery, start int64) (matches []*seriesMatch, release func(), err error) {
+	matchers, err := compileMatchers(q.Matchers)
+	if err != nil {
+		return nil, nil, err
+	}
+	selected := func(userID int64, name string, labels map[string]string) bool {
+		return userID == q.UserID && (q.Name == "" || name == q.Name) && matchSeries(matchers, name, labels)
+	}
+
+	found := make(map[seriesKey]*seriesMatch)
+	lookup := func(name string, labels map[string]string) *seriesMatch {
+		key := seriesKey{userID: q.UserID, name: name, hash: labelsHash(labels)}
+		m, ok := found[key]
+		if !ok {
+			m = &seriesMatch{metric: &pb.Metric{Name: name, Labels: labels}}
+			found[key] = m
+		}
+		return m
+	}
+
+	s.mu.RLock()
+	var pinned []*block
+	for _, b := range s.blocks {
+		if !b.overlaps(start, q.End) {
+			continue
+		}
+		used := false
+		for i := range b.series {
+			bs := &b.series[i]
+			if selected(bs.UserID, bs.Name, bs.Labels) {
+				m := lookup(bs.Name, bs.Labels)
+				m.blocks = append(m.blocks, blockRef{b, bs})
+				used = true
+			}
+		}
+		if used {
+			b.readers.Add(1)
+			pinned = append(pinned, b)
+		}
+	}
+	for _, hs := range s.head {
+		if !selected(hs.userID, hs.metric.Name, hs.metric.Labels) || !hs.hasDataIn(start, q.End) {
+			continue
+		}
+		m := lookup(hs.metric.Name, hs.metric.Labels)
+		m.head = sampleRange(hs.samples, start, q.End)
+		m.headHistograms = histogramRange(hs.histograms, start, q.End)
+	}
+	s.mu.RUnlock()
+
+	keys := make([]seriesKey, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sortSeriesKeys(keys)
+	matches = make([]*seriesMatch, 0, len(keys))
+	for _, key := range keys {
+		m := found[key]
+		slices.SortStableFunc(m.blocks, func(a, b blockRef) int { return cmp.Compare(a.b.meta.Written, b.b.meta.Written) })
+		matches = append(matches, m)
+	}
+	release = func() {
+		for _, b := range pinned {
+			b.readers.Done()
+		}
+	}
+	return matches, release, nil
+}
+
+func (s *tsdbStorage) QuerySeries(ctx context.Context, q *SeriesQuery, emit emitFunc) error {
+	step := queryStep(q, time.Now().Unix())
+	start := q.Start
+	if step > 0 {
+		start = alignDown(start, step)
+	}
+	matches, release, err := s.selectSeries(q, start)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for _, m := range matches {
+		var samples []*pb.Sample
+		for _, ref := range m.blocks {
+			part, err := ref.b.readSeries(ref.series, start, q.End)
+			if err != nil {
+				return err
+			}
+			samples = append(samples, part...)
+		}
+		samples = append(samples, m.head...)
+		if len(samples) == 0 {
+			continue
+		}
+		// Blocks may overlap each other and the head, so merge by time. Where
+		// an overwrite left samples at the same timestamp in several places,
+		// the latest written, read last, wins.
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+		samples = keepLast(samples)
+		if step > 0 {
+			if samples, err = aggregateSamples(samples, step, q.Aggregation); err != nil {
+				return err
+			}
+		}
+		if err := emitChunks(emit, step, m.metric, samples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *tsdbStorage) QueryHistograms(ctx context.Context, q *SeriesQuery, emit emitFunc) error {
+	matches, release, err := s.selectSeries(q, q.Start)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for _, m := range matches {
+		var hs []*pb.HistogramSample
+		for _, ref := range m.blocks {
+			part, err := ref.b.readHistograms(ref.series, q.Start, q.End)
+			if err != nil {
+				return err
+			}
+			hs = append(hs, part...)
+		}
+		hs = append(hs, m.headHistograms...)
+		if len(hs) == 0 {
+			continue
+		}
+		sort.SliceStable(hs, func(i, j int) bool { return hs[i].Timestamp < hs[j].Timestamp })
+		hs = keepLast(hs)
+		if err := emitHistogramChunks(emit, m.metric, hs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortSeriesKeys(keys []seriesKey) {
+	slices.SortFunc(keys, func(a, b seriesKey) int {
+		if a.userID != b.userID {
+			return int(a.userID - b.userID)
+		}
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.hash, b.hash)
+	})
+}
+
+func (s *tsdbStorage) ListMetricNames(ctx context.Context, userID int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var names []string
+	addName := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, b := range s.blocks {
+		for i := range b.series {
+			if b.series[i].UserID == userID {
+				addName(b.series[i].Name)
+			}
+		}
+	}
+	for _, hs := range s.head {
+		if hs.userID == userID && (len(hs.samples) > 0 || len(hs.histograms) > 0) {
+			addName(hs.metric.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *tsdbStorage) LabelNames(ctx context.Context, q *SeriesQuery) ([]string, error) {
+	return s.collectLabels(q, newLabelCollector(""))
+}
+
+func (s *tsdbStorage) LabelValues(ctx context.Context, q *SeriesQuery, label string) ([]string, error) {
+	return s.collectLabels(q, newLabelCollector(label))
+}
+
+// collectLabels works from block indexes and the head without reading any
+// chunks, so block data is only checked at chunk granularity.
+func (s *tsdbStorage) collectLabels(q *SeriesQuery, c *labelCollector) ([]string, error) {
+	matchers, err := compileMatchers(q.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	selected := func(userID int64, name string, labels map[string]string) bool {
+		return userID == q.UserID && (q.Name == "" || name == q.Name) && matchSeries(matchers, name, labels)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, b := range s.blocks {
+		if !b.overlaps(q.Start, q.End) {
+			continue
+		}
+		for i := range b.series {
+			bs := &b.series[i]
+			if selected(bs.UserID, bs.Name, bs.Labels) && bs.hasChunksIn(q.Start, q.End) {
+				c.add(bs.Name, bs.Labels)
+			}
+		}
+	}
+	for _, hs := range s.head {
+		if selected(hs.userID, hs.metric.Name, hs.metric.Labels) && hs.hasDataIn(q.Start, q.End) {
+			c.add(hs.metric.Name, hs.metric.Labels)
+		}
+	}
+	return c.sorted(), nil
+}
+
+// UsageStats takes first and last seen times from block indexes, and only
+// reads the float and histogram chunks that hold samples since the cutoff,
+// pinning their blocks as QuerySeries does. Block bytes are the compressed size of those chunks.
+func (s *tsdbStorage) UsageStats(ctx context.Context, userID, since int64) ([]*pb.MetricUsage, []*pb.LabelUsage, error) {
+	type usage struct {
+		metric                      *pb.Metric
+		first, last, samples, bytes int64
+		recent                      []blockRef
+	}
+	found := make(map[seriesKey]*usage)
+	lookup := func(name string, labels map[string]string) *usage {
+		key := seriesKey{userID: userID, name: name, hash: labelsHash(labels)}
+		su, ok := found[key]
+		if !ok {
+			su = &usage{metric: &pb.Metric{Name: name, Labels: labels}, first: math.MaxInt64, last: math.MinInt64}
+			found[key] = su
+		}
+		return su
+	}
+
+	s.mu.RLock()
+	var pinned []*block
+	for _, b := range s.blocks {
+		used := false
+		for i := range b.series {
+			bs := &b.series[i]
+			if bs.UserID != userID || len(bs.Chunks)+len(bs.Histograms) == 0 {
+				continue
+			}
+			su := lookup(bs.Name, bs.Labels)
+			for _, c := range slices.Concat(bs.Chunks, bs.Histograms) {
+				su.first = min(su.first, c.MinTime)
+				su.last = max(su.last, c.MaxTime)
+			}
+			if bs.hasChunksIn(since, 0) {
+				su.recent = append(su.recent, blockRef{b, bs})
+				used = true
+			}
+		}
+		if used {
+			b.readers.Add(1)
+			pinned = append(pinned, b)
+		}
+	}
+	for _, hs := range s.head {
+		if hs.userID != userID || len(hs.samples)+len(hs.histograms) == 0 {
+			continue
+		}
+		su := lookup(hs.metric.Name, hs.metric.Labels)
+		if n := len(hs.samples); n > 0 {
+			su.first = min(su.first, hs.samples[0].Timestamp)
+			su.last = max(su.last, hs.samples[n-1].Timestamp)
+			i := sort.Search(n, func(i int) bool { return hs.samples[i].Timestamp >= since })
+			su.samples += int64(n - i)
+			su.bytes += int64(n-i) * rawSampleBytes
+		}
+		if n := len(hs.histograms); n > 0 {
+			su.first = min(su.first, hs.histograms[0].Timestamp)
+			su.last = max(su.last, hs.histograms[n-1].Timestamp)
+			i := sort.Search(n, func(i int) bool { return hs.histograms[i].Timestamp >= since })
+			for _, h := range hs.histograms[i:] {
+				su.samples++
+				su.bytes += rawHistogramBytes(h)
+			}
+		}
+	}
+	s.mu.RUnlock()
+	defer func() {
+		for _, b := range pinned {
+			b.readers.Done()
+		}
+	}()
+
+	u := newUsageCollector()
+	for _, su := range found {
+		for _, ref := range su.recent {
+			samples, err := ref.b.readSeries(ref.series, since, 0)
+			if err != nil {
+				return nil, nil, err
+			}
+			hists, err := ref.b.readHistograms(ref.series, since, 0)
+			if err != nil {
+				return nil, nil, err
+			}
+			su.samples += int64(len(samples) + len(hists))
+			for _, c := range slices.Concat(ref.series.Chunks, ref.series.Histograms) {
+				if c.MaxTime >= since {
+					su.bytes += int64(c.Length)
+				}
+			}
+		}
+		u.add(su.metric, su.first, su.last, su.samples, su.bytes)
+	}
+	metrics, labels := u.result()
+	return metrics, labels, nil
+}
+
+func (s *tsdbStorage) CountWritten(ctx context.Context, userID, since int64) (WrittenCounts, error) {
+	return countWritten(ctx, s, userID, since)
+}
+
+func (s *tsdbStorage) DeleteMetric(ctx context.Context, userID int64, name string) error {
+	s.maintMu.Lock()
+	defer s.maintMu.Unlock()
+
+	s.mu.Lock()
+	s.deleteHeadSeries(userID, name)
+	err := s.wal.logDelete(userID, name)
+	if err == nil {
+		err = s.wal.sync()
+	}
+	blocks := slices.Clone(s.blocks)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Blocks are immutable, so each one holding the metric is rewritten
+	// without it.
+	selected := func(bs *blockSeries) bool { return bs.UserID == userID && bs.Name == name }
+	if _, err := s.deleteFromBlocks(blocks, selected, math.MinInt64, 0, false); err != nil {
+		return err
+	}
+
+	if err := s.meta.DeleteMetric(ctx, userID, name); err != nil {
+		return err
+	}
+	return s.saveMetadata()
+}
+
+// DeleteSeries trims the selected head series, logging each trim to the
+// WAL, and rewrites the blocks holding samples in the range without them.
+func (s *tsdbStorage) DeleteSeries(ctx context.Context, q *SeriesQuery, dryRun bool) (DeleteResult, error) {
+	matchers, err := compileMatchers(q.Matchers)
+	if err != nil {
+		return DeleteResult{}, err
+	}
+	selected := func(userID int64, name string, labels map[string]string) bool {
+		return userID == q.UserID && (q.Name == "" || name == q.Name) && matchSeries(matchers, name, labels)
+	}
+
+	s.maintMu.Lock()
+	defer s.maintMu.Unlock()
+
+	removed := make(map[seriesKey]int64)
+	s.mu.Lock()
+	for key, hs := range s.head {
+		if !selected(hs.userID, hs.metric.Name, hs.metric.Labels) || !hs.hasDataIn(q.Start, q.End) {
+			continue
+		}
+		if dryRun {
+			removed[key] += int64(len(sampleRange(hs.samples, q.Start, q.End)) + len(histogramRange(hs.histograms, q.Start, q.End)))
+			continue
+		}
+		if err = s.wal.logDeleteRange(hs.ref, q.Start, q.End); err != nil {
+			break
+		}
+		removed[key] += int64(hs.deleteRange(q.Start, q.End))
+	}
+	if err == nil && !dryRun {
+		err = s.wal.sync()
+	}
+	blocks := slices.Clone(s.blocks)
+	s.mu.Unlock()
+	if err != nil {
+		return DeleteResult{}, err
+	}
+
+	fromBlocks, err := s.deleteFromBlocks(blocks, func(bs *blockSeries) bool {
+		return selected(bs.UserID, bs.Name, bs.Labels)
+	}, q.Start, q.End, dryRun)
+	if err != nil {
+		return DeleteResult{}, err
+	}
+	for key, n := range fromBlocks {
+		removed[key] += n
+	}
+	var res DeleteResult
+	for _, n := range removed {
+		res.Series++
+		res.Samples += n
+	}
+	return res, nil
+}
+
+// deleteFromBlocks rewrites each block holding samples of the selected
+// series within [start, end] without them; end <= 0 means no upper bound.
+// It returns how many samples it removed from each series, or with dryRun
+// would have, leaving the blocks alone. The caller must hold maintMu.
+func (s *tsdbStorage) deleteFromBlocks(blocks []*block, selected func(*blockSeries) bool, start, end int64, dryRun bool) (map[seriesKey]int64, error) {
+	removed := make(map[seriesKey]int64)
+	for _, b := range blocks {
+		if !b.overlaps(start, end) {
+			continue
+		}
+		// The index tells which blocks can hold anything to remove; only
+		// those are read.
+		if !slices.ContainsFunc(b.series, func(bs blockSeries) bool { return selected(&bs) && bs.hasChunksIn(start, end) }) {
+			continue
+		}
+		var keep []*seriesData
+		hit := false
+		for i := range b.series {
+			bs := &b.series[i]
+			target := selected(bs) && bs.hasChunksIn(start, end)
+			if !target && dryRun {
+				continue
+			}
+			samples, err := b.readSeries(bs, math.MinInt64, 0)
+			if err != nil {
+				return nil, err
+			}
+			hs, err := b.readHistograms(bs, math.MinInt64, 0)
+			if err != nil {
+				return nil, err
+			}
+			if target {
+				n := len(samples) + len(hs)
+				samples = slices.DeleteFunc(samples, func(s *pb.Sample) bool { return inRange(s.Timestamp, start, end) })
+				hs = slices.DeleteFunc(hs, func(h *pb.HistogramSample) bool { return inRange(h.Timestamp, start, end) })
+				if n -= len(samples) + len(hs); n > 0 {
+					removed[seriesKey{userID: bs.UserID, name: bs.Name, hash: labelsHash(bs.Labels)}] += int64(n)
+					hit = true
+				}
+			}
+			if len(samples) > 0 || len(hs) > 0 {
+				keep = append(keep, &seriesData{userID: bs.UserID, metric: &pb.Metric{Name: bs.Name, Labels: bs.Labels}, samples: samples, histograms: hs})
+			}
+		}
+		if !hit || dryRun {
+			continue
+		}
+		var replacement []*block
+		if len(keep) > 0 {
+			nb, err := writeBlock(filepath.Join(s.dir, blocksDir), blockMeta{
+				MinTime:   b.meta.MinTime,
+				MaxTime:   b.meta.MaxTime,
+				Level:     b.meta.Level,
+				Compacted: []string{filepath.Base(b.dir)},
+				Written:   b.meta.Written,
+			}, keep)
+			if err != nil {
+				return nil, err
+			}
+			replacement = append(replacement, nb)
+		}
+		if err := s.replaceBlocks([]*block{b}, replacement); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// replaceBlocks swaps old blocks for new ones and deletes the old ones
+// from disk once the queries still reading them are done.
+func (s *tsdbStorage) replaceBlocks(old, replacement []*block) error {
+	s.mu.Lock()
+	s.blocks = slices.DeleteFunc(s.blocks, func(b *block) bool { return slices.Contains(old, b) })
+	s.blocks = append(s.blocks, replacement...)
+	sortBlocks(s.blocks)
+	s.mu.Unlock()
+
+	var errs []error
+	for _, b := range old {
+		b.readers.Wait()
+		errs = append(errs, b.remove())
+	}
+	return errors.Join(errs...)
+}
+
+// maintain runs one round of background work: cutting old head data into
+// a block, compacting blocks and deleting blocks past retention.
+func (s *tsdbStorage) maintain(now int64, logger *slog.Logger) error {
+	s.maintMu.Lock()
+	defer s.maintMu.Unlock()
+
+	if err := s.cutHead(now, logger); err != nil {
+		return fmt.Errorf("cut head: %w", err)
+	}
+	if err := s.compact(now, logger); err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+	return s.applyRetention(now, logger)
+}
+
+// headCutoff is the time before which head data is moved into blocks. Half
+// a block range of slack lets late samples still land in the head.
+func headCutoff(now int64) int64 {
+	return alignDown(now-compactionRanges[0]/2, compactionRanges[0])
+}
+
+// headCutMarker is written before a head cut and removed once the WAL has
+// been checkpointed, so a crash in between can be told apart on startup.
+type headCutMarker struct {
+	// ID tags the cut's blocks. Cutoffs repeat when late samples are cut
+	// after their window, so they can't serve as one.
+	ID     int64 `json:"id"`
+	Cutoff int64 `json:"cutoff"`
+	Blocks int   `json:"blocks"`
+}
+
+// recoverHeadCut finishes or rolls back a head cut interrupted by a crash.
+// If every block of the cut made it to disk, the WAL still holds their
+// samples, so replay skips everything before the cutoff. Otherwise the
+// partial blocks are deleted and the WAL is replayed in full.
+func (s *tsdbStorage) recoverHeadCut(cut headCutMarker) error {
+	var written []*block
+	for _, b := range s.blocks {
+		if b.meta.HeadCut == cut.ID {
+			written = append(written, b)
+		}
+	}
+	if len(written) == cut.Blocks {
+		s.replayFrom = cut.Cutoff
+		return nil
+	}
+	return s.replaceBlocks(written, nil)
+}
+
+// cutHead writes head samples older than the cutoff into one block per
+// block-range window, drops them from the head and rewrites the WAL to
+// hold only what's left.
+func (s *tsdbStorage) cutHead(now int64, logger *slog.Logger) error {
+	cutoff := headCutoff(now)
+	blockRange := compactionRanges[0]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	windows := make(map[int64][]*seriesData)
+	for _, hs := range s.head {
+		parts := make(map[int64]*seriesData)
+		part := func(t int64) *seriesData {
+			window := alignDown(t, blockRange)
+			sd, ok := parts[window]
+			if !ok {
+				sd = &seriesData{userID: hs.userID, metric: hs.metric}
+				parts[window] = sd
+				windows[window] = append(windows[window], sd)
+			}
+			return sd
+		}
+		for _, sample := range hs.samples {
+			if sample.Timestamp >= cutoff {
+				break
+			}
+			sd := part(sample.Timestamp)
+			sd.samples = append(sd.samples, sample)
+		}
+		for _, h := range hs.histograms {
+			if h.Timestamp >= cutoff {
+				break
+			}
+			sd := part(h.Timestamp)
+			sd.histograms = append(sd.histograms, h)
+		}
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+
+	cut := headCutMarker{ID: time.Now().UnixNano(), Cutoff: cutoff, Blocks: len(windows)}
+	markerPath := filepath.Join(s.dir, tsdbHeadCutFile)
+	if err := writeJSON(markerPath, cut); err != nil {
+		return err
+	}
+	for window, series := range windows {
+		sortSeriesData(series)
+		b, err := writeBlock(filepath.Join(s.dir, blocksDir), blockMeta{
+			MinTime: window,
+			MaxTime: window + blockRange,
+			Level:   1,
+			HeadCut: cut.ID,
+		}, series)
+		if err != nil {
+			return err
+		}
+		s.blocks = append(s.blocks, b)
+		logger.Info("TSDB head cut", "block", filepath.Base(b.dir), "series", b.meta.NumSeries, "samples", b.meta.NumSamples)
+	}
+	sortBlocks(s.blocks)
+
+	for key, hs := range s.head {
+		n := sort.Search(len(hs.samples), func(i int) bool { return hs.samples[i].Timestamp >= cutoff })
+		m := sort.Search(len(hs.histograms), func(i int) bool { return hs.histograms[i].Timestamp >= cutoff })
+		if n == len(hs.samples) && m == len(hs.histograms) {
+			delete(s.head, key)
+			delete(s.refs, hs.ref)
+			continue
+		}
+		hs.samples = slices.Clone(hs.samples[n:])
+		hs.histograms = slices.Clone(hs.histograms[m:])
+	}
+	if err := s.checkpointWAL(); err != nil {
+		return err
+	}
+	return os.Remove(markerPath)
+}
+
+// checkpointWAL replaces the WAL with one describing just the current
+// head. The caller must hold s.mu.
+func (s *tsdbStorage) checkpointWAL() error {
+	path := filepath.Join(s.dir, tsdbWALFile)
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	w, err := openWAL(tmp)
+	if err != nil {
+		return err
+	}
+	for _, hs := range s.head {
+		if err := w.logSeries(hs); err != nil {
+			w.close()
+			return err
+		}
+		if err := w.logSamples(hs.ref, hs.samples); err != nil {
+			w.close()
+			return err
+		}
+		if len(hs.histograms) > 0 {
+			if err := w.logHistograms(hs.ref, hs.histograms); err != nil {
+				w.close()
+				return err
+			}
+		}
+	}
+	if err := w.close(); err != nil {
+		return err
+	}
+	if err := s.wal.close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	s.wal, err = openWAL(path)
+	return err
+}
+
+func sortSeriesData(series []*seriesData) {
+	slices.SortFunc(series, func(a, b *seriesData) int {
+		if a.userID != b.userID {
+			return int(a.userID - b.userID)
+		}
+		if c := strings.Compare(a.metric.Name, b.metric.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(labelsHash(a.metric.Labels), labelsHash(b.metric.Labels))
+	})
+}
+
+// compact merges blocks that fit together in an aligned window of the next
+// compaction range, level by level. Only windows entirely behind the head
+// are compacted, so a window is never merged while still filling up.
+// Series retention policies shorter than the block-level retention are
+// applied here by leaving expired samples out of the merged block.
+func (s *tsdbStorage) compact(now int64, logger *slog.Logger) error {
+	cutoff := headCutoff(now)
+	for level := 1; level < len(compactionRanges); level++ {
+		r := compactionRanges[level]
+
+		s.mu.RLock()
+		groups := make(map[int64][]*block)
+		for _, b := range s.blocks {
+			if b.meta.MaxTime-b.meta.MinTime > r {
+				continue
+			}
+			window := alignDown(b.meta.MinTime, r)
+			if b.meta.MaxTime > window+r || window+r > cutoff {
+				continue
+			}
+			groups[window] = append(groups[window], b)
+		}
+		s.mu.RUnlock()
+
+		for window, group := range groups {
+			if len(group) < 2 {
+				continue
+			}
+			merged, err := s.mergeBlocks(group, blockMeta{MinTime: window, MaxTime: window + r, Level: level + 1}, now)
+			if err != nil {
+				return err
+			}
+			var replacement []*block
+			if merged != nil {
+				replacement = append(replacement, merged)
+				logger.Info("TSDB blocks compacted", "block", filepath.Base(merged.dir), "sources", len(group), "samples", merged.meta.NumSamples)
+			}
+			if err := s.replaceBlocks(group, replacement); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeBlocks writes one block holding the samples of all the given
+// blocks, minus those expired under retention policies. It returns nil if
+// nothing is left to write.
+func (s *tsdbStorage) mergeBlocks(blocks []*block, meta blockMeta, now int64) (*block, error) {
+	resolvers, err := s.retentionResolvers()
+	if err != nil {
+		return nil, err
+	}
+	// Read the blocks oldest written first, so that where they hold samples
+	// at the same timestamp the latest written is kept.
+	blocks = slices.Clone(blocks)
+	slices.SortStableFunc(blocks, func(a, b *block) int { return cmp.Compare(a.meta.Written, b.meta.Written) })
+	merged := make(map[seriesKey]*seriesData)
+	for _, b := range blocks {
+		meta.Compacted = append(meta.Compacted, filepath.Base(b.dir))
+		meta.Written = max(meta.Written, b.meta.Written)
+		for i := range b.series {
+			bs := &b.series[i]
+			samples, err := b.readSeries(bs, math.MinInt64, 0)
+			if err != nil {
+				return nil, err
+			}
+			hs, err := b.readHistograms(bs, math.MinInt64, 0)
+			if err != nil {
+				return nil, err
+			}
+			key := seriesKey{userID: bs.UserID, name: bs.Name, hash: labelsHash(bs.Labels)}
+			sd, ok := merged[key]
+			if !ok {
+				sd = &seriesData{userID: bs.UserID, metric: &pb.Metric{Name: bs.Name, Labels: bs.Labels}}
+				merged[key] = sd
+			}
+			sd.samples = append(sd.samples, samples...)
+			sd.histograms = append(sd.histograms, hs...)
+		}
+	}
+
+	var series []*seriesData
+	for _, sd := range merged {
+		sort.SliceStable(sd.samples, func(i, j int) bool { return sd.samples[i].Timestamp < sd.samples[j].Timestamp })
+		sort.SliceStable(sd.histograms, func(i, j int) bool { return sd.histograms[i].Timestamp < sd.histograms[j].Timestamp })
+		sd.samples, sd.histograms = keepLast(sd.samples), keepLast(sd.histograms)
+		days := s.retentionDays
+		if resolver, ok := resolvers[sd.userID]; ok {
+			days = resolver.days(sd.metric.Name, sd.metric.Labels)
+		}
+		expired := now - int64(days)*86400
+		n := sort.Search(len(sd.samples), func(i int) bool { return sd.samples[i].Timestamp >= expired })
+		m := sort.Search(len(sd.histograms), func(i int) bool { return sd.histograms[i].Timestamp >= expired })
+		sd.samples, sd.histograms = sd.samples[n:], sd.histograms[m:]
+		if len(sd.samples) > 0 || len(sd.histograms) > 0 {
+			series = append(series, sd)
+		}
+	}
+	if len(series) == 0 {
+		return nil, nil
+	}
+	sortSeriesData(series)
+	return writeBlock(filepath.Join(s.dir, blocksDir), meta, series)
+}
+
+// retentionResolvers builds a resolver for every user with policies.
+func (s *tsdbStorage) retentionResolvers() (map[int64]*retentionResolver, error) {
+	policies, err := s.meta.GetRetentionPolicies(context.Background(), 0)
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[int64][]*pb.RetentionPolicy)
+	for _, p := range policies {
+		byUser[p.UserId] = append(byUser[p.UserId], p)
+	}
+	resolvers := make(map[int64]*retentionResolver, len(byUser))
+	for userID, list := range byUser {
+		if resolvers[userID], err = newRetentionResolver(list, s.retentionDays); err != nil {
+			return nil, err
+		}
+	}
+	return resolvers, nil
+}
+
+// applyRetention deletes blocks that end before the longest retention
+// anyone is entitled to, then trims the series kept for less than that
+// from the blocks that remain, one group of series sharing a retention at
+// a time. Compaction drops expired samples too, but blocks at the top
+// level or alone in their window are never compacted again.
+func (s *tsdbStorage) applyRetention(now int64, logger *slog.Logger) error {
+	policies, err := s.meta.GetRetentionPolicies(context.Background(), 0)
+	if err != nil {
+		return err
+	}
+	longest := s.retentionDays
+	for _, p := range policies {
+		longest = max(longest, int(p.RetentionDays))
+	}
+	if err := s.dropExpiredBlocks(now-int64(longest)*86400, longest, logger); err != nil {
+		return err
+	}
+
+	resolvers, err := s.retentionResolvers()
+	if err != nil {
+		return err
+	}
+	shorter := []int{s.retentionDays}
+	for _, p := range policies {
+		shorter = append(shorter, int(p.RetentionDays))
+	}
+	slices.Sort(shorter)
+	for _, days := range slices.Compact(shorter) {
+		if days >= longest {
+			break
+		}
+		s.mu.RLock()
+		blocks := slices.Clone(s.blocks)
+		s.mu.RUnlock()
+		removed, err := s.deleteFromBlocks(blocks, func(bs *blockSeries) bool {
+			if resolver, ok := resolvers[bs.UserID]; ok {
+				return resolver.days(bs.Name, bs.Labels) == days
+			}
+			return s.retentionDays == days
+		}, math.MinInt64, now-int64(days)*86400-1, false)
+		if err != nil {
+			return err
+		}
+		var samples int64
+		for _, n := range removed {
+			samples += n
+		}
+		if samples > 0 {
+			logger.Info("TSDB retention cleanup", "series", len(removed), "samples_deleted", samples, "cutoff_days", days)
+		}
+	}
+	return nil
+}
+
+// dropExpiredBlocks deletes the blocks ending at or before cutoff.
+func (s *tsdbStorage) dropExpiredBlocks(cutoff int64, days int, logger *slog.Logger) error {
+
+	s.mu.RLock()
+	var expired []*block
+	for _, b := range s.blocks {
+		if b.meta.MaxTime <= cutoff {
+			expired = append(expired, b)
+		}
+	}
+	s.mu.RUnlock()
+	if len(expired) == 0 {
+		return nil
+	}
+	if err := s.replaceBlocks(expired, nil); err != nil {
+		return err
+	}
+	logger.Info("TSDB retention cleanup", "blocks_deleted", len(expired), "cutoff_days", days)
+	return nil
+}
+
+// tsdbMetadata is the on-disk form of the metadata kept in s.meta.
+type tsdbMetadata struct {
+	Users      []tsdbUser            `json:"users"`
+	Rules      []*pb.AlertRule       `json:"rules"`
+	Policies   []*pb.RetentionPolicy `json:"policies"`
+	NextRule   int64                 `json:"next_rule"`
+	NextPolicy int64                 `json:"next_policy"`
+	Metadata   []tsdbMetricMetadata  `json:"metadata"`
+}
+
+type tsdbMetricMetadata struct {
+	UserID   int64              `json:"user_id"`
+	Metadata *pb.MetricMetadata `json:"metadata"`
+}
+
+type tsdbUser struct {
+	ID     int64  `json:"id"`
+	Email  string `json:"email"`
+	APIKey string `json:"api_key"`
+}
+
+func (s *tsdbStorage) loadMetadata() error {
+	var md tsdbMetadata
+	err := readJSON(filepath.Join(s.dir, tsdbMetadataFile), &md)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	m := s.meta
+	m.users = m.users[:0]
+	for _, u := range md.Users {
+		m.users = append(m.users, memUser{id: u.ID, email: u.Email, apiKey: u.APIKey})
+	}
+	m.rules, m.policies = md.Rules, md.Policies
+	m.nextRule, m.nextPolicy = md.NextRule, md.NextPolicy
+	clear(m.metadata)
+	for _, e := range md.Metadata {
+		m.metadata[metadataKey{e.UserID, e.Metadata.MetricName}] = e.Metadata
+	}
+	return nil
+}
+
+func (s *tsdbStorage) saveMetadata() error {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+
+	m := s.meta
+	m.mu.RLock()
+	md := tsdbMetadata{Rules: m.rules, Policies: m.policies, NextRule: m.nextRule, NextPolicy: m.nextPolicy}
+	for _, u := range m.users {
+		md.Users = append(md.Users, tsdbUser{ID: u.id, Email: u.email, APIKey: u.apiKey})
+	}
+	// Metadata is updated in place, so copy it before releasing the lock.
+	for key, e := range m.metadata {
+		md.Metadata = append(md.Metadata, tsdbMetricMetadata{
+			UserID:   key.userID,
+			Metadata: &pb.MetricMetadata{MetricName: e.MetricName, Type: e.Type, Unit: e.Unit, Help: e.Help},
+		})
+	}
+	m.mu.RUnlock()
+	return writeJSON(filepath.Join(s.dir, tsdbMetadataFile), md)
+}
+
+func (s *tsdbStorage) CreateUser(ctx context.Context, email, apiKey string) (int64, error) {
+	id, err := s.meta.CreateUser(ctx, email, apiKey)
+	if err != nil {
+		return 0, err
+	}
+	return id, s.saveMetadata()
+}
+
+func (s *tsdbStorage) LookupAPIKey(ctx context.Context, apiKey string) (int64, bool, error) {
+	return s.meta.LookupAPIKey(ctx, apiKey)
+}
+
+// SetMetadata only rewrites the metadata file when something changed, since
+// agents resend the same metadata with every batch.
+func (s *tsdbStorage) SetMetadata(ctx context.Context, userID int64, list []*pb.MetricMetadata) error {
+	if !s.meta.mergeMetadata(userID, list) {
+		return nil
+	}
+	return s.saveMetadata()
+}
+
+func (s *tsdbStorage) GetMetadata(ctx context.Context, userID int64, name string) ([]*pb.MetricMetadata, error) {
+	return s.meta.GetMetadata(ctx, userID, name)
+}
+
+func (s *tsdbStorage) CreateAlertRule(ctx context.Context, rule *pb.AlertRule) (int64, error) {
+	id, err := s.meta.CreateAlertRule(ctx, rule)
+	if err != nil {
+		return 0, err
+	}
+	return id, s.saveMetadata()
+}
+
+func (s *tsdbStorage) GetAlertRules(ctx context.Context, userID int64) ([]*pb.AlertRule, error) {
+	return s.meta.GetAlertRules(ctx, userID)
+}
+
+func (s *tsdbStorage) DeleteAlertRule(ctx context.Context, userID, ruleID int64) (bool, error) {
+	ok, err := s.meta.DeleteAlertRule(ctx, userID, ruleID)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return ok, s.saveMetadata()
+}
+
+func (s *tsdbStorage) CreateRetentionPolicy(ctx context.Context, policy *pb.RetentionPolicy) (int64, error) {
+	id, err := s.meta.CreateRetentionPolicy(ctx, policy)
+	if err != nil {
+		return 0, err
+	}
+	return id, s.saveMetadata()
+}
+
+func (s *tsdbStorage) GetRetentionPolicies(ctx context.Context, userID int64) ([]*pb.RetentionPolicy, error) {
+	return s.meta.GetRetentionPolicies(ctx, userID)
+}
+
+func (s *tsdbStorage) DeleteRetentionPolicy(ctx context.Context, userID, policyID int64) (bool, error) {
+	ok, err := s.meta.DeleteRetentionPolicy(ctx, userID, policyID)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return ok, s.saveMetadata()
+}
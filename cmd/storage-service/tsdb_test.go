@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	pb "pmts/proto"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func openTestTSDB(t *testing.T, dir string) *tsdbStorage {
+	t.Helper()
+	s, err := openTSDB(dir, 30, keepFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// querySamples returns every float sample of the named series of user 1.
+func querySamples(t *testing.T, s Storage, name string) []*pb.Sample {
+	t.Helper()
+	var out []*pb.Sample
+	err := s.QuerySeries(context.Background(), &SeriesQuery{UserID: 1, Name: name}, func(_ int64, chunk *pb.TimeSeries) error {
+		out = append(out, chunk.Samples...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func testSeries(name string, labels map[string]string, samples ...*pb.Sample) *pb.TimeSeries {
+	return &pb.TimeSeries{Metric: &pb.Metric{Name: name, Labels: labels}, Samples: samples}
+}
+
+func TestTSDBPolicyRetentionTrimsBlocks(t *testing.T) {
+	ctx := context.Background()
+	s := openTestTSDB(t, t.TempDir())
+	if _, err := s.CreateRetentionPolicy(ctx, &pb.RetentionPolicy{UserId: 1, MetricName: "debug", RetentionDays: 2}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	old := now - 3*86400
+	_, err := s.AppendSamples(ctx, 1, []*pb.TimeSeries{
+		testSeries("debug", nil, &pb.Sample{Timestamp: old, Value: 1}, &pb.Sample{Timestamp: now - 3600, Value: 2}),
+		testSeries("keep", nil, &pb.Sample{Timestamp: old, Value: 1}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The old samples end up in a block alone in its window, which
+	// compaction never touches again.
+	if err := s.maintain(now, discardLogger); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := querySamples(t, s, "debug"); len(got) != 1 || got[0].Timestamp != now-3600 {
+		t.Errorf("debug samples = %v, want only the one at now-1h", got)
+	}
+	if got := querySamples(t, s, "keep"); len(got) != 1 {
+		t.Errorf("keep samples = %v, want 1", got)
+	}
+}
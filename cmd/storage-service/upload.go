@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	pb "pmts/proto"
+)
+
+// maxUploadTxSamples caps the transaction size a StreamUpload client may
+// ask for, since a whole transaction is held in memory before it commits.
+const maxUploadTxSamples = 1_000_000
+
+// StreamUpload takes an unbounded stream of chunks for bulk loads such as
+// backfills. Chunks are buffered until they hold the transaction size in
+// samples and then committed together. When a transaction fails its chunks
+// are retried one at a time, so a bad chunk only fails itself; those are
+// listed in the summary sent when the client closes the stream.
+func (s *Server) StreamUpload(stream pb.MonitoringService_StreamUploadServer) error {
+	ctx := stream.Context()
+	resp := &pb.StreamUploadResponse{}
+
+	type chunk struct {
+		index   int32
+		list    []*pb.TimeSeries
+		samples int
+	}
+	var (
+		userID   int64
+		txSize   = s.uploadTxSamples
+		pending  []chunk
+		buffered int
+	)
+	commit := func() {
+		if len(pending) == 0 {
+			return
+		}
+		var list []*pb.TimeSeries
+		for _, c := range pending {
+			list = append(list, c.list...)
+		}
+		resp.Transactions++
+		res, err := s.appendLimited(ctx, userID, list, false)
+		if err == nil {
+			resp.StoredCount += int64(res.Stored)
+			resp.DuplicateCount += int64(res.Duplicates)
+			resp.LimitedCount += int64(res.Limited)
+		} else {
+			for _, c := range pending {
+				resp.Transactions++
+				res, err := s.appendLimited(ctx, userID, c.list, false)
+				if err != nil {
+					resp.Failures = append(resp.Failures, &pb.UploadFailure{Chunk: c.index, Samples: int32(c.samples), Error: err.Error()})
+					continue
+				}
+				resp.StoredCount += int64(res.Stored)
+				resp.DuplicateCount += int64(res.Duplicates)
+				resp.LimitedCount += int64(res.Limited)
+			}
+		}
+		pending, buffered = nil, 0
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			commit()
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+
+		c := chunk{index: resp.Chunks, list: req.List, samples: countSamples(req.List)}
+		resp.Chunks++
+		if c.index == 0 {
+			userID = req.UserId
+			if userID == 0 {
+				userID = 1
+			}
+			if req.TransactionSize > 0 {
+				txSize = min(int(req.TransactionSize), maxUploadTxSamples)
+			}
+		} else if req.UserId != 0 && req.UserId != userID {
+			resp.Failures = append(resp.Failures, &pb.UploadFailure{
+				Chunk:   c.index,
+				Samples: int32(c.samples),
+				Error:   fmt.Sprintf("user_id %d does not match the stream's user_id %d", req.UserId, userID),
+			})
+			continue
+		}
+
+		pending = append(pending, c)
+		buffered += c.samples
+		if buffered >= txSize {
+			commit()
+		}
+	}
+}
+
+func countSamples(list []*pb.TimeSeries) int {
+	n := 0
+	for _, ts := range list {
+		n += len(ts.Samples) + len(ts.Histograms)
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// rawSampleBytes is what an uncompressed sample takes: a timestamp and a
+// float64. Backends that keep samples that way report it as their size.
+const rawSampleBytes = 16
+
+// rawHistogramBytes is what an uncompressed histogram sample takes: its
+// timestamp, sum and count, and eight bytes per bound and bucket count.
+func rawHistogramBytes(h *pb.HistogramSample) int64 {
+	return 24 + 8*int64(len(h.Bounds)+len(h.Counts))
+}
+
+// GetUsageStats shows which metrics and labels a tenant's storage goes to,
+// so a cardinality explosion can be traced back to its source.
+func (s *Server) GetUsageStats(ctx context.Context, req *pb.UsageStatsRequest) (*pb.UsageStatsResponse, error) {
+	uid := req.UserId
+	if uid == 0 {
+		uid = 1
+	}
+	metrics, labels, err := s.store.UsageStats(ctx, uid, time.Now().Add(-24*time.Hour).Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.UsageStatsResponse{}
+	for _, m := range metrics {
+		resp.Series += m.Series
+		resp.SamplesLastDay += m.SamplesLastDay
+		resp.BytesLastDay += m.BytesLastDay
+	}
+	slices.SortFunc(metrics, func(a, b *pb.MetricUsage) int {
+		if a.Series != b.Series {
+			return int(b.Series - a.Series)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	slices.SortFunc(labels, func(a, b *pb.LabelUsage) int {
+		if a.DistinctValues != b.DistinctValues {
+			return int(b.DistinctValues - a.DistinctValues)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if limit := int(req.Limit); limit > 0 {
+		metrics = metrics[:min(limit, len(metrics))]
+		labels = labels[:min(limit, len(labels))]
+	}
+	resp.Metrics, resp.Labels = metrics, labels
+	return resp, nil
+}
+
+// usageCollector adds up the usage of series one at a time, for backends
+// that can walk their series directly.
+type usageCollector struct {
+	metrics map[string]*pb.MetricUsage
+	labels  map[string]*labelStats
+}
+
+type labelStats struct {
+	values map[string]bool
+	series int64
+}
+
+func newUsageCollector() *usageCollector {
+	return &usageCollector{metrics: make(map[string]*pb.MetricUsage), labels: make(map[string]*labelStats)}
+}
+
+// add records one series whose samples span [first, last], of which
+// samples, taking bytes, are recent enough to count.
+func (u *usageCollector) add(metric *pb.Metric, first, last, samples, bytes int64) {
+	m, ok := u.metrics[metric.Name]
+	if !ok {
+		m = &pb.MetricUsage{Name: metric.Name, FirstSeen: first, LastSeen: last}
+		u.metrics[metric.Name] = m
+	}
+	m.Series++
+	m.SamplesLastDay += samples
+	m.BytesLastDay += bytes
+	m.FirstSeen = min(m.FirstSeen, first)
+	m.LastSeen = max(m.LastSeen, last)
+
+	for k, v := range metric.Labels {
+		l, ok := u.labels[k]
+		if !ok {
+			l = &labelStats{values: make(map[string]bool)}
+			u.labels[k] = l
+		}
+		l.values[v] = true
+		l.series++
+	}
+}
+
+func (u *usageCollector) result() ([]*pb.MetricUsage, []*pb.LabelUsage) {
+	metrics := make([]*pb.MetricUsage, 0, len(u.metrics))
+	for _, m := range u.metrics {
+		metrics = append(metrics, m)
+	}
+	labels := make([]*pb.LabelUsage, 0, len(u.labels))
+	for name, l := range u.labels {
+		labels = append(labels, &pb.LabelUsage{Name: name, DistinctValues: int64(len(l.values)), Series: l.series})
+	}
+	return metrics, labels
+}
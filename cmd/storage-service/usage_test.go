@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "pmts/proto"
+)
+
+func TestUsageStatsCountsHistograms(t *testing.T) {
+	now := time.Now().Unix()
+	// Old enough for a head cut to move it into a block.
+	base := alignDown(now, 3600) - 6*3600
+	h := &pb.HistogramSample{Timestamp: base + 20, Bounds: []float64{1}, Counts: []uint64{1}, Sum: 1, Count: 1}
+	list := []*pb.TimeSeries{
+		{Metric: &pb.Metric{Name: "latency", Labels: map[string]string{"route": "/"}}, Histograms: []*pb.HistogramSample{h}},
+		testSeries("up", map[string]string{"route": "/"}, &pb.Sample{Timestamp: base + 10, Value: 1}),
+	}
+	backends := []struct {
+		name string
+		open func(t *testing.T) Storage
+		// cut moves the head into blocks after writing.
+		cut bool
+	}{
+		{"memory", func(t *testing.T) Storage { return newMemStorage(30, keepFirst) }, false},
+		{"tsdb head", func(t *testing.T) Storage { return openTestTSDB(t, t.TempDir()) }, false},
+		{"tsdb blocks", func(t *testing.T) Storage { return openTestTSDB(t, t.TempDir()) }, true},
+	}
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := b.open(t)
+			mustAppend(t, s, list...)
+			if b.cut {
+				tsdb := s.(*tsdbStorage)
+				if err := tsdb.cutHead(now, discardLogger); err != nil {
+					t.Fatal(err)
+				}
+				if len(tsdb.blocks) == 0 {
+					t.Fatal("head cut wrote no block")
+				}
+			}
+			metrics, labels, err := s.UsageStats(context.Background(), 1, base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			byName := make(map[string]*pb.MetricUsage)
+			for _, m := range metrics {
+				byName[m.Name] = m
+			}
+			m := byName["latency"]
+			if m == nil {
+				t.Fatalf("no usage for the histogram metric in %v", metrics)
+			}
+			if m.Series != 1 || m.SamplesLastDay != 1 || m.BytesLastDay == 0 {
+				t.Errorf("latency: %d series, %d samples, %d bytes, want 1, 1 and some", m.Series, m.SamplesLastDay, m.BytesLastDay)
+			}
+			if m.FirstSeen != h.Timestamp || m.LastSeen != h.Timestamp {
+				t.Errorf("latency seen %d to %d, want %d", m.FirstSeen, m.LastSeen, h.Timestamp)
+			}
+			if len(labels) != 1 || labels[0].Series != 2 {
+				t.Errorf("labels = %v, want route on both series", labels)
+			}
+		})
+	}
+}
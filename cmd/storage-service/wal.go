@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	pb "pmts/proto"
+)
+
+// The write-ahead log makes head writes durable until they are compacted
+// into a block. It is a single file of records, each framed as
+//
+//	type (1 byte) | payload length (uvarint) | payload | CRC32 of payload
+//
+// A torn record at the end, left by a crash mid-write, is truncated away
+// on replay.
+const (
+	walRecordSeries      byte = 1 // ref, user, name, labels
+	walRecordSamples     byte = 2 // ref, count, (timestamp, value)...
+	walRecordDelete      byte = 3 // user, metric name
+	walRecordHistograms  byte = 4 // ref, then a histogram chunk
+	walRecordDeleteRange byte = 5 // ref, start, end
+)
+
+type wal struct {
+	path string
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// openWAL opens the log at path for appending, creating it if needed.
+func openWAL(path string) (*wal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &wal{path: path, f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (l *wal) writeRecord(typ byte, payload []byte) error {
+	var hdr [1 + binary.MaxVarintLen64]byte
+	hdr[0] = typ
+	n := binary.PutUvarint(hdr[1:], uint64(len(payload)))
+	if _, err := l.w.Write(hdr[:1+n]); err != nil {
+		return err
+	}
+	if _, err := l.w.Write(payload); err != nil {
+		return err
+	}
+	return binary.Write(l.w, binary.BigEndian, crc32.ChecksumIEEE(payload))
+}
+
+// sync flushes buffered records and fsyncs them to disk.
+func (l *wal) sync() error {
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+func (l *wal) close() error {
+	if err := l.sync(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+func (l *wal) logSeries(s *headSeries) error {
+	buf := binary.AppendUvarint(nil, s.ref)
+	buf = binary.AppendVarint(buf, s.userID)
+	buf = appendString(buf, s.metric.Name)
+	buf = appendLabels(buf, s.metric.Labels)
+	return l.writeRecord(walRecordSeries, buf)
+}
+
+func (l *wal) logSamples(ref uint64, samples []*pb.Sample) error {
+	buf := binary.AppendUvarint(nil, ref)
+	buf = binary.AppendUvarint(buf, uint64(len(samples)))
+	for _, s := range samples {
+		buf = binary.AppendVarint(buf, s.Timestamp)
+		buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(s.Value))
+	}
+	return l.writeRecord(walRecordSamples, buf)
+}
+
+func (l *wal) logHistograms(ref uint64, hs []*pb.HistogramSample) error {
+	buf := binary.AppendUvarint(nil, ref)
+	buf = append(buf, encodeHistogramChunk(hs)...)
+	return l.writeRecord(walRecordHistograms, buf)
+}
+
+func (l *wal) logDelete(userID int64, name string) error {
+	buf := binary.AppendVarint(nil, userID)
+	buf = appendString(buf, name)
+	return l.writeRecord(walRecordDelete, buf)
+}
+
+// logDeleteRange records that the samples of series ref within [start,
+// end] were deleted; end <= 0 means no upper bound.
+func (l *wal) logDeleteRange(ref uint64, start, end int64) error {
+	buf := binary.AppendUvarint(nil, ref)
+	buf = binary.AppendVarint(buf, start)
+	buf = binary.AppendVarint(buf, end)
+	return l.writeRecord(walRecordDeleteRange, buf)
+}
+
+// walReplayer receives WAL records in the order they were written.
+type walReplayer interface {
+	replaySeries(ref uint64, userID int64, metric *pb.Metric)
+	replaySamples(ref uint64, samples []*pb.Sample)
+	replayHistograms(ref uint64, hs []*pb.HistogramSample)
+	replayDelete(userID int64, name string)
+	replayDeleteRange(ref uint64, start, end int64)
+}
+
+// replayWAL feeds every intact record in the log at path to h, then
+// truncates whatever follows the last intact record.
+func replayWAL(path string, h walReplayer) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := &countingReader{r: bufio.NewReader(f)}
+	var good int64
+	for {
+		if err := replayRecord(r, h); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			// Anything after a bad record can't be trusted either.
+			return os.Truncate(path, good)
+		}
+		good = r.n
+	}
+}
+
+func replayRecord(r *countingReader, h walReplayer) error {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	var sum uint32
+	if err := binary.Read(r, binary.BigEndian, &sum); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return errWALCorrupt
+	}
+
+	d := decoder{buf: payload}
+	switch typ {
+	case walRecordSeries:
+		ref := d.uvarint()
+		userID := d.varint()
+		metric := &pb.Metric{Name: d.string(), Labels: d.labels()}
+		if d.err == nil {
+			h.replaySeries(ref, userID, metric)
+		}
+	case walRecordSamples:
+		ref := d.uvarint()
+		n := d.uvarint()
+		samples := make([]*pb.Sample, 0, min(n, uint64(len(payload))))
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			ts := d.varint()
+			value := math.Float64frombits(d.uint64())
+			samples = append(samples, &pb.Sample{Timestamp: ts, Value: value})
+		}
+		if d.err == nil {
+			h.replaySamples(ref, samples)
+		}
+	case walRecordHistograms:
+		ref := d.uvarint()
+		n := d.uvarint()
+		hs := make([]*pb.HistogramSample, 0, min(n, uint64(len(payload))))
+		var prev *pb.HistogramSample
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			prev = d.histogram(prev)
+			hs = append(hs, prev)
+		}
+		if d.err == nil {
+			h.replayHistograms(ref, hs)
+		}
+	case walRecordDelete:
+		userID := d.varint()
+		name := d.string()
+		if d.err == nil {
+			h.replayDelete(userID, name)
+		}
+	case walRecordDeleteRange:
+		ref := d.uvarint()
+		start := d.varint()
+		end := d.varint()
+		if d.err == nil {
+			h.replayDeleteRange(ref, start, end)
+		}
+	default:
+		return errWALCorrupt
+	}
+	return d.err
+}
+
+var errWALCorrupt = errors.New("tsdb: corrupt WAL record")
+
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// appendLabels writes labels sorted by name so equal label sets encode
+// identically.
+func appendLabels(buf []byte, labels map[string]string) []byte {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	buf = binary.AppendUvarint(buf, uint64(len(names)))
+	for _, k := range names {
+		buf = appendString(buf, k)
+		buf = appendString(buf, labels[k])
+	}
+	return buf
+}
+
+// decoder reads the fields appended above, remembering the first error so
+// callers can check once at the end.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errWALCorrupt
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errWALCorrupt
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) uint64() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 8 {
+		d.err = errWALCorrupt
+		return 0
+	}
+	v := binary.BigEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 1 {
+		d.err = errWALCorrupt
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if uint64(len(d.buf)) < n {
+		d.err = errWALCorrupt
+		return ""
+	}
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s
+}
+
+func (d *decoder) labels() map[string]string {
+	n := d.uvarint()
+	labels := make(map[string]string, min(n, uint64(len(d.buf))))
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		k := d.string()
+		labels[k] = d.string()
+	}
+	return labels
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// reopenTSDB closes s and opens dir again, so that the head is rebuilt
+// from the WAL.
+func reopenTSDB(t *testing.T, s *tsdbStorage, dir string) *tsdbStorage {
+	t.Helper()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := openTSDB(dir, 30, keepFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func timestamps(samples []*pb.Sample) []int64 {
+	var ts []int64
+	for _, s := range samples {
+		ts = append(ts, s.Timestamp)
+	}
+	return ts
+}
+
+func TestWALReplay(t *testing.T) {
+	ctx := context.Background()
+	base := time.Now().Unix() - 3600
+	at := func(offsets ...int64) []*pb.Sample {
+		var out []*pb.Sample
+		for _, o := range offsets {
+			out = append(out, &pb.Sample{Timestamp: base + o, Value: float64(o)})
+		}
+		return out
+	}
+	rel := func(ts []int64) []int64 {
+		for i := range ts {
+			ts[i] -= base
+		}
+		return ts
+	}
+	tests := []struct {
+		name  string
+		write func(t *testing.T, s *tsdbStorage)
+		// want is each series' sample offsets from base after replay.
+		want map[string][]int64
+	}{
+		{
+			name: "samples",
+			write: func(t *testing.T, s *tsdbStorage) {
+				mustAppend(t, s, testSeries("a", nil, at(1, 2)...), testSeries("b", map[string]string{"x": "y"}, at(3)...))
+				mustAppend(t, s, testSeries("a", nil, at(4)...))
+			},
+			want: map[string][]int64{"a": {1, 2, 4}, "b": {3}},
+		},
+		{
+			name: "out of order and duplicate",
+			write: func(t *testing.T, s *tsdbStorage) {
+				mustAppend(t, s, testSeries("a", nil, at(5)...))
+				mustAppend(t, s, testSeries("a", nil, at(3, 5)...))
+			},
+			want: map[string][]int64{"a": {3, 5}},
+		},
+		{
+			name: "deleted metric",
+			write: func(t *testing.T, s *tsdbStorage) {
+				mustAppend(t, s, testSeries("a", nil, at(1, 2)...), testSeries("b", nil, at(1)...))
+				if err := s.DeleteMetric(ctx, 1, "a"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[string][]int64{"a": nil, "b": {1}},
+		},
+		{
+			name: "written again after delete",
+			write: func(t *testing.T, s *tsdbStorage) {
+				mustAppend(t, s, testSeries("a", nil, at(1)...))
+				if err := s.DeleteMetric(ctx, 1, "a"); err != nil {
+					t.Fatal(err)
+				}
+				mustAppend(t, s, testSeries("a", nil, at(2)...))
+			},
+			want: map[string][]int64{"a": {2}},
+		},
+		{
+			name: "deleted range",
+			write: func(t *testing.T, s *tsdbStorage) {
+				mustAppend(t, s, testSeries("a", nil, at(1, 2, 3, 4, 5)...))
+				q := &SeriesQuery{UserID: 1, Name: "a", Start: base + 2, End: base + 3}
+				if _, err := s.DeleteSeries(ctx, q, false); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[string][]int64{"a": {1, 4, 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := openTSDB(dir, 30, keepFirst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.write(t, s)
+			s = reopenTSDB(t, s, dir)
+			defer s.Close()
+			for name, want := range tt.want {
+				if got := rel(timestamps(querySamples(t, s, name))); !slices.Equal(got, want) {
+					t.Errorf("%s after replay = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWALReplayTornTail(t *testing.T) {
+	base := time.Now().Unix() - 3600
+	tests := []struct {
+		name string
+		tail []byte
+	}{
+		{"partial header", []byte{walRecordSamples}},
+		{"partial payload", []byte{walRecordSamples, 20, 1, 2, 3}},
+		{"bad checksum", []byte{walRecordDelete, 2, 2, 'a', 0, 0, 0, 0}},
+		{"unknown type", []byte{0x7f, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := openTSDB(dir, 30, keepFirst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mustAppend(t, s, testSeries("a", nil, &pb.Sample{Timestamp: base, Value: 1}))
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, tsdbWALFile)
+			intact, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write(tt.tail)
+			f.Close()
+
+			s, err = openTSDB(dir, 30, keepFirst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi, err := os.Stat(path); err != nil || fi.Size() != intact.Size() {
+				t.Errorf("WAL not truncated back to its last intact record")
+			}
+			// What is written after the truncation must replay too.
+			mustAppend(t, s, testSeries("a", nil, &pb.Sample{Timestamp: base + 1, Value: 2}))
+			s = reopenTSDB(t, s, dir)
+			defer s.Close()
+			if got := timestamps(querySamples(t, s, "a")); !slices.Equal(got, []int64{base, base + 1}) {
+				t.Errorf("a after replay = %v, want [%d %d]", got, base, base+1)
+			}
+		})
+	}
+}
+
+func mustAppend(t *testing.T, s Storage, list ...*pb.TimeSeries) {
+	t.Helper()
+	if _, err := s.AppendSamples(context.Background(), 1, list); err != nil {
+		t.Fatal(err)
+	}
+}
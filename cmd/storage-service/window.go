@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	pb "pmts/proto"
+)
+
+// acceptanceWindow bounds the timestamps live ingest accepts: samples more
+// than maxAge in the past or maxFuture ahead of now are dropped, and
+// counted. A zero bound is not enforced. The gateway applies the same
+// window; checking again here covers clients that bypass it. Queued batches
+// are checked against when they were published, not when they are stored.
+type acceptanceWindow struct {
+	maxAge    time.Duration
+	maxFuture time.Duration
+}
+
+func windowFromEnv() acceptanceWindow {
+	return acceptanceWindow{
+		maxAge:    envDuration("INGEST_MAX_AGE", 24*time.Hour),
+		maxFuture: envDuration("INGEST_MAX_FUTURE_SKEW", 10*time.Minute),
+	}
+}
+
+func (w acceptanceWindow) contains(ts int64, now time.Time) bool {
+	t := time.Unix(ts, 0)
+	if w.maxAge > 0 && t.Before(now.Add(-w.maxAge)) {
+		return false
+	}
+	return w.maxFuture <= 0 || !t.After(now.Add(w.maxFuture))
+}
+
+// filter returns list without the samples outside the window, and how many
+// those were. list itself is left alone.
+func (w acceptanceWindow) filter(list []*pb.TimeSeries, now time.Time) ([]*pb.TimeSeries, int) {
+	if w.maxAge <= 0 && w.maxFuture <= 0 {
+		return list, 0
+	}
+	out := make([]*pb.TimeSeries, 0, len(list))
+	rejected := 0
+	for _, ts := range list {
+		kept := &pb.TimeSeries{Metric: ts.Metric}
+		for _, s := range ts.Samples {
+			if w.contains(s.Timestamp, now) {
+				kept.Samples = append(kept.Samples, s)
+			} else {
+				rejected++
+			}
+		}
+		for _, h := range ts.Histograms {
+			if w.contains(h.Timestamp, now) {
+				kept.Histograms = append(kept.Histograms, h)
+			} else {
+				rejected++
+			}
+		}
+		if len(kept.Samples) > 0 || len(kept.Histograms) > 0 {
+			out = append(out, kept)
+		}
+	}
+	return out, rejected
+}
+
+// envDuration reads a Go duration such as "36h" from the environment,
+// falling back to def when the variable is unset or invalid. Unlike envInt
+// it takes 0, which callers use to turn a limit off.
+func envDuration(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return def
+}
@@ -79,7 +79,10 @@ export class DataCat {
         },
         body: JSON.stringify(batch),
       });
-      if (!res.ok) {
+      if (res.status === 422) {
+        // Every sample was outside the server's acceptance window; resending won't help.
+        console.error('[DataCat] flush rejected: timestamps outside the acceptance window');
+      } else if (!res.ok) {
         console.error(`[DataCat] flush failed: ${res.status} ${res.statusText}`);
         // Put metrics back so they aren't lost on transient errors
         this.buffer.unshift(...batch);
@@ -21,6 +21,225 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type MetricMetadata_Type int32
+
+const (
+	MetricMetadata_UNKNOWN   MetricMetadata_Type = 0
+	MetricMetadata_COUNTER   MetricMetadata_Type = 1
+	MetricMetadata_GAUGE     MetricMetadata_Type = 2
+	MetricMetadata_HISTOGRAM MetricMetadata_Type = 3
+	MetricMetadata_SUMMARY   MetricMetadata_Type = 4
+)
+
+// Enum value maps for MetricMetadata_Type.
+var (
+	MetricMetadata_Type_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "COUNTER",
+		2: "GAUGE",
+		3: "HISTOGRAM",
+		4: "SUMMARY",
+	}
+	MetricMetadata_Type_value = map[string]int32{
+		"UNKNOWN":   0,
+		"COUNTER":   1,
+		"GAUGE":     2,
+		"HISTOGRAM": 3,
+		"SUMMARY":   4,
+	}
+)
+
+func (x MetricMetadata_Type) Enum() *MetricMetadata_Type {
+	p := new(MetricMetadata_Type)
+	*p = x
+	return p
+}
+
+func (x MetricMetadata_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MetricMetadata_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_monitoring_proto_enumTypes[0].Descriptor()
+}
+
+func (MetricMetadata_Type) Type() protoreflect.EnumType {
+	return &file_proto_monitoring_proto_enumTypes[0]
+}
+
+func (x MetricMetadata_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MetricMetadata_Type.Descriptor instead.
+func (MetricMetadata_Type) EnumDescriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{4, 0}
+}
+
+type LabelMatcher_Type int32
+
+const (
+	LabelMatcher_EQ  LabelMatcher_Type = 0
+	LabelMatcher_NEQ LabelMatcher_Type = 1
+	LabelMatcher_RE  LabelMatcher_Type = 2
+	LabelMatcher_NRE LabelMatcher_Type = 3
+)
+
+// Enum value maps for LabelMatcher_Type.
+var (
+	LabelMatcher_Type_name = map[int32]string{
+		0: "EQ",
+		1: "NEQ",
+		2: "RE",
+		3: "NRE",
+	}
+	LabelMatcher_Type_value = map[string]int32{
+		"EQ":  0,
+		"NEQ": 1,
+		"RE":  2,
+		"NRE": 3,
+	}
+)
+
+func (x LabelMatcher_Type) Enum() *LabelMatcher_Type {
+	p := new(LabelMatcher_Type)
+	*p = x
+	return p
+}
+
+func (x LabelMatcher_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LabelMatcher_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_monitoring_proto_enumTypes[1].Descriptor()
+}
+
+func (LabelMatcher_Type) Type() protoreflect.EnumType {
+	return &file_proto_monitoring_proto_enumTypes[1]
+}
+
+func (x LabelMatcher_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LabelMatcher_Type.Descriptor instead.
+func (LabelMatcher_Type) EnumDescriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{10, 0}
+}
+
+type GetMetricsRequest_Aggregation int32
+
+const (
+	GetMetricsRequest_AVG   GetMetricsRequest_Aggregation = 0
+	GetMetricsRequest_MIN   GetMetricsRequest_Aggregation = 1
+	GetMetricsRequest_MAX   GetMetricsRequest_Aggregation = 2
+	GetMetricsRequest_SUM   GetMetricsRequest_Aggregation = 3
+	GetMetricsRequest_COUNT GetMetricsRequest_Aggregation = 4
+	GetMetricsRequest_LAST  GetMetricsRequest_Aggregation = 5
+)
+
+// Enum value maps for GetMetricsRequest_Aggregation.
+var (
+	GetMetricsRequest_Aggregation_name = map[int32]string{
+		0: "AVG",
+		1: "MIN",
+		2: "MAX",
+		3: "SUM",
+		4: "COUNT",
+		5: "LAST",
+	}
+	GetMetricsRequest_Aggregation_value = map[string]int32{
+		"AVG":   0,
+		"MIN":   1,
+		"MAX":   2,
+		"SUM":   3,
+		"COUNT": 4,
+		"LAST":  5,
+	}
+)
+
+func (x GetMetricsRequest_Aggregation) Enum() *GetMetricsRequest_Aggregation {
+	p := new(GetMetricsRequest_Aggregation)
+	*p = x
+	return p
+}
+
+func (x GetMetricsRequest_Aggregation) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GetMetricsRequest_Aggregation) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_monitoring_proto_enumTypes[2].Descriptor()
+}
+
+func (GetMetricsRequest_Aggregation) Type() protoreflect.EnumType {
+	return &file_proto_monitoring_proto_enumTypes[2]
+}
+
+func (x GetMetricsRequest_Aggregation) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GetMetricsRequest_Aggregation.Descriptor instead.
+func (GetMetricsRequest_Aggregation) EnumDescriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{11, 0}
+}
+
+// What to do with data the user already has: a sample at the same
+// timestamp of the same series, metadata for the same metric, or a rule
+// on the same metric with the same threshold.
+type ImportRequest_Conflict int32
+
+const (
+	ImportRequest_KEEP_EXISTING ImportRequest_Conflict = 0
+	ImportRequest_OVERWRITE     ImportRequest_Conflict = 1
+	// Stop the import at the first conflict. What came before it stays
+	// imported.
+	ImportRequest_REJECT ImportRequest_Conflict = 2
+)
+
+// Enum value maps for ImportRequest_Conflict.
+var (
+	ImportRequest_Conflict_name = map[int32]string{
+		0: "KEEP_EXISTING",
+		1: "OVERWRITE",
+		2: "REJECT",
+	}
+	ImportRequest_Conflict_value = map[string]int32{
+		"KEEP_EXISTING": 0,
+		"OVERWRITE":     1,
+		"REJECT":        2,
+	}
+)
+
+func (x ImportRequest_Conflict) Enum() *ImportRequest_Conflict {
+	p := new(ImportRequest_Conflict)
+	*p = x
+	return p
+}
+
+func (x ImportRequest_Conflict) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ImportRequest_Conflict) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_monitoring_proto_enumTypes[3].Descriptor()
+}
+
+func (ImportRequest_Conflict) Type() protoreflect.EnumType {
+	return &file_proto_monitoring_proto_enumTypes[3]
+}
+
+func (x ImportRequest_Conflict) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ImportRequest_Conflict.Descriptor instead.
+func (ImportRequest_Conflict) EnumDescriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{50, 0}
+}
+
 type Metric struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
@@ -125,28 +344,36 @@ func (x *Sample) GetValue() float64 {
 	return 0
 }
 
-type TimeSeries struct {
+// A histogram's state at one point in time. counts[i] observations fell in
+// the bucket whose upper bound is bounds[i] and whose lower bound is the
+// previous bound; count also includes observations above the last bound.
+// Like a counter, the counts accumulate over time and reset to zero when
+// the process restarts.
+type HistogramSample struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Metric        *Metric                `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
-	Samples       []*Sample              `protobuf:"bytes,2,rep,name=samples,proto3" json:"samples,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Bounds        []float64              `protobuf:"fixed64,2,rep,packed,name=bounds,proto3" json:"bounds,omitempty"`
+	Counts        []uint64               `protobuf:"varint,3,rep,packed,name=counts,proto3" json:"counts,omitempty"`
+	Sum           float64                `protobuf:"fixed64,4,opt,name=sum,proto3" json:"sum,omitempty"`
+	Count         uint64                 `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *TimeSeries) Reset() {
-	*x = TimeSeries{}
+func (x *HistogramSample) Reset() {
+	*x = HistogramSample{}
 	mi := &file_proto_monitoring_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TimeSeries) String() string {
+func (x *HistogramSample) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TimeSeries) ProtoMessage() {}
+func (*HistogramSample) ProtoMessage() {}
 
-func (x *TimeSeries) ProtoReflect() protoreflect.Message {
+func (x *HistogramSample) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_monitoring_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -158,47 +385,69 @@ func (x *TimeSeries) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TimeSeries.ProtoReflect.Descriptor instead.
-func (*TimeSeries) Descriptor() ([]byte, []int) {
+// Deprecated: Use HistogramSample.ProtoReflect.Descriptor instead.
+func (*HistogramSample) Descriptor() ([]byte, []int) {
 	return file_proto_monitoring_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *TimeSeries) GetMetric() *Metric {
+func (x *HistogramSample) GetTimestamp() int64 {
 	if x != nil {
-		return x.Metric
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *HistogramSample) GetBounds() []float64 {
+	if x != nil {
+		return x.Bounds
 	}
 	return nil
 }
 
-func (x *TimeSeries) GetSamples() []*Sample {
+func (x *HistogramSample) GetCounts() []uint64 {
 	if x != nil {
-		return x.Samples
+		return x.Counts
 	}
 	return nil
 }
 
-type UploadRequest struct {
+func (x *HistogramSample) GetSum() float64 {
+	if x != nil {
+		return x.Sum
+	}
+	return 0
+}
+
+func (x *HistogramSample) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type TimeSeries struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	List          []*TimeSeries          `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
-	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Metric        *Metric                `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	Samples       []*Sample              `protobuf:"bytes,2,rep,name=samples,proto3" json:"samples,omitempty"`
+	Histograms    []*HistogramSample     `protobuf:"bytes,3,rep,name=histograms,proto3" json:"histograms,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UploadRequest) Reset() {
-	*x = UploadRequest{}
+func (x *TimeSeries) Reset() {
+	*x = TimeSeries{}
 	mi := &file_proto_monitoring_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UploadRequest) String() string {
+func (x *TimeSeries) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UploadRequest) ProtoMessage() {}
+func (*TimeSeries) ProtoMessage() {}
 
-func (x *UploadRequest) ProtoReflect() protoreflect.Message {
+func (x *TimeSeries) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_monitoring_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -210,47 +459,58 @@ func (x *UploadRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UploadRequest.ProtoReflect.Descriptor instead.
-func (*UploadRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use TimeSeries.ProtoReflect.Descriptor instead.
+func (*TimeSeries) Descriptor() ([]byte, []int) {
 	return file_proto_monitoring_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *UploadRequest) GetList() []*TimeSeries {
+func (x *TimeSeries) GetMetric() *Metric {
 	if x != nil {
-		return x.List
+		return x.Metric
 	}
 	return nil
 }
 
-func (x *UploadRequest) GetUserId() int64 {
+func (x *TimeSeries) GetSamples() []*Sample {
 	if x != nil {
-		return x.UserId
+		return x.Samples
 	}
-	return 0
+	return nil
 }
 
-type UploadResponse struct {
+func (x *TimeSeries) GetHistograms() []*HistogramSample {
+	if x != nil {
+		return x.Histograms
+	}
+	return nil
+}
+
+// Describes what a metric measures. Metadata is kept per metric name; an
+// update only overwrites the fields it sets.
+type MetricMetadata struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	StoredCount   int32                  `protobuf:"varint,1,opt,name=stored_count,json=storedCount,proto3" json:"stored_count,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	MetricName    string                 `protobuf:"bytes,1,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
+	Type          MetricMetadata_Type    `protobuf:"varint,2,opt,name=type,proto3,enum=monitoring.MetricMetadata_Type" json:"type,omitempty"`
+	Unit          string                 `protobuf:"bytes,3,opt,name=unit,proto3" json:"unit,omitempty"`
+	Help          string                 `protobuf:"bytes,4,opt,name=help,proto3" json:"help,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UploadResponse) Reset() {
-	*x = UploadResponse{}
+func (x *MetricMetadata) Reset() {
+	*x = MetricMetadata{}
 	mi := &file_proto_monitoring_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UploadResponse) String() string {
+func (x *MetricMetadata) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UploadResponse) ProtoMessage() {}
+func (*MetricMetadata) ProtoMessage() {}
 
-func (x *UploadResponse) ProtoReflect() protoreflect.Message {
+func (x *MetricMetadata) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_monitoring_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -262,49 +522,62 @@ func (x *UploadResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UploadResponse.ProtoReflect.Descriptor instead.
-func (*UploadResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use MetricMetadata.ProtoReflect.Descriptor instead.
+func (*MetricMetadata) Descriptor() ([]byte, []int) {
 	return file_proto_monitoring_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *UploadResponse) GetStoredCount() int32 {
+func (x *MetricMetadata) GetMetricName() string {
 	if x != nil {
-		return x.StoredCount
+		return x.MetricName
 	}
-	return 0
+	return ""
 }
 
-func (x *UploadResponse) GetError() string {
+func (x *MetricMetadata) GetType() MetricMetadata_Type {
 	if x != nil {
-		return x.Error
+		return x.Type
+	}
+	return MetricMetadata_UNKNOWN
+}
+
+func (x *MetricMetadata) GetUnit() string {
+	if x != nil {
+		return x.Unit
 	}
 	return ""
 }
 
-type GetMetricsRequest struct {
+func (x *MetricMetadata) GetHelp() string {
+	if x != nil {
+		return x.Help
+	}
+	return ""
+}
+
+type UploadRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	MatchName     string                 `protobuf:"bytes,1,opt,name=match_name,json=matchName,proto3" json:"match_name,omitempty"`
+	List          []*TimeSeries          `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
 	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	StartTime     int64                  `protobuf:"varint,3,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
-	EndTime       int64                  `protobuf:"varint,4,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	Metadata      []*MetricMetadata      `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetMetricsRequest) Reset() {
-	*x = GetMetricsRequest{}
+func (x *UploadRequest) Reset() {
+	*x = UploadRequest{}
 	mi := &file_proto_monitoring_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetMetricsRequest) String() string {
+func (x *UploadRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetMetricsRequest) ProtoMessage() {}
+func (*UploadRequest) ProtoMessage() {}
 
-func (x *GetMetricsRequest) ProtoReflect() protoreflect.Message {
+func (x *UploadRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_monitoring_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -316,60 +589,63 @@ func (x *GetMetricsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetMetricsRequest.ProtoReflect.Descriptor instead.
-func (*GetMetricsRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use UploadRequest.ProtoReflect.Descriptor instead.
+func (*UploadRequest) Descriptor() ([]byte, []int) {
 	return file_proto_monitoring_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *GetMetricsRequest) GetMatchName() string {
+func (x *UploadRequest) GetList() []*TimeSeries {
 	if x != nil {
-		return x.MatchName
+		return x.List
 	}
-	return ""
+	return nil
 }
 
-func (x *GetMetricsRequest) GetUserId() int64 {
+func (x *UploadRequest) GetUserId() int64 {
 	if x != nil {
 		return x.UserId
 	}
 	return 0
 }
 
-func (x *GetMetricsRequest) GetStartTime() int64 {
-	if x != nil {
-		return x.StartTime
-	}
-	return 0
-}
-
-func (x *GetMetricsRequest) GetEndTime() int64 {
+func (x *UploadRequest) GetMetadata() []*MetricMetadata {
 	if x != nil {
-		return x.EndTime
+		return x.Metadata
 	}
-	return 0
+	return nil
 }
 
-type GetMetricsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	List          []*TimeSeries          `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
+type UploadResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	StoredCount int32                  `protobuf:"varint,1,opt,name=stored_count,json=storedCount,proto3" json:"stored_count,omitempty"`
+	Error       string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	// Samples whose series already had one at their timestamp, stored or
+	// earlier in the batch. The storage service's duplicate policy decides
+	// whether they were dropped, overwrote the old sample or failed the
+	// batch; only in the last case is error set.
+	DuplicateCount int32 `protobuf:"varint,3,opt,name=duplicate_count,json=duplicateCount,proto3" json:"duplicate_count,omitempty"`
+	// Samples dropped for having a timestamp outside the acceptance window.
+	RejectedCount int32 `protobuf:"varint,4,opt,name=rejected_count,json=rejectedCount,proto3" json:"rejected_count,omitempty"`
+	// Samples dropped for exceeding one of the user's limits.
+	LimitedCount  int32 `protobuf:"varint,5,opt,name=limited_count,json=limitedCount,proto3" json:"limited_count,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetMetricsResponse) Reset() {
-	*x = GetMetricsResponse{}
+func (x *UploadResponse) Reset() {
+	*x = UploadResponse{}
 	mi := &file_proto_monitoring_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetMetricsResponse) String() string {
+func (x *UploadResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetMetricsResponse) ProtoMessage() {}
+func (*UploadResponse) ProtoMessage() {}
 
-func (x *GetMetricsResponse) ProtoReflect() protoreflect.Message {
+func (x *UploadResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_monitoring_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -381,84 +657,2071 @@ func (x *GetMetricsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetMetricsResponse.ProtoReflect.Descriptor instead.
-func (*GetMetricsResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use UploadResponse.ProtoReflect.Descriptor instead.
+func (*UploadResponse) Descriptor() ([]byte, []int) {
 	return file_proto_monitoring_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *GetMetricsResponse) GetList() []*TimeSeries {
+func (x *UploadResponse) GetStoredCount() int32 {
 	if x != nil {
-		return x.List
+		return x.StoredCount
 	}
-	return nil
+	return 0
 }
 
-type ListNamesRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *UploadResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
 }
 
-func (x *ListNamesRequest) Reset() {
-	*x = ListNamesRequest{}
-	mi := &file_proto_monitoring_proto_msgTypes[7]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *UploadResponse) GetDuplicateCount() int32 {
+	if x != nil {
+		return x.DuplicateCount
+	}
+	return 0
 }
 
-func (x *ListNamesRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
+func (x *UploadResponse) GetRejectedCount() int32 {
+	if x != nil {
+		return x.RejectedCount
+	}
+	return 0
+}
 
-func (*ListNamesRequest) ProtoMessage() {}
+func (x *UploadResponse) GetLimitedCount() int32 {
+	if x != nil {
+		return x.LimitedCount
+	}
+	return 0
+}
 
-func (x *ListNamesRequest) ProtoReflect() protoreflect.Message {
+// One chunk of a StreamUpload. user_id and transaction_size are read from
+// the first chunk; later chunks may leave them unset.
+type StreamUploadRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	List   []*TimeSeries          `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
+	UserId int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Samples to commit per transaction; 0 uses the server default.
+	TransactionSize int32 `protobuf:"varint,3,opt,name=transaction_size,json=transactionSize,proto3" json:"transaction_size,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *StreamUploadRequest) Reset() {
+	*x = StreamUploadRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamUploadRequest) ProtoMessage() {}
+
+func (x *StreamUploadRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_monitoring_proto_msgTypes[7]
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamUploadRequest.ProtoReflect.Descriptor instead.
+func (*StreamUploadRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StreamUploadRequest) GetList() []*TimeSeries {
+	if x != nil {
+		return x.List
+	}
+	return nil
+}
+
+func (x *StreamUploadRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *StreamUploadRequest) GetTransactionSize() int32 {
+	if x != nil {
+		return x.TransactionSize
+	}
+	return 0
+}
+
+type StreamUploadResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	StoredCount  int64                  `protobuf:"varint,1,opt,name=stored_count,json=storedCount,proto3" json:"stored_count,omitempty"`
+	Chunks       int32                  `protobuf:"varint,2,opt,name=chunks,proto3" json:"chunks,omitempty"`
+	Transactions int32                  `protobuf:"varint,3,opt,name=transactions,proto3" json:"transactions,omitempty"`
+	Failures     []*UploadFailure       `protobuf:"bytes,4,rep,name=failures,proto3" json:"failures,omitempty"`
+	// As in UploadResponse, over the chunks that were stored.
+	DuplicateCount int64 `protobuf:"varint,5,opt,name=duplicate_count,json=duplicateCount,proto3" json:"duplicate_count,omitempty"`
+	LimitedCount   int64 `protobuf:"varint,6,opt,name=limited_count,json=limitedCount,proto3" json:"limited_count,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StreamUploadResponse) Reset() {
+	*x = StreamUploadResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamUploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamUploadResponse) ProtoMessage() {}
+
+func (x *StreamUploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamUploadResponse.ProtoReflect.Descriptor instead.
+func (*StreamUploadResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StreamUploadResponse) GetStoredCount() int64 {
+	if x != nil {
+		return x.StoredCount
+	}
+	return 0
+}
+
+func (x *StreamUploadResponse) GetChunks() int32 {
+	if x != nil {
+		return x.Chunks
+	}
+	return 0
+}
+
+func (x *StreamUploadResponse) GetTransactions() int32 {
+	if x != nil {
+		return x.Transactions
+	}
+	return 0
+}
+
+func (x *StreamUploadResponse) GetFailures() []*UploadFailure {
+	if x != nil {
+		return x.Failures
+	}
+	return nil
+}
+
+func (x *StreamUploadResponse) GetDuplicateCount() int64 {
+	if x != nil {
+		return x.DuplicateCount
+	}
+	return 0
+}
+
+func (x *StreamUploadResponse) GetLimitedCount() int64 {
+	if x != nil {
+		return x.LimitedCount
+	}
+	return 0
+}
+
+// A chunk that could not be stored; none of its samples were written.
+type UploadFailure struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Zero-based position of the chunk in the stream.
+	Chunk         int32  `protobuf:"varint,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	Samples       int32  `protobuf:"varint,2,opt,name=samples,proto3" json:"samples,omitempty"`
+	Error         string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadFailure) Reset() {
+	*x = UploadFailure{}
+	mi := &file_proto_monitoring_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadFailure) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadFailure) ProtoMessage() {}
+
+func (x *UploadFailure) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadFailure.ProtoReflect.Descriptor instead.
+func (*UploadFailure) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UploadFailure) GetChunk() int32 {
+	if x != nil {
+		return x.Chunk
+	}
+	return 0
+}
+
+func (x *UploadFailure) GetSamples() int32 {
+	if x != nil {
+		return x.Samples
+	}
+	return 0
+}
+
+func (x *UploadFailure) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type LabelMatcher struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          LabelMatcher_Type      `protobuf:"varint,1,opt,name=type,proto3,enum=monitoring.LabelMatcher_Type" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LabelMatcher) Reset() {
+	*x = LabelMatcher{}
+	mi := &file_proto_monitoring_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelMatcher) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelMatcher) ProtoMessage() {}
+
+func (x *LabelMatcher) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelMatcher.ProtoReflect.Descriptor instead.
+func (*LabelMatcher) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LabelMatcher) GetType() LabelMatcher_Type {
+	if x != nil {
+		return x.Type
+	}
+	return LabelMatcher_EQ
+}
+
+func (x *LabelMatcher) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LabelMatcher) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type GetMetricsRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	MatchName string                 `protobuf:"bytes,1,opt,name=match_name,json=matchName,proto3" json:"match_name,omitempty"`
+	UserId    int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	StartTime int64                  `protobuf:"varint,3,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime   int64                  `protobuf:"varint,4,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	Matchers  []*LabelMatcher        `protobuf:"bytes,5,rep,name=matchers,proto3" json:"matchers,omitempty"`
+	// Bucket width in seconds; samples in each bucket are combined with
+	// aggregation. 0 means raw data.
+	Step int64 `protobuf:"varint,6,opt,name=step,proto3" json:"step,omitempty"`
+	// Upper bound on points per series; widens the step when set.
+	MaxPoints     int32                         `protobuf:"varint,7,opt,name=max_points,json=maxPoints,proto3" json:"max_points,omitempty"`
+	Aggregation   GetMetricsRequest_Aggregation `protobuf:"varint,8,opt,name=aggregation,proto3,enum=monitoring.GetMetricsRequest_Aggregation" json:"aggregation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricsRequest) Reset() {
+	*x = GetMetricsRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricsRequest) ProtoMessage() {}
+
+func (x *GetMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricsRequest.ProtoReflect.Descriptor instead.
+func (*GetMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetMetricsRequest) GetMatchName() string {
+	if x != nil {
+		return x.MatchName
+	}
+	return ""
+}
+
+func (x *GetMetricsRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *GetMetricsRequest) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *GetMetricsRequest) GetEndTime() int64 {
+	if x != nil {
+		return x.EndTime
+	}
+	return 0
+}
+
+func (x *GetMetricsRequest) GetMatchers() []*LabelMatcher {
+	if x != nil {
+		return x.Matchers
+	}
+	return nil
+}
+
+func (x *GetMetricsRequest) GetStep() int64 {
+	if x != nil {
+		return x.Step
+	}
+	return 0
+}
+
+func (x *GetMetricsRequest) GetMaxPoints() int32 {
+	if x != nil {
+		return x.MaxPoints
+	}
+	return 0
+}
+
+func (x *GetMetricsRequest) GetAggregation() GetMetricsRequest_Aggregation {
+	if x != nil {
+		return x.Aggregation
+	}
+	return GetMetricsRequest_AVG
+}
+
+// Estimates quantiles of the observations histogram series recorded over
+// [start_time, end_time], or over each step-wide window of it when step is
+// set. The result has one float series per histogram series and quantile,
+// labeled with the quantile.
+type QuantileRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	MatchName string                 `protobuf:"bytes,1,opt,name=match_name,json=matchName,proto3" json:"match_name,omitempty"`
+	UserId    int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	StartTime int64                  `protobuf:"varint,3,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime   int64                  `protobuf:"varint,4,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	Matchers  []*LabelMatcher        `protobuf:"bytes,5,rep,name=matchers,proto3" json:"matchers,omitempty"`
+	Step      int64                  `protobuf:"varint,6,opt,name=step,proto3" json:"step,omitempty"`
+	// Between 0 and 1; defaults to 0.5, 0.95 and 0.99.
+	Quantiles     []float64 `protobuf:"fixed64,7,rep,packed,name=quantiles,proto3" json:"quantiles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuantileRequest) Reset() {
+	*x = QuantileRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuantileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuantileRequest) ProtoMessage() {}
+
+func (x *QuantileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuantileRequest.ProtoReflect.Descriptor instead.
+func (*QuantileRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *QuantileRequest) GetMatchName() string {
+	if x != nil {
+		return x.MatchName
+	}
+	return ""
+}
+
+func (x *QuantileRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *QuantileRequest) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *QuantileRequest) GetEndTime() int64 {
+	if x != nil {
+		return x.EndTime
+	}
+	return 0
+}
+
+func (x *QuantileRequest) GetMatchers() []*LabelMatcher {
+	if x != nil {
+		return x.Matchers
+	}
+	return nil
+}
+
+func (x *QuantileRequest) GetStep() int64 {
+	if x != nil {
+		return x.Step
+	}
+	return 0
+}
+
+func (x *QuantileRequest) GetQuantiles() []float64 {
+	if x != nil {
+		return x.Quantiles
+	}
+	return nil
+}
+
+// From StreamMetrics, each message carries a single chunk of one series in
+// list. A long series is split over consecutive messages that repeat its
+// metric.
+type GetMetricsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	List  []*TimeSeries          `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
+	// Bucket width in seconds of the data that served the query, 0 for raw.
+	Resolution    int64 `protobuf:"varint,2,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricsResponse) Reset() {
+	*x = GetMetricsResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricsResponse) ProtoMessage() {}
+
+func (x *GetMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricsResponse.ProtoReflect.Descriptor instead.
+func (*GetMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetMetricsResponse) GetList() []*TimeSeries {
+	if x != nil {
+		return x.List
+	}
+	return nil
+}
+
+func (x *GetMetricsResponse) GetResolution() int64 {
+	if x != nil {
+		return x.Resolution
+	}
+	return 0
+}
+
+type ListNamesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNamesRequest) Reset() {
+	*x = ListNamesRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNamesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNamesRequest) ProtoMessage() {}
+
+func (x *ListNamesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNamesRequest.ProtoReflect.Descriptor instead.
+func (*ListNamesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListNamesRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type ListNamesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Names         []string               `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNamesResponse) Reset() {
+	*x = ListNamesResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNamesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNamesResponse) ProtoMessage() {}
+
+func (x *ListNamesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNamesResponse.ProtoReflect.Descriptor instead.
+func (*ListNamesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListNamesResponse) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+// Label discovery only looks at series with samples in [start_time,
+// end_time]; zero bounds are open. match_name and matchers narrow it further.
+type LabelNamesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	MatchName     string                 `protobuf:"bytes,2,opt,name=match_name,json=matchName,proto3" json:"match_name,omitempty"`
+	Matchers      []*LabelMatcher        `protobuf:"bytes,3,rep,name=matchers,proto3" json:"matchers,omitempty"`
+	StartTime     int64                  `protobuf:"varint,4,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime       int64                  `protobuf:"varint,5,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LabelNamesRequest) Reset() {
+	*x = LabelNamesRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelNamesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelNamesRequest) ProtoMessage() {}
+
+func (x *LabelNamesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelNamesRequest.ProtoReflect.Descriptor instead.
+func (*LabelNamesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *LabelNamesRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *LabelNamesRequest) GetMatchName() string {
+	if x != nil {
+		return x.MatchName
+	}
+	return ""
+}
+
+func (x *LabelNamesRequest) GetMatchers() []*LabelMatcher {
+	if x != nil {
+		return x.Matchers
+	}
+	return nil
+}
+
+func (x *LabelNamesRequest) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *LabelNamesRequest) GetEndTime() int64 {
+	if x != nil {
+		return x.EndTime
+	}
+	return 0
+}
+
+type LabelNamesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Names         []string               `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LabelNamesResponse) Reset() {
+	*x = LabelNamesResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelNamesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelNamesResponse) ProtoMessage() {}
+
+func (x *LabelNamesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelNamesResponse.ProtoReflect.Descriptor instead.
+func (*LabelNamesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *LabelNamesResponse) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+type LabelValuesRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// __name__ lists metric names.
+	LabelName     string          `protobuf:"bytes,2,opt,name=label_name,json=labelName,proto3" json:"label_name,omitempty"`
+	MatchName     string          `protobuf:"bytes,3,opt,name=match_name,json=matchName,proto3" json:"match_name,omitempty"`
+	Matchers      []*LabelMatcher `protobuf:"bytes,4,rep,name=matchers,proto3" json:"matchers,omitempty"`
+	StartTime     int64           `protobuf:"varint,5,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime       int64           `protobuf:"varint,6,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LabelValuesRequest) Reset() {
+	*x = LabelValuesRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelValuesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelValuesRequest) ProtoMessage() {}
+
+func (x *LabelValuesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelValuesRequest.ProtoReflect.Descriptor instead.
+func (*LabelValuesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *LabelValuesRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *LabelValuesRequest) GetLabelName() string {
+	if x != nil {
+		return x.LabelName
+	}
+	return ""
+}
+
+func (x *LabelValuesRequest) GetMatchName() string {
+	if x != nil {
+		return x.MatchName
+	}
+	return ""
+}
+
+func (x *LabelValuesRequest) GetMatchers() []*LabelMatcher {
+	if x != nil {
+		return x.Matchers
+	}
+	return nil
+}
+
+func (x *LabelValuesRequest) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *LabelValuesRequest) GetEndTime() int64 {
+	if x != nil {
+		return x.EndTime
+	}
+	return 0
+}
+
+type LabelValuesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LabelValuesResponse) Reset() {
+	*x = LabelValuesResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelValuesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelValuesResponse) ProtoMessage() {}
+
+func (x *LabelValuesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelValuesResponse.ProtoReflect.Descriptor instead.
+func (*LabelValuesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *LabelValuesResponse) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type UsageStatsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Caps the metrics and labels returned; 0 returns all of them.
+	Limit         int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UsageStatsRequest) Reset() {
+	*x = UsageStatsRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UsageStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UsageStatsRequest) ProtoMessage() {}
+
+func (x *UsageStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UsageStatsRequest.ProtoReflect.Descriptor instead.
+func (*UsageStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *UsageStatsRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *UsageStatsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// Usage of one tenant's storage. Only series that still have samples count.
+// The last-day figures cover the 24 hours before the request, and bytes are
+// the backend's estimate of the space the samples take, without indexes.
+type UsageStatsResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Series         int64                  `protobuf:"varint,1,opt,name=series,proto3" json:"series,omitempty"`
+	SamplesLastDay int64                  `protobuf:"varint,2,opt,name=samples_last_day,json=samplesLastDay,proto3" json:"samples_last_day,omitempty"`
+	BytesLastDay   int64                  `protobuf:"varint,3,opt,name=bytes_last_day,json=bytesLastDay,proto3" json:"bytes_last_day,omitempty"`
+	// Sorted by series, most first.
+	Metrics []*MetricUsage `protobuf:"bytes,4,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	// Sorted by distinct values, most first.
+	Labels        []*LabelUsage `protobuf:"bytes,5,rep,name=labels,proto3" json:"labels,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UsageStatsResponse) Reset() {
+	*x = UsageStatsResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UsageStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UsageStatsResponse) ProtoMessage() {}
+
+func (x *UsageStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UsageStatsResponse.ProtoReflect.Descriptor instead.
+func (*UsageStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *UsageStatsResponse) GetSeries() int64 {
+	if x != nil {
+		return x.Series
+	}
+	return 0
+}
+
+func (x *UsageStatsResponse) GetSamplesLastDay() int64 {
+	if x != nil {
+		return x.SamplesLastDay
+	}
+	return 0
+}
+
+func (x *UsageStatsResponse) GetBytesLastDay() int64 {
+	if x != nil {
+		return x.BytesLastDay
+	}
+	return 0
+}
+
+func (x *UsageStatsResponse) GetMetrics() []*MetricUsage {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+func (x *UsageStatsResponse) GetLabels() []*LabelUsage {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type MetricUsage struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Series         int64                  `protobuf:"varint,2,opt,name=series,proto3" json:"series,omitempty"`
+	SamplesLastDay int64                  `protobuf:"varint,3,opt,name=samples_last_day,json=samplesLastDay,proto3" json:"samples_last_day,omitempty"`
+	BytesLastDay   int64                  `protobuf:"varint,4,opt,name=bytes_last_day,json=bytesLastDay,proto3" json:"bytes_last_day,omitempty"`
+	// Timestamps of the oldest and newest samples stored.
+	FirstSeen     int64 `protobuf:"varint,5,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"`
+	LastSeen      int64 `protobuf:"varint,6,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetricUsage) Reset() {
+	*x = MetricUsage{}
+	mi := &file_proto_monitoring_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetricUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricUsage) ProtoMessage() {}
+
+func (x *MetricUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricUsage.ProtoReflect.Descriptor instead.
+func (*MetricUsage) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *MetricUsage) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MetricUsage) GetSeries() int64 {
+	if x != nil {
+		return x.Series
+	}
+	return 0
+}
+
+func (x *MetricUsage) GetSamplesLastDay() int64 {
+	if x != nil {
+		return x.SamplesLastDay
+	}
+	return 0
+}
+
+func (x *MetricUsage) GetBytesLastDay() int64 {
+	if x != nil {
+		return x.BytesLastDay
+	}
+	return 0
+}
+
+func (x *MetricUsage) GetFirstSeen() int64 {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return 0
+}
+
+func (x *MetricUsage) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+type LabelUsage struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	DistinctValues int64                  `protobuf:"varint,2,opt,name=distinct_values,json=distinctValues,proto3" json:"distinct_values,omitempty"`
+	// Series carrying the label.
+	Series        int64 `protobuf:"varint,3,opt,name=series,proto3" json:"series,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LabelUsage) Reset() {
+	*x = LabelUsage{}
+	mi := &file_proto_monitoring_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelUsage) ProtoMessage() {}
+
+func (x *LabelUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelUsage.ProtoReflect.Descriptor instead.
+func (*LabelUsage) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *LabelUsage) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LabelUsage) GetDistinctValues() int64 {
+	if x != nil {
+		return x.DistinctValues
+	}
+	return 0
+}
+
+func (x *LabelUsage) GetSeries() int64 {
+	if x != nil {
+		return x.Series
+	}
+	return 0
+}
+
+type GetMetadataRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Only this metric's metadata when set.
+	MetricName    string `protobuf:"bytes,2,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetadataRequest) Reset() {
+	*x = GetMetadataRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetadataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetadataRequest) ProtoMessage() {}
+
+func (x *GetMetadataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetadataRequest.ProtoReflect.Descriptor instead.
+func (*GetMetadataRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetMetadataRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *GetMetadataRequest) GetMetricName() string {
+	if x != nil {
+		return x.MetricName
+	}
+	return ""
+}
+
+type GetMetadataResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      []*MetricMetadata      `protobuf:"bytes,1,rep,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetadataResponse) Reset() {
+	*x = GetMetadataResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetadataResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetadataResponse) ProtoMessage() {}
+
+func (x *GetMetadataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetadataResponse.ProtoReflect.Descriptor instead.
+func (*GetMetadataResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetMetadataResponse) GetMetadata() []*MetricMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type VerifyKeyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ApiKey        string                 `protobuf:"bytes,1,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyKeyRequest) Reset() {
+	*x = VerifyKeyRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyKeyRequest) ProtoMessage() {}
+
+func (x *VerifyKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyKeyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyKeyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *VerifyKeyRequest) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+type VerifyKeyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Valid         bool                   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyKeyResponse) Reset() {
+	*x = VerifyKeyResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyKeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyKeyResponse) ProtoMessage() {}
+
+func (x *VerifyKeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyKeyResponse.ProtoReflect.Descriptor instead.
+func (*VerifyKeyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *VerifyKeyResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *VerifyKeyResponse) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type CreateUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateUserRequest) Reset() {
+	*x = CreateUserRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateUserRequest) ProtoMessage() {}
+
+func (x *CreateUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateUserRequest.ProtoReflect.Descriptor instead.
+func (*CreateUserRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *CreateUserRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+type CreateUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ApiKey        string                 `protobuf:"bytes,2,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateUserResponse) Reset() {
+	*x = CreateUserResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateUserResponse) ProtoMessage() {}
+
+func (x *CreateUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateUserResponse.ProtoReflect.Descriptor instead.
+func (*CreateUserResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *CreateUserResponse) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CreateUserResponse) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+func (x *CreateUserResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type AlertRule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RuleId        int64                  `protobuf:"varint,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	MetricName    string                 `protobuf:"bytes,2,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
+	Threshold     float64                `protobuf:"fixed64,3,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	UserId        int64                  `protobuf:"varint,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	WebhookUrl    string                 `protobuf:"bytes,5,opt,name=webhook_url,json=webhookUrl,proto3" json:"webhook_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlertRule) Reset() {
+	*x = AlertRule{}
+	mi := &file_proto_monitoring_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlertRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlertRule) ProtoMessage() {}
+
+func (x *AlertRule) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlertRule.ProtoReflect.Descriptor instead.
+func (*AlertRule) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *AlertRule) GetRuleId() int64 {
+	if x != nil {
+		return x.RuleId
+	}
+	return 0
+}
+
+func (x *AlertRule) GetMetricName() string {
+	if x != nil {
+		return x.MetricName
+	}
+	return ""
+}
+
+func (x *AlertRule) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *AlertRule) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *AlertRule) GetWebhookUrl() string {
+	if x != nil {
+		return x.WebhookUrl
+	}
+	return ""
+}
+
+type CreateRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	MetricName    string                 `protobuf:"bytes,2,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
+	Threshold     float64                `protobuf:"fixed64,3,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	WebhookUrl    string                 `protobuf:"bytes,4,opt,name=webhook_url,json=webhookUrl,proto3" json:"webhook_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRuleRequest) Reset() {
+	*x = CreateRuleRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRuleRequest) ProtoMessage() {}
+
+func (x *CreateRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRuleRequest.ProtoReflect.Descriptor instead.
+func (*CreateRuleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *CreateRuleRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CreateRuleRequest) GetMetricName() string {
+	if x != nil {
+		return x.MetricName
+	}
+	return ""
+}
+
+func (x *CreateRuleRequest) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *CreateRuleRequest) GetWebhookUrl() string {
+	if x != nil {
+		return x.WebhookUrl
+	}
+	return ""
+}
+
+type CreateRuleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RuleId        int64                  `protobuf:"varint,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRuleResponse) Reset() {
+	*x = CreateRuleResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRuleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRuleResponse) ProtoMessage() {}
+
+func (x *CreateRuleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRuleResponse.ProtoReflect.Descriptor instead.
+func (*CreateRuleResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *CreateRuleResponse) GetRuleId() int64 {
+	if x != nil {
+		return x.RuleId
+	}
+	return 0
+}
+
+type GetRulesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRulesRequest) Reset() {
+	*x = GetRulesRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRulesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRulesRequest) ProtoMessage() {}
+
+func (x *GetRulesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRulesRequest.ProtoReflect.Descriptor instead.
+func (*GetRulesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *GetRulesRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type GetRulesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rules         []*AlertRule           `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRulesResponse) Reset() {
+	*x = GetRulesResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRulesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRulesResponse) ProtoMessage() {}
+
+func (x *GetRulesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRulesResponse.ProtoReflect.Descriptor instead.
+func (*GetRulesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *GetRulesResponse) GetRules() []*AlertRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type DeleteRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RuleId        int64                  `protobuf:"varint,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRuleRequest) Reset() {
+	*x = DeleteRuleRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRuleRequest) ProtoMessage() {}
+
+func (x *DeleteRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRuleRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRuleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *DeleteRuleRequest) GetRuleId() int64 {
+	if x != nil {
+		return x.RuleId
+	}
+	return 0
+}
+
+func (x *DeleteRuleRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type DeleteRuleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRuleResponse) Reset() {
+	*x = DeleteRuleResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRuleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRuleResponse) ProtoMessage() {}
+
+func (x *DeleteRuleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRuleResponse.ProtoReflect.Descriptor instead.
+func (*DeleteRuleResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *DeleteRuleResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+// Forgets a metric: its samples, histograms, metadata and the alert rules
+// that watch it. DeleteSeries removes data only.
+type DeleteMetricRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MetricName    string                 `protobuf:"bytes,1,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMetricRequest) Reset() {
+	*x = DeleteMetricRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMetricRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMetricRequest) ProtoMessage() {}
+
+func (x *DeleteMetricRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMetricRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMetricRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *DeleteMetricRequest) GetMetricName() string {
+	if x != nil {
+		return x.MetricName
+	}
+	return ""
+}
+
+func (x *DeleteMetricRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type DeleteMetricResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMetricResponse) Reset() {
+	*x = DeleteMetricResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMetricResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMetricResponse) ProtoMessage() {}
+
+func (x *DeleteMetricResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMetricResponse.ProtoReflect.Descriptor instead.
+func (*DeleteMetricResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *DeleteMetricResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+// Deletes the samples and histograms of the series the selector matches
+// within [start_time, end_time], where a zero bound is open. Alert rules and
+// metadata are left alone, even when every sample goes: unlike DeleteMetric,
+// which forgets a metric along with its rules, this corrects the data of one
+// that is still being sent. With dry_run nothing is deleted and the response
+// says what would have been.
+type DeleteSeriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	MatchName     string                 `protobuf:"bytes,2,opt,name=match_name,json=matchName,proto3" json:"match_name,omitempty"`
+	Matchers      []*LabelMatcher        `protobuf:"bytes,3,rep,name=matchers,proto3" json:"matchers,omitempty"`
+	StartTime     int64                  `protobuf:"varint,4,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime       int64                  `protobuf:"varint,5,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	DryRun        bool                   `protobuf:"varint,6,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSeriesRequest) Reset() {
+	*x = DeleteSeriesRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSeriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSeriesRequest) ProtoMessage() {}
+
+func (x *DeleteSeriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSeriesRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSeriesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *DeleteSeriesRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *DeleteSeriesRequest) GetMatchName() string {
+	if x != nil {
+		return x.MatchName
+	}
+	return ""
+}
+
+func (x *DeleteSeriesRequest) GetMatchers() []*LabelMatcher {
+	if x != nil {
+		return x.Matchers
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use ListNamesRequest.ProtoReflect.Descriptor instead.
-func (*ListNamesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{7}
+func (x *DeleteSeriesRequest) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
 }
 
-func (x *ListNamesRequest) GetUserId() int64 {
+func (x *DeleteSeriesRequest) GetEndTime() int64 {
 	if x != nil {
-		return x.UserId
+		return x.EndTime
 	}
 	return 0
 }
 
-type ListNamesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Names         []string               `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *DeleteSeriesRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
 }
 
-func (x *ListNamesResponse) Reset() {
-	*x = ListNamesResponse{}
-	mi := &file_proto_monitoring_proto_msgTypes[8]
+type DeleteSeriesResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	DeletedSamples int64                  `protobuf:"varint,1,opt,name=deleted_samples,json=deletedSamples,proto3" json:"deleted_samples,omitempty"`
+	Series         int64                  `protobuf:"varint,2,opt,name=series,proto3" json:"series,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DeleteSeriesResponse) Reset() {
+	*x = DeleteSeriesResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListNamesResponse) String() string {
+func (x *DeleteSeriesResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListNamesResponse) ProtoMessage() {}
+func (*DeleteSeriesResponse) ProtoMessage() {}
 
-func (x *ListNamesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[8]
+func (x *DeleteSeriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -469,40 +2732,54 @@ func (x *ListNamesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListNamesResponse.ProtoReflect.Descriptor instead.
-func (*ListNamesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use DeleteSeriesResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSeriesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{40}
 }
 
-func (x *ListNamesResponse) GetNames() []string {
+func (x *DeleteSeriesResponse) GetDeletedSamples() int64 {
 	if x != nil {
-		return x.Names
+		return x.DeletedSamples
 	}
-	return nil
+	return 0
 }
 
-type VerifyKeyRequest struct {
+func (x *DeleteSeriesResponse) GetSeries() int64 {
+	if x != nil {
+		return x.Series
+	}
+	return 0
+}
+
+// A retention policy overrides the global retention for a user's data. An
+// empty metric_name and no matchers applies to all of the user's metrics;
+// the most specific matching policy wins.
+type RetentionPolicy struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ApiKey        string                 `protobuf:"bytes,1,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	PolicyId      int64                  `protobuf:"varint,1,opt,name=policy_id,json=policyId,proto3" json:"policy_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	MetricName    string                 `protobuf:"bytes,3,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
+	Matchers      []*LabelMatcher        `protobuf:"bytes,4,rep,name=matchers,proto3" json:"matchers,omitempty"`
+	RetentionDays int32                  `protobuf:"varint,5,opt,name=retention_days,json=retentionDays,proto3" json:"retention_days,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *VerifyKeyRequest) Reset() {
-	*x = VerifyKeyRequest{}
-	mi := &file_proto_monitoring_proto_msgTypes[9]
+func (x *RetentionPolicy) Reset() {
+	*x = RetentionPolicy{}
+	mi := &file_proto_monitoring_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *VerifyKeyRequest) String() string {
+func (x *RetentionPolicy) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*VerifyKeyRequest) ProtoMessage() {}
+func (*RetentionPolicy) ProtoMessage() {}
 
-func (x *VerifyKeyRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[9]
+func (x *RetentionPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -513,41 +2790,71 @@ func (x *VerifyKeyRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use VerifyKeyRequest.ProtoReflect.Descriptor instead.
-func (*VerifyKeyRequest) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use RetentionPolicy.ProtoReflect.Descriptor instead.
+func (*RetentionPolicy) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{41}
 }
 
-func (x *VerifyKeyRequest) GetApiKey() string {
+func (x *RetentionPolicy) GetPolicyId() int64 {
 	if x != nil {
-		return x.ApiKey
+		return x.PolicyId
+	}
+	return 0
+}
+
+func (x *RetentionPolicy) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *RetentionPolicy) GetMetricName() string {
+	if x != nil {
+		return x.MetricName
 	}
 	return ""
 }
 
-type VerifyKeyResponse struct {
+func (x *RetentionPolicy) GetMatchers() []*LabelMatcher {
+	if x != nil {
+		return x.Matchers
+	}
+	return nil
+}
+
+func (x *RetentionPolicy) GetRetentionDays() int32 {
+	if x != nil {
+		return x.RetentionDays
+	}
+	return 0
+}
+
+type CreatePolicyRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Valid         bool                   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
-	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	MetricName    string                 `protobuf:"bytes,2,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
+	Matchers      []*LabelMatcher        `protobuf:"bytes,3,rep,name=matchers,proto3" json:"matchers,omitempty"`
+	RetentionDays int32                  `protobuf:"varint,4,opt,name=retention_days,json=retentionDays,proto3" json:"retention_days,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *VerifyKeyResponse) Reset() {
-	*x = VerifyKeyResponse{}
-	mi := &file_proto_monitoring_proto_msgTypes[10]
+func (x *CreatePolicyRequest) Reset() {
+	*x = CreatePolicyRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *VerifyKeyResponse) String() string {
+func (x *CreatePolicyRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*VerifyKeyResponse) ProtoMessage() {}
+func (*CreatePolicyRequest) ProtoMessage() {}
 
-func (x *VerifyKeyResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[10]
+func (x *CreatePolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -558,47 +2865,61 @@ func (x *VerifyKeyResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use VerifyKeyResponse.ProtoReflect.Descriptor instead.
-func (*VerifyKeyResponse) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use CreatePolicyRequest.ProtoReflect.Descriptor instead.
+func (*CreatePolicyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{42}
 }
 
-func (x *VerifyKeyResponse) GetValid() bool {
+func (x *CreatePolicyRequest) GetUserId() int64 {
 	if x != nil {
-		return x.Valid
+		return x.UserId
 	}
-	return false
+	return 0
 }
 
-func (x *VerifyKeyResponse) GetUserId() int64 {
+func (x *CreatePolicyRequest) GetMetricName() string {
 	if x != nil {
-		return x.UserId
+		return x.MetricName
+	}
+	return ""
+}
+
+func (x *CreatePolicyRequest) GetMatchers() []*LabelMatcher {
+	if x != nil {
+		return x.Matchers
+	}
+	return nil
+}
+
+func (x *CreatePolicyRequest) GetRetentionDays() int32 {
+	if x != nil {
+		return x.RetentionDays
 	}
 	return 0
 }
 
-type CreateUserRequest struct {
+type CreatePolicyResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	PolicyId      int64                  `protobuf:"varint,1,opt,name=policy_id,json=policyId,proto3" json:"policy_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateUserRequest) Reset() {
-	*x = CreateUserRequest{}
-	mi := &file_proto_monitoring_proto_msgTypes[11]
+func (x *CreatePolicyResponse) Reset() {
+	*x = CreatePolicyResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateUserRequest) String() string {
+func (x *CreatePolicyResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateUserRequest) ProtoMessage() {}
+func (*CreatePolicyResponse) ProtoMessage() {}
 
-func (x *CreateUserRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[11]
+func (x *CreatePolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -609,42 +2930,40 @@ func (x *CreateUserRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateUserRequest.ProtoReflect.Descriptor instead.
-func (*CreateUserRequest) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use CreatePolicyResponse.ProtoReflect.Descriptor instead.
+func (*CreatePolicyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{43}
 }
 
-func (x *CreateUserRequest) GetEmail() string {
+func (x *CreatePolicyResponse) GetPolicyId() int64 {
 	if x != nil {
-		return x.Email
+		return x.PolicyId
 	}
-	return ""
+	return 0
 }
 
-type CreateUserResponse struct {
+type GetPoliciesRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	ApiKey        string                 `protobuf:"bytes,2,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
-	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateUserResponse) Reset() {
-	*x = CreateUserResponse{}
-	mi := &file_proto_monitoring_proto_msgTypes[12]
+func (x *GetPoliciesRequest) Reset() {
+	*x = GetPoliciesRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[44]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateUserResponse) String() string {
+func (x *GetPoliciesRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateUserResponse) ProtoMessage() {}
+func (*GetPoliciesRequest) ProtoMessage() {}
 
-func (x *CreateUserResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[12]
+func (x *GetPoliciesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[44]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -655,58 +2974,85 @@ func (x *CreateUserResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateUserResponse.ProtoReflect.Descriptor instead.
-func (*CreateUserResponse) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use GetPoliciesRequest.ProtoReflect.Descriptor instead.
+func (*GetPoliciesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{44}
 }
 
-func (x *CreateUserResponse) GetUserId() int64 {
+func (x *GetPoliciesRequest) GetUserId() int64 {
 	if x != nil {
 		return x.UserId
 	}
 	return 0
 }
 
-func (x *CreateUserResponse) GetApiKey() string {
+type GetPoliciesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Policies      []*RetentionPolicy     `protobuf:"bytes,1,rep,name=policies,proto3" json:"policies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPoliciesResponse) Reset() {
+	*x = GetPoliciesResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPoliciesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPoliciesResponse) ProtoMessage() {}
+
+func (x *GetPoliciesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[45]
 	if x != nil {
-		return x.ApiKey
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *CreateUserResponse) GetError() string {
+// Deprecated: Use GetPoliciesResponse.ProtoReflect.Descriptor instead.
+func (*GetPoliciesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *GetPoliciesResponse) GetPolicies() []*RetentionPolicy {
 	if x != nil {
-		return x.Error
+		return x.Policies
 	}
-	return ""
+	return nil
 }
 
-type AlertRule struct {
+type DeletePolicyRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RuleId        int64                  `protobuf:"varint,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
-	MetricName    string                 `protobuf:"bytes,2,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
-	Threshold     float64                `protobuf:"fixed64,3,opt,name=threshold,proto3" json:"threshold,omitempty"`
-	UserId        int64                  `protobuf:"varint,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	WebhookUrl    string                 `protobuf:"bytes,5,opt,name=webhook_url,json=webhookUrl,proto3" json:"webhook_url,omitempty"`
+	PolicyId      int64                  `protobuf:"varint,1,opt,name=policy_id,json=policyId,proto3" json:"policy_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AlertRule) Reset() {
-	*x = AlertRule{}
-	mi := &file_proto_monitoring_proto_msgTypes[13]
+func (x *DeletePolicyRequest) Reset() {
+	*x = DeletePolicyRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[46]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AlertRule) String() string {
+func (x *DeletePolicyRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AlertRule) ProtoMessage() {}
+func (*DeletePolicyRequest) ProtoMessage() {}
 
-func (x *AlertRule) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[13]
+func (x *DeletePolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[46]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -717,71 +3063,99 @@ func (x *AlertRule) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AlertRule.ProtoReflect.Descriptor instead.
-func (*AlertRule) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use DeletePolicyRequest.ProtoReflect.Descriptor instead.
+func (*DeletePolicyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{46}
 }
 
-func (x *AlertRule) GetRuleId() int64 {
+func (x *DeletePolicyRequest) GetPolicyId() int64 {
 	if x != nil {
-		return x.RuleId
+		return x.PolicyId
 	}
 	return 0
 }
 
-func (x *AlertRule) GetMetricName() string {
+func (x *DeletePolicyRequest) GetUserId() int64 {
 	if x != nil {
-		return x.MetricName
+		return x.UserId
 	}
-	return ""
+	return 0
 }
 
-func (x *AlertRule) GetThreshold() float64 {
-	if x != nil {
-		return x.Threshold
-	}
-	return 0
+type DeletePolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AlertRule) GetUserId() int64 {
+func (x *DeletePolicyResponse) Reset() {
+	*x = DeletePolicyResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePolicyResponse) ProtoMessage() {}
+
+func (x *DeletePolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[47]
 	if x != nil {
-		return x.UserId
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *AlertRule) GetWebhookUrl() string {
+// Deprecated: Use DeletePolicyResponse.ProtoReflect.Descriptor instead.
+func (*DeletePolicyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *DeletePolicyResponse) GetOk() bool {
 	if x != nil {
-		return x.WebhookUrl
+		return x.Ok
 	}
-	return ""
+	return false
 }
 
-type CreateRuleRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	MetricName    string                 `protobuf:"bytes,2,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
-	Threshold     float64                `protobuf:"fixed64,3,opt,name=threshold,proto3" json:"threshold,omitempty"`
-	WebhookUrl    string                 `protobuf:"bytes,4,opt,name=webhook_url,json=webhookUrl,proto3" json:"webhook_url,omitempty"`
+// One entry of a tenant archive; exactly one field is set. An export lists
+// metadata first, then alert rules, then series chunks, a series' float and
+// histogram samples coming in separate chunks. Rules carry no IDs.
+type ArchiveRecord struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Record:
+	//
+	//	*ArchiveRecord_Metadata
+	//	*ArchiveRecord_Rule
+	//	*ArchiveRecord_Series
+	Record        isArchiveRecord_Record `protobuf_oneof:"record"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateRuleRequest) Reset() {
-	*x = CreateRuleRequest{}
-	mi := &file_proto_monitoring_proto_msgTypes[14]
+func (x *ArchiveRecord) Reset() {
+	*x = ArchiveRecord{}
+	mi := &file_proto_monitoring_proto_msgTypes[48]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateRuleRequest) String() string {
+func (x *ArchiveRecord) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateRuleRequest) ProtoMessage() {}
+func (*ArchiveRecord) ProtoMessage() {}
 
-func (x *CreateRuleRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[14]
+func (x *ArchiveRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[48]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -792,61 +3166,89 @@ func (x *CreateRuleRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateRuleRequest.ProtoReflect.Descriptor instead.
-func (*CreateRuleRequest) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use ArchiveRecord.ProtoReflect.Descriptor instead.
+func (*ArchiveRecord) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{48}
 }
 
-func (x *CreateRuleRequest) GetUserId() int64 {
+func (x *ArchiveRecord) GetRecord() isArchiveRecord_Record {
 	if x != nil {
-		return x.UserId
+		return x.Record
 	}
-	return 0
+	return nil
 }
 
-func (x *CreateRuleRequest) GetMetricName() string {
+func (x *ArchiveRecord) GetMetadata() *MetricMetadata {
 	if x != nil {
-		return x.MetricName
+		if x, ok := x.Record.(*ArchiveRecord_Metadata); ok {
+			return x.Metadata
+		}
 	}
-	return ""
+	return nil
 }
 
-func (x *CreateRuleRequest) GetThreshold() float64 {
+func (x *ArchiveRecord) GetRule() *AlertRule {
 	if x != nil {
-		return x.Threshold
+		if x, ok := x.Record.(*ArchiveRecord_Rule); ok {
+			return x.Rule
+		}
 	}
-	return 0
+	return nil
 }
 
-func (x *CreateRuleRequest) GetWebhookUrl() string {
+func (x *ArchiveRecord) GetSeries() *TimeSeries {
 	if x != nil {
-		return x.WebhookUrl
+		if x, ok := x.Record.(*ArchiveRecord_Series); ok {
+			return x.Series
+		}
 	}
-	return ""
+	return nil
 }
 
-type CreateRuleResponse struct {
+type isArchiveRecord_Record interface {
+	isArchiveRecord_Record()
+}
+
+type ArchiveRecord_Metadata struct {
+	Metadata *MetricMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"`
+}
+
+type ArchiveRecord_Rule struct {
+	Rule *AlertRule `protobuf:"bytes,2,opt,name=rule,proto3,oneof"`
+}
+
+type ArchiveRecord_Series struct {
+	Series *TimeSeries `protobuf:"bytes,3,opt,name=series,proto3,oneof"`
+}
+
+func (*ArchiveRecord_Metadata) isArchiveRecord_Record() {}
+
+func (*ArchiveRecord_Rule) isArchiveRecord_Record() {}
+
+func (*ArchiveRecord_Series) isArchiveRecord_Record() {}
+
+type ExportRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RuleId        int64                  `protobuf:"varint,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateRuleResponse) Reset() {
-	*x = CreateRuleResponse{}
-	mi := &file_proto_monitoring_proto_msgTypes[15]
+func (x *ExportRequest) Reset() {
+	*x = ExportRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[49]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateRuleResponse) String() string {
+func (x *ExportRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateRuleResponse) ProtoMessage() {}
+func (*ExportRequest) ProtoMessage() {}
 
-func (x *CreateRuleResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[15]
+func (x *ExportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[49]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -857,40 +3259,44 @@ func (x *CreateRuleResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateRuleResponse.ProtoReflect.Descriptor instead.
-func (*CreateRuleResponse) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{15}
+// Deprecated: Use ExportRequest.ProtoReflect.Descriptor instead.
+func (*ExportRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{49}
 }
 
-func (x *CreateRuleResponse) GetRuleId() int64 {
+func (x *ExportRequest) GetUserId() int64 {
 	if x != nil {
-		return x.RuleId
+		return x.UserId
 	}
 	return 0
 }
 
-type GetRulesRequest struct {
+// Streams an archive into user_id, which need not be the user it was
+// exported from. user_id and conflict are read from the first message.
+type ImportRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Conflict      ImportRequest_Conflict `protobuf:"varint,2,opt,name=conflict,proto3,enum=monitoring.ImportRequest_Conflict" json:"conflict,omitempty"`
+	Records       []*ArchiveRecord       `protobuf:"bytes,3,rep,name=records,proto3" json:"records,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetRulesRequest) Reset() {
-	*x = GetRulesRequest{}
-	mi := &file_proto_monitoring_proto_msgTypes[16]
+func (x *ImportRequest) Reset() {
+	*x = ImportRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[50]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetRulesRequest) String() string {
+func (x *ImportRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetRulesRequest) ProtoMessage() {}
+func (*ImportRequest) ProtoMessage() {}
 
-func (x *GetRulesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[16]
+func (x *ImportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[50]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -901,40 +3307,64 @@ func (x *GetRulesRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetRulesRequest.ProtoReflect.Descriptor instead.
-func (*GetRulesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{16}
+// Deprecated: Use ImportRequest.ProtoReflect.Descriptor instead.
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{50}
 }
 
-func (x *GetRulesRequest) GetUserId() int64 {
+func (x *ImportRequest) GetUserId() int64 {
 	if x != nil {
 		return x.UserId
 	}
 	return 0
 }
 
-type GetRulesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Rules         []*AlertRule           `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *ImportRequest) GetConflict() ImportRequest_Conflict {
+	if x != nil {
+		return x.Conflict
+	}
+	return ImportRequest_KEEP_EXISTING
 }
 
-func (x *GetRulesResponse) Reset() {
-	*x = GetRulesResponse{}
-	mi := &file_proto_monitoring_proto_msgTypes[17]
+func (x *ImportRequest) GetRecords() []*ArchiveRecord {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+type ImportResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	StoredSamples    int64                  `protobuf:"varint,1,opt,name=stored_samples,json=storedSamples,proto3" json:"stored_samples,omitempty"`
+	DuplicateSamples int64                  `protobuf:"varint,2,opt,name=duplicate_samples,json=duplicateSamples,proto3" json:"duplicate_samples,omitempty"`
+	RulesCreated     int32                  `protobuf:"varint,3,opt,name=rules_created,json=rulesCreated,proto3" json:"rules_created,omitempty"`
+	RulesReplaced    int32                  `protobuf:"varint,4,opt,name=rules_replaced,json=rulesReplaced,proto3" json:"rules_replaced,omitempty"`
+	RulesSkipped     int32                  `protobuf:"varint,5,opt,name=rules_skipped,json=rulesSkipped,proto3" json:"rules_skipped,omitempty"`
+	MetadataSet      int32                  `protobuf:"varint,6,opt,name=metadata_set,json=metadataSet,proto3" json:"metadata_set,omitempty"`
+	MetadataSkipped  int32                  `protobuf:"varint,7,opt,name=metadata_skipped,json=metadataSkipped,proto3" json:"metadata_skipped,omitempty"`
+	// Set when a REJECT import stopped at a conflict.
+	Error string `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	// Samples dropped for exceeding one of the user's limits.
+	LimitedSamples int64 `protobuf:"varint,9,opt,name=limited_samples,json=limitedSamples,proto3" json:"limited_samples,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ImportResponse) Reset() {
+	*x = ImportResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[51]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetRulesResponse) String() string {
+func (x *ImportResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetRulesResponse) ProtoMessage() {}
+func (*ImportResponse) ProtoMessage() {}
 
-func (x *GetRulesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[17]
+func (x *ImportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[51]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -945,92 +3375,103 @@ func (x *GetRulesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetRulesResponse.ProtoReflect.Descriptor instead.
-func (*GetRulesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{17}
+// Deprecated: Use ImportResponse.ProtoReflect.Descriptor instead.
+func (*ImportResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{51}
 }
 
-func (x *GetRulesResponse) GetRules() []*AlertRule {
+func (x *ImportResponse) GetStoredSamples() int64 {
 	if x != nil {
-		return x.Rules
+		return x.StoredSamples
 	}
-	return nil
+	return 0
 }
 
-type DeleteRuleRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	RuleId        int64                  `protobuf:"varint,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
-	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *ImportResponse) GetDuplicateSamples() int64 {
+	if x != nil {
+		return x.DuplicateSamples
+	}
+	return 0
 }
 
-func (x *DeleteRuleRequest) Reset() {
-	*x = DeleteRuleRequest{}
-	mi := &file_proto_monitoring_proto_msgTypes[18]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *ImportResponse) GetRulesCreated() int32 {
+	if x != nil {
+		return x.RulesCreated
+	}
+	return 0
 }
 
-func (x *DeleteRuleRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *ImportResponse) GetRulesReplaced() int32 {
+	if x != nil {
+		return x.RulesReplaced
+	}
+	return 0
 }
 
-func (*DeleteRuleRequest) ProtoMessage() {}
-
-func (x *DeleteRuleRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[18]
+func (x *ImportResponse) GetRulesSkipped() int32 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.RulesSkipped
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use DeleteRuleRequest.ProtoReflect.Descriptor instead.
-func (*DeleteRuleRequest) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{18}
+func (x *ImportResponse) GetMetadataSet() int32 {
+	if x != nil {
+		return x.MetadataSet
+	}
+	return 0
 }
 
-func (x *DeleteRuleRequest) GetRuleId() int64 {
+func (x *ImportResponse) GetMetadataSkipped() int32 {
 	if x != nil {
-		return x.RuleId
+		return x.MetadataSkipped
 	}
 	return 0
 }
 
-func (x *DeleteRuleRequest) GetUserId() int64 {
+func (x *ImportResponse) GetError() string {
 	if x != nil {
-		return x.UserId
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ImportResponse) GetLimitedSamples() int64 {
+	if x != nil {
+		return x.LimitedSamples
 	}
 	return 0
 }
 
-type DeleteRuleResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+// What a user may write; 0 means no limit. Series and samples are counted
+// per UTC day: a series counts once it has a sample written that day.
+type TenantLimits struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	MaxSeries           int64                  `protobuf:"varint,1,opt,name=max_series,json=maxSeries,proto3" json:"max_series,omitempty"`
+	MaxSamplesPerDay    int64                  `protobuf:"varint,2,opt,name=max_samples_per_day,json=maxSamplesPerDay,proto3" json:"max_samples_per_day,omitempty"`
+	MaxLabelsPerSeries  int32                  `protobuf:"varint,3,opt,name=max_labels_per_series,json=maxLabelsPerSeries,proto3" json:"max_labels_per_series,omitempty"`
+	MaxLabelValueLength int32                  `protobuf:"varint,4,opt,name=max_label_value_length,json=maxLabelValueLength,proto3" json:"max_label_value_length,omitempty"`
+	// Applies to live ingest only, in bursts of up to ten seconds' worth.
+	MaxSamplesPerSecond int64 `protobuf:"varint,5,opt,name=max_samples_per_second,json=maxSamplesPerSecond,proto3" json:"max_samples_per_second,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
-func (x *DeleteRuleResponse) Reset() {
-	*x = DeleteRuleResponse{}
-	mi := &file_proto_monitoring_proto_msgTypes[19]
+func (x *TenantLimits) Reset() {
+	*x = TenantLimits{}
+	mi := &file_proto_monitoring_proto_msgTypes[52]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteRuleResponse) String() string {
+func (x *TenantLimits) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteRuleResponse) ProtoMessage() {}
+func (*TenantLimits) ProtoMessage() {}
 
-func (x *DeleteRuleResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[19]
+func (x *TenantLimits) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[52]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1041,41 +3482,72 @@ func (x *DeleteRuleResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteRuleResponse.ProtoReflect.Descriptor instead.
-func (*DeleteRuleResponse) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{19}
+// Deprecated: Use TenantLimits.ProtoReflect.Descriptor instead.
+func (*TenantLimits) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{52}
 }
 
-func (x *DeleteRuleResponse) GetOk() bool {
+func (x *TenantLimits) GetMaxSeries() int64 {
 	if x != nil {
-		return x.Ok
+		return x.MaxSeries
 	}
-	return false
+	return 0
 }
 
-type DeleteMetricRequest struct {
+func (x *TenantLimits) GetMaxSamplesPerDay() int64 {
+	if x != nil {
+		return x.MaxSamplesPerDay
+	}
+	return 0
+}
+
+func (x *TenantLimits) GetMaxLabelsPerSeries() int32 {
+	if x != nil {
+		return x.MaxLabelsPerSeries
+	}
+	return 0
+}
+
+func (x *TenantLimits) GetMaxLabelValueLength() int32 {
+	if x != nil {
+		return x.MaxLabelValueLength
+	}
+	return 0
+}
+
+func (x *TenantLimits) GetMaxSamplesPerSecond() int64 {
+	if x != nil {
+		return x.MaxSamplesPerSecond
+	}
+	return 0
+}
+
+// Reports a user's limits and what they have used of them today. With
+// series set, the response also lists which of them are new today and
+// would not fit under max_series.
+type QuotaRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	MetricName    string                 `protobuf:"bytes,1,opt,name=metric_name,json=metricName,proto3" json:"metric_name,omitempty"`
-	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Series        []*Metric              `protobuf:"bytes,2,rep,name=series,proto3" json:"series,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteMetricRequest) Reset() {
-	*x = DeleteMetricRequest{}
-	mi := &file_proto_monitoring_proto_msgTypes[20]
+func (x *QuotaRequest) Reset() {
+	*x = QuotaRequest{}
+	mi := &file_proto_monitoring_proto_msgTypes[53]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteMetricRequest) String() string {
+func (x *QuotaRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteMetricRequest) ProtoMessage() {}
+func (*QuotaRequest) ProtoMessage() {}
 
-func (x *DeleteMetricRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[20]
+func (x *QuotaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[53]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1086,47 +3558,57 @@ func (x *DeleteMetricRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteMetricRequest.ProtoReflect.Descriptor instead.
-func (*DeleteMetricRequest) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{20}
+// Deprecated: Use QuotaRequest.ProtoReflect.Descriptor instead.
+func (*QuotaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{53}
 }
 
-func (x *DeleteMetricRequest) GetMetricName() string {
+func (x *QuotaRequest) GetUserId() int64 {
 	if x != nil {
-		return x.MetricName
+		return x.UserId
 	}
-	return ""
+	return 0
 }
 
-func (x *DeleteMetricRequest) GetUserId() int64 {
+func (x *QuotaRequest) GetSeries() []*Metric {
 	if x != nil {
-		return x.UserId
+		return x.Series
 	}
-	return 0
+	return nil
 }
 
-type DeleteMetricResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+type QuotaResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Limits *TenantLimits          `protobuf:"bytes,1,opt,name=limits,proto3" json:"limits,omitempty"`
+	Series int64                  `protobuf:"varint,2,opt,name=series,proto3" json:"series,omitempty"`
+	// Samples accepted today, duplicates of stored samples included: limits
+	// are applied before they are dropped.
+	SamplesToday int64 `protobuf:"varint,3,opt,name=samples_today,json=samplesToday,proto3" json:"samples_today,omitempty"`
+	// Samples the storage service dropped today for exceeding a limit.
+	LimitedToday int64 `protobuf:"varint,4,opt,name=limited_today,json=limitedToday,proto3" json:"limited_today,omitempty"`
+	// When the daily counts start over, as a Unix timestamp.
+	ResetsAt int64 `protobuf:"varint,5,opt,name=resets_at,json=resetsAt,proto3" json:"resets_at,omitempty"`
+	// Indexes into the request's series.
+	OverSeries    []int32 `protobuf:"varint,6,rep,packed,name=over_series,json=overSeries,proto3" json:"over_series,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteMetricResponse) Reset() {
-	*x = DeleteMetricResponse{}
-	mi := &file_proto_monitoring_proto_msgTypes[21]
+func (x *QuotaResponse) Reset() {
+	*x = QuotaResponse{}
+	mi := &file_proto_monitoring_proto_msgTypes[54]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteMetricResponse) String() string {
+func (x *QuotaResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteMetricResponse) ProtoMessage() {}
+func (*QuotaResponse) ProtoMessage() {}
 
-func (x *DeleteMetricResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_monitoring_proto_msgTypes[21]
+func (x *QuotaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_monitoring_proto_msgTypes[54]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1137,16 +3619,51 @@ func (x *DeleteMetricResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteMetricResponse.ProtoReflect.Descriptor instead.
-func (*DeleteMetricResponse) Descriptor() ([]byte, []int) {
-	return file_proto_monitoring_proto_rawDescGZIP(), []int{21}
+// Deprecated: Use QuotaResponse.ProtoReflect.Descriptor instead.
+func (*QuotaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_monitoring_proto_rawDescGZIP(), []int{54}
 }
 
-func (x *DeleteMetricResponse) GetOk() bool {
+func (x *QuotaResponse) GetLimits() *TenantLimits {
 	if x != nil {
-		return x.Ok
+		return x.Limits
 	}
-	return false
+	return nil
+}
+
+func (x *QuotaResponse) GetSeries() int64 {
+	if x != nil {
+		return x.Series
+	}
+	return 0
+}
+
+func (x *QuotaResponse) GetSamplesToday() int64 {
+	if x != nil {
+		return x.SamplesToday
+	}
+	return 0
+}
+
+func (x *QuotaResponse) GetLimitedToday() int64 {
+	if x != nil {
+		return x.LimitedToday
+	}
+	return 0
+}
+
+func (x *QuotaResponse) GetResetsAt() int64 {
+	if x != nil {
+		return x.ResetsAt
+	}
+	return 0
+}
+
+func (x *QuotaResponse) GetOverSeries() []int32 {
+	if x != nil {
+		return x.OverSeries
+	}
+	return nil
 }
 
 var File_proto_monitoring_proto protoreflect.FileDescriptor
@@ -1163,30 +3680,154 @@ const file_proto_monitoring_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"<\n" +
 	"\x06Sample\x12\x1c\n" +
 	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\x01R\x05value\"f\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\"\x87\x01\n" +
+	"\x0fHistogramSample\x12\x1c\n" +
+	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12\x16\n" +
+	"\x06bounds\x18\x02 \x03(\x01R\x06bounds\x12\x16\n" +
+	"\x06counts\x18\x03 \x03(\x04R\x06counts\x12\x10\n" +
+	"\x03sum\x18\x04 \x01(\x01R\x03sum\x12\x14\n" +
+	"\x05count\x18\x05 \x01(\x04R\x05count\"\xa3\x01\n" +
 	"\n" +
 	"TimeSeries\x12*\n" +
 	"\x06metric\x18\x01 \x01(\v2\x12.monitoring.MetricR\x06metric\x12,\n" +
-	"\asamples\x18\x02 \x03(\v2\x12.monitoring.SampleR\asamples\"T\n" +
+	"\asamples\x18\x02 \x03(\v2\x12.monitoring.SampleR\asamples\x12;\n" +
+	"\n" +
+	"histograms\x18\x03 \x03(\v2\x1b.monitoring.HistogramSampleR\n" +
+	"histograms\"\xd7\x01\n" +
+	"\x0eMetricMetadata\x12\x1f\n" +
+	"\vmetric_name\x18\x01 \x01(\tR\n" +
+	"metricName\x123\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x1f.monitoring.MetricMetadata.TypeR\x04type\x12\x12\n" +
+	"\x04unit\x18\x03 \x01(\tR\x04unit\x12\x12\n" +
+	"\x04help\x18\x04 \x01(\tR\x04help\"G\n" +
+	"\x04Type\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\v\n" +
+	"\aCOUNTER\x10\x01\x12\t\n" +
+	"\x05GAUGE\x10\x02\x12\r\n" +
+	"\tHISTOGRAM\x10\x03\x12\v\n" +
+	"\aSUMMARY\x10\x04\"\x8c\x01\n" +
 	"\rUploadRequest\x12*\n" +
 	"\x04list\x18\x01 \x03(\v2\x16.monitoring.TimeSeriesR\x04list\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\x03R\x06userId\"I\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\x126\n" +
+	"\bmetadata\x18\x03 \x03(\v2\x1a.monitoring.MetricMetadataR\bmetadata\"\xbe\x01\n" +
 	"\x0eUploadResponse\x12!\n" +
 	"\fstored_count\x18\x01 \x01(\x05R\vstoredCount\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"\x85\x01\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12'\n" +
+	"\x0fduplicate_count\x18\x03 \x01(\x05R\x0eduplicateCount\x12%\n" +
+	"\x0erejected_count\x18\x04 \x01(\x05R\rrejectedCount\x12#\n" +
+	"\rlimited_count\x18\x05 \x01(\x05R\flimitedCount\"\x85\x01\n" +
+	"\x13StreamUploadRequest\x12*\n" +
+	"\x04list\x18\x01 \x03(\v2\x16.monitoring.TimeSeriesR\x04list\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\x12)\n" +
+	"\x10transaction_size\x18\x03 \x01(\x05R\x0ftransactionSize\"\xfa\x01\n" +
+	"\x14StreamUploadResponse\x12!\n" +
+	"\fstored_count\x18\x01 \x01(\x03R\vstoredCount\x12\x16\n" +
+	"\x06chunks\x18\x02 \x01(\x05R\x06chunks\x12\"\n" +
+	"\ftransactions\x18\x03 \x01(\x05R\ftransactions\x125\n" +
+	"\bfailures\x18\x04 \x03(\v2\x19.monitoring.UploadFailureR\bfailures\x12'\n" +
+	"\x0fduplicate_count\x18\x05 \x01(\x03R\x0eduplicateCount\x12#\n" +
+	"\rlimited_count\x18\x06 \x01(\x03R\flimitedCount\"U\n" +
+	"\rUploadFailure\x12\x14\n" +
+	"\x05chunk\x18\x01 \x01(\x05R\x05chunk\x12\x18\n" +
+	"\asamples\x18\x02 \x01(\x05R\asamples\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\x95\x01\n" +
+	"\fLabelMatcher\x121\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1d.monitoring.LabelMatcher.TypeR\x04type\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\"(\n" +
+	"\x04Type\x12\x06\n" +
+	"\x02EQ\x10\x00\x12\a\n" +
+	"\x03NEQ\x10\x01\x12\x06\n" +
+	"\x02RE\x10\x02\x12\a\n" +
+	"\x03NRE\x10\x03\"\x83\x03\n" +
 	"\x11GetMetricsRequest\x12\x1d\n" +
 	"\n" +
 	"match_name\x18\x01 \x01(\tR\tmatchName\x12\x17\n" +
 	"\auser_id\x18\x02 \x01(\x03R\x06userId\x12\x1d\n" +
 	"\n" +
 	"start_time\x18\x03 \x01(\x03R\tstartTime\x12\x19\n" +
-	"\bend_time\x18\x04 \x01(\x03R\aendTime\"@\n" +
+	"\bend_time\x18\x04 \x01(\x03R\aendTime\x124\n" +
+	"\bmatchers\x18\x05 \x03(\v2\x18.monitoring.LabelMatcherR\bmatchers\x12\x12\n" +
+	"\x04step\x18\x06 \x01(\x03R\x04step\x12\x1d\n" +
+	"\n" +
+	"max_points\x18\a \x01(\x05R\tmaxPoints\x12K\n" +
+	"\vaggregation\x18\b \x01(\x0e2).monitoring.GetMetricsRequest.AggregationR\vaggregation\"F\n" +
+	"\vAggregation\x12\a\n" +
+	"\x03AVG\x10\x00\x12\a\n" +
+	"\x03MIN\x10\x01\x12\a\n" +
+	"\x03MAX\x10\x02\x12\a\n" +
+	"\x03SUM\x10\x03\x12\t\n" +
+	"\x05COUNT\x10\x04\x12\b\n" +
+	"\x04LAST\x10\x05\"\xeb\x01\n" +
+	"\x0fQuantileRequest\x12\x1d\n" +
+	"\n" +
+	"match_name\x18\x01 \x01(\tR\tmatchName\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\x12\x1d\n" +
+	"\n" +
+	"start_time\x18\x03 \x01(\x03R\tstartTime\x12\x19\n" +
+	"\bend_time\x18\x04 \x01(\x03R\aendTime\x124\n" +
+	"\bmatchers\x18\x05 \x03(\v2\x18.monitoring.LabelMatcherR\bmatchers\x12\x12\n" +
+	"\x04step\x18\x06 \x01(\x03R\x04step\x12\x1c\n" +
+	"\tquantiles\x18\a \x03(\x01R\tquantiles\"`\n" +
 	"\x12GetMetricsResponse\x12*\n" +
-	"\x04list\x18\x01 \x03(\v2\x16.monitoring.TimeSeriesR\x04list\"+\n" +
+	"\x04list\x18\x01 \x03(\v2\x16.monitoring.TimeSeriesR\x04list\x12\x1e\n" +
+	"\n" +
+	"resolution\x18\x02 \x01(\x03R\n" +
+	"resolution\"+\n" +
 	"\x10ListNamesRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\x03R\x06userId\")\n" +
 	"\x11ListNamesResponse\x12\x14\n" +
-	"\x05names\x18\x01 \x03(\tR\x05names\"+\n" +
+	"\x05names\x18\x01 \x03(\tR\x05names\"\xbb\x01\n" +
+	"\x11LabelNamesRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x1d\n" +
+	"\n" +
+	"match_name\x18\x02 \x01(\tR\tmatchName\x124\n" +
+	"\bmatchers\x18\x03 \x03(\v2\x18.monitoring.LabelMatcherR\bmatchers\x12\x1d\n" +
+	"\n" +
+	"start_time\x18\x04 \x01(\x03R\tstartTime\x12\x19\n" +
+	"\bend_time\x18\x05 \x01(\x03R\aendTime\"*\n" +
+	"\x12LabelNamesResponse\x12\x14\n" +
+	"\x05names\x18\x01 \x03(\tR\x05names\"\xdb\x01\n" +
+	"\x12LabelValuesRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x1d\n" +
+	"\n" +
+	"label_name\x18\x02 \x01(\tR\tlabelName\x12\x1d\n" +
+	"\n" +
+	"match_name\x18\x03 \x01(\tR\tmatchName\x124\n" +
+	"\bmatchers\x18\x04 \x03(\v2\x18.monitoring.LabelMatcherR\bmatchers\x12\x1d\n" +
+	"\n" +
+	"start_time\x18\x05 \x01(\x03R\tstartTime\x12\x19\n" +
+	"\bend_time\x18\x06 \x01(\x03R\aendTime\"-\n" +
+	"\x13LabelValuesResponse\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\"B\n" +
+	"\x11UsageStatsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"\xdf\x01\n" +
+	"\x12UsageStatsResponse\x12\x16\n" +
+	"\x06series\x18\x01 \x01(\x03R\x06series\x12(\n" +
+	"\x10samples_last_day\x18\x02 \x01(\x03R\x0esamplesLastDay\x12$\n" +
+	"\x0ebytes_last_day\x18\x03 \x01(\x03R\fbytesLastDay\x121\n" +
+	"\ametrics\x18\x04 \x03(\v2\x17.monitoring.MetricUsageR\ametrics\x12.\n" +
+	"\x06labels\x18\x05 \x03(\v2\x16.monitoring.LabelUsageR\x06labels\"\xc5\x01\n" +
+	"\vMetricUsage\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06series\x18\x02 \x01(\x03R\x06series\x12(\n" +
+	"\x10samples_last_day\x18\x03 \x01(\x03R\x0esamplesLastDay\x12$\n" +
+	"\x0ebytes_last_day\x18\x04 \x01(\x03R\fbytesLastDay\x12\x1d\n" +
+	"\n" +
+	"first_seen\x18\x05 \x01(\x03R\tfirstSeen\x12\x1b\n" +
+	"\tlast_seen\x18\x06 \x01(\x03R\blastSeen\"a\n" +
+	"\n" +
+	"LabelUsage\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12'\n" +
+	"\x0fdistinct_values\x18\x02 \x01(\x03R\x0edistinctValues\x12\x16\n" +
+	"\x06series\x18\x03 \x01(\x03R\x06series\"N\n" +
+	"\x12GetMetadataRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x1f\n" +
+	"\vmetric_name\x18\x02 \x01(\tR\n" +
+	"metricName\"M\n" +
+	"\x13GetMetadataResponse\x126\n" +
+	"\bmetadata\x18\x01 \x03(\v2\x1a.monitoring.MetricMetadataR\bmetadata\"+\n" +
 	"\x10VerifyKeyRequest\x12\x17\n" +
 	"\aapi_key\x18\x01 \x01(\tR\x06apiKey\"B\n" +
 	"\x11VerifyKeyResponse\x12\x14\n" +
@@ -1229,19 +3870,113 @@ const file_proto_monitoring_proto_rawDesc = "" +
 	"metricName\x12\x17\n" +
 	"\auser_id\x18\x02 \x01(\x03R\x06userId\"&\n" +
 	"\x14DeleteMetricResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok2\xd2\x05\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"\xd6\x01\n" +
+	"\x13DeleteSeriesRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x1d\n" +
+	"\n" +
+	"match_name\x18\x02 \x01(\tR\tmatchName\x124\n" +
+	"\bmatchers\x18\x03 \x03(\v2\x18.monitoring.LabelMatcherR\bmatchers\x12\x1d\n" +
+	"\n" +
+	"start_time\x18\x04 \x01(\x03R\tstartTime\x12\x19\n" +
+	"\bend_time\x18\x05 \x01(\x03R\aendTime\x12\x17\n" +
+	"\adry_run\x18\x06 \x01(\bR\x06dryRun\"W\n" +
+	"\x14DeleteSeriesResponse\x12'\n" +
+	"\x0fdeleted_samples\x18\x01 \x01(\x03R\x0edeletedSamples\x12\x16\n" +
+	"\x06series\x18\x02 \x01(\x03R\x06series\"\xc5\x01\n" +
+	"\x0fRetentionPolicy\x12\x1b\n" +
+	"\tpolicy_id\x18\x01 \x01(\x03R\bpolicyId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\x12\x1f\n" +
+	"\vmetric_name\x18\x03 \x01(\tR\n" +
+	"metricName\x124\n" +
+	"\bmatchers\x18\x04 \x03(\v2\x18.monitoring.LabelMatcherR\bmatchers\x12%\n" +
+	"\x0eretention_days\x18\x05 \x01(\x05R\rretentionDays\"\xac\x01\n" +
+	"\x13CreatePolicyRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x1f\n" +
+	"\vmetric_name\x18\x02 \x01(\tR\n" +
+	"metricName\x124\n" +
+	"\bmatchers\x18\x03 \x03(\v2\x18.monitoring.LabelMatcherR\bmatchers\x12%\n" +
+	"\x0eretention_days\x18\x04 \x01(\x05R\rretentionDays\"3\n" +
+	"\x14CreatePolicyResponse\x12\x1b\n" +
+	"\tpolicy_id\x18\x01 \x01(\x03R\bpolicyId\"-\n" +
+	"\x12GetPoliciesRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\"N\n" +
+	"\x13GetPoliciesResponse\x127\n" +
+	"\bpolicies\x18\x01 \x03(\v2\x1b.monitoring.RetentionPolicyR\bpolicies\"K\n" +
+	"\x13DeletePolicyRequest\x12\x1b\n" +
+	"\tpolicy_id\x18\x01 \x01(\x03R\bpolicyId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\"&\n" +
+	"\x14DeletePolicyResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"\xb2\x01\n" +
+	"\rArchiveRecord\x128\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x1a.monitoring.MetricMetadataH\x00R\bmetadata\x12+\n" +
+	"\x04rule\x18\x02 \x01(\v2\x15.monitoring.AlertRuleH\x00R\x04rule\x120\n" +
+	"\x06series\x18\x03 \x01(\v2\x16.monitoring.TimeSeriesH\x00R\x06seriesB\b\n" +
+	"\x06record\"(\n" +
+	"\rExportRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\"\xd7\x01\n" +
+	"\rImportRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12>\n" +
+	"\bconflict\x18\x02 \x01(\x0e2\".monitoring.ImportRequest.ConflictR\bconflict\x123\n" +
+	"\arecords\x18\x03 \x03(\v2\x19.monitoring.ArchiveRecordR\arecords\"8\n" +
+	"\bConflict\x12\x11\n" +
+	"\rKEEP_EXISTING\x10\x00\x12\r\n" +
+	"\tOVERWRITE\x10\x01\x12\n" +
+	"\n" +
+	"\x06REJECT\x10\x02\"\xe2\x02\n" +
+	"\x0eImportResponse\x12%\n" +
+	"\x0estored_samples\x18\x01 \x01(\x03R\rstoredSamples\x12+\n" +
+	"\x11duplicate_samples\x18\x02 \x01(\x03R\x10duplicateSamples\x12#\n" +
+	"\rrules_created\x18\x03 \x01(\x05R\frulesCreated\x12%\n" +
+	"\x0erules_replaced\x18\x04 \x01(\x05R\rrulesReplaced\x12#\n" +
+	"\rrules_skipped\x18\x05 \x01(\x05R\frulesSkipped\x12!\n" +
+	"\fmetadata_set\x18\x06 \x01(\x05R\vmetadataSet\x12)\n" +
+	"\x10metadata_skipped\x18\a \x01(\x05R\x0fmetadataSkipped\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x12'\n" +
+	"\x0flimited_samples\x18\t \x01(\x03R\x0elimitedSamples\"\xf9\x01\n" +
+	"\fTenantLimits\x12\x1d\n" +
+	"\n" +
+	"max_series\x18\x01 \x01(\x03R\tmaxSeries\x12-\n" +
+	"\x13max_samples_per_day\x18\x02 \x01(\x03R\x10maxSamplesPerDay\x121\n" +
+	"\x15max_labels_per_series\x18\x03 \x01(\x05R\x12maxLabelsPerSeries\x123\n" +
+	"\x16max_label_value_length\x18\x04 \x01(\x05R\x13maxLabelValueLength\x123\n" +
+	"\x16max_samples_per_second\x18\x05 \x01(\x03R\x13maxSamplesPerSecond\"S\n" +
+	"\fQuotaRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12*\n" +
+	"\x06series\x18\x02 \x03(\v2\x12.monitoring.MetricR\x06series\"\xe1\x01\n" +
+	"\rQuotaResponse\x120\n" +
+	"\x06limits\x18\x01 \x01(\v2\x18.monitoring.TenantLimitsR\x06limits\x12\x16\n" +
+	"\x06series\x18\x02 \x01(\x03R\x06series\x12#\n" +
+	"\rsamples_today\x18\x03 \x01(\x03R\fsamplesToday\x12#\n" +
+	"\rlimited_today\x18\x04 \x01(\x03R\flimitedToday\x12\x1b\n" +
+	"\tresets_at\x18\x05 \x01(\x03R\bresetsAt\x12\x1f\n" +
+	"\vover_series\x18\x06 \x03(\x05R\n" +
+	"overSeries2\xc3\x0e\n" +
 	"\x11MonitoringService\x12F\n" +
-	"\rUploadSamples\x12\x19.monitoring.UploadRequest\x1a\x1a.monitoring.UploadResponse\x12K\n" +
+	"\rUploadSamples\x12\x19.monitoring.UploadRequest\x1a\x1a.monitoring.UploadResponse\x12S\n" +
+	"\fStreamUpload\x12\x1f.monitoring.StreamUploadRequest\x1a .monitoring.StreamUploadResponse(\x01\x12K\n" +
 	"\n" +
-	"GetMetrics\x12\x1d.monitoring.GetMetricsRequest\x1a\x1e.monitoring.GetMetricsResponse\x12N\n" +
-	"\x0fListMetricNames\x12\x1c.monitoring.ListNamesRequest\x1a\x1d.monitoring.ListNamesResponse\x12H\n" +
+	"GetMetrics\x12\x1d.monitoring.GetMetricsRequest\x1a\x1e.monitoring.GetMetricsResponse\x12P\n" +
+	"\rStreamMetrics\x12\x1d.monitoring.GetMetricsRequest\x1a\x1e.monitoring.GetMetricsResponse0\x01\x12M\n" +
+	"\x0eQueryQuantiles\x12\x1b.monitoring.QuantileRequest\x1a\x1e.monitoring.GetMetricsResponse\x12N\n" +
+	"\x0fListMetricNames\x12\x1c.monitoring.ListNamesRequest\x1a\x1d.monitoring.ListNamesResponse\x12O\n" +
+	"\x0eListLabelNames\x12\x1d.monitoring.LabelNamesRequest\x1a\x1e.monitoring.LabelNamesResponse\x12R\n" +
+	"\x0fListLabelValues\x12\x1e.monitoring.LabelValuesRequest\x1a\x1f.monitoring.LabelValuesResponse\x12N\n" +
+	"\rGetUsageStats\x12\x1d.monitoring.UsageStatsRequest\x1a\x1e.monitoring.UsageStatsResponse\x12N\n" +
+	"\vGetMetadata\x12\x1e.monitoring.GetMetadataRequest\x1a\x1f.monitoring.GetMetadataResponse\x12H\n" +
 	"\tVerifyKey\x12\x1c.monitoring.VerifyKeyRequest\x1a\x1d.monitoring.VerifyKeyResponse\x12K\n" +
 	"\n" +
 	"CreateUser\x12\x1d.monitoring.CreateUserRequest\x1a\x1e.monitoring.CreateUserResponse\x12P\n" +
 	"\x0fCreateAlertRule\x12\x1d.monitoring.CreateRuleRequest\x1a\x1e.monitoring.CreateRuleResponse\x12J\n" +
 	"\rGetAlertRules\x12\x1b.monitoring.GetRulesRequest\x1a\x1c.monitoring.GetRulesResponse\x12P\n" +
 	"\x0fDeleteAlertRule\x12\x1d.monitoring.DeleteRuleRequest\x1a\x1e.monitoring.DeleteRuleResponse\x12Q\n" +
-	"\fDeleteMetric\x12\x1f.monitoring.DeleteMetricRequest\x1a .monitoring.DeleteMetricResponseB\fZ\n" +
+	"\fDeleteMetric\x12\x1f.monitoring.DeleteMetricRequest\x1a .monitoring.DeleteMetricResponse\x12Q\n" +
+	"\fDeleteSeries\x12\x1f.monitoring.DeleteSeriesRequest\x1a .monitoring.DeleteSeriesResponse\x12Z\n" +
+	"\x15CreateRetentionPolicy\x12\x1f.monitoring.CreatePolicyRequest\x1a .monitoring.CreatePolicyResponse\x12W\n" +
+	"\x14GetRetentionPolicies\x12\x1e.monitoring.GetPoliciesRequest\x1a\x1f.monitoring.GetPoliciesResponse\x12Z\n" +
+	"\x15DeleteRetentionPolicy\x12\x1f.monitoring.DeletePolicyRequest\x1a .monitoring.DeletePolicyResponse\x12F\n" +
+	"\fExportTenant\x12\x19.monitoring.ExportRequest\x1a\x19.monitoring.ArchiveRecord0\x01\x12G\n" +
+	"\fImportTenant\x12\x19.monitoring.ImportRequest\x1a\x1a.monitoring.ImportResponse(\x01\x12?\n" +
+	"\bGetQuota\x12\x18.monitoring.QuotaRequest\x1a\x19.monitoring.QuotaResponseB\fZ\n" +
 	"pmts/protob\x06proto3"
 
 var (
@@ -1256,62 +3991,153 @@ func file_proto_monitoring_proto_rawDescGZIP() []byte {
 	return file_proto_monitoring_proto_rawDescData
 }
 
-var file_proto_monitoring_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_proto_monitoring_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_proto_monitoring_proto_msgTypes = make([]protoimpl.MessageInfo, 56)
 var file_proto_monitoring_proto_goTypes = []any{
-	(*Metric)(nil),               // 0: monitoring.Metric
-	(*Sample)(nil),               // 1: monitoring.Sample
-	(*TimeSeries)(nil),           // 2: monitoring.TimeSeries
-	(*UploadRequest)(nil),        // 3: monitoring.UploadRequest
-	(*UploadResponse)(nil),       // 4: monitoring.UploadResponse
-	(*GetMetricsRequest)(nil),    // 5: monitoring.GetMetricsRequest
-	(*GetMetricsResponse)(nil),   // 6: monitoring.GetMetricsResponse
-	(*ListNamesRequest)(nil),     // 7: monitoring.ListNamesRequest
-	(*ListNamesResponse)(nil),    // 8: monitoring.ListNamesResponse
-	(*VerifyKeyRequest)(nil),     // 9: monitoring.VerifyKeyRequest
-	(*VerifyKeyResponse)(nil),    // 10: monitoring.VerifyKeyResponse
-	(*CreateUserRequest)(nil),    // 11: monitoring.CreateUserRequest
-	(*CreateUserResponse)(nil),   // 12: monitoring.CreateUserResponse
-	(*AlertRule)(nil),            // 13: monitoring.AlertRule
-	(*CreateRuleRequest)(nil),    // 14: monitoring.CreateRuleRequest
-	(*CreateRuleResponse)(nil),   // 15: monitoring.CreateRuleResponse
-	(*GetRulesRequest)(nil),      // 16: monitoring.GetRulesRequest
-	(*GetRulesResponse)(nil),     // 17: monitoring.GetRulesResponse
-	(*DeleteRuleRequest)(nil),    // 18: monitoring.DeleteRuleRequest
-	(*DeleteRuleResponse)(nil),   // 19: monitoring.DeleteRuleResponse
-	(*DeleteMetricRequest)(nil),  // 20: monitoring.DeleteMetricRequest
-	(*DeleteMetricResponse)(nil), // 21: monitoring.DeleteMetricResponse
-	nil,                          // 22: monitoring.Metric.LabelsEntry
+	(MetricMetadata_Type)(0),           // 0: monitoring.MetricMetadata.Type
+	(LabelMatcher_Type)(0),             // 1: monitoring.LabelMatcher.Type
+	(GetMetricsRequest_Aggregation)(0), // 2: monitoring.GetMetricsRequest.Aggregation
+	(ImportRequest_Conflict)(0),        // 3: monitoring.ImportRequest.Conflict
+	(*Metric)(nil),                     // 4: monitoring.Metric
+	(*Sample)(nil),                     // 5: monitoring.Sample
+	(*HistogramSample)(nil),            // 6: monitoring.HistogramSample
+	(*TimeSeries)(nil),                 // 7: monitoring.TimeSeries
+	(*MetricMetadata)(nil),             // 8: monitoring.MetricMetadata
+	(*UploadRequest)(nil),              // 9: monitoring.UploadRequest
+	(*UploadResponse)(nil),             // 10: monitoring.UploadResponse
+	(*StreamUploadRequest)(nil),        // 11: monitoring.StreamUploadRequest
+	(*StreamUploadResponse)(nil),       // 12: monitoring.StreamUploadResponse
+	(*UploadFailure)(nil),              // 13: monitoring.UploadFailure
+	(*LabelMatcher)(nil),               // 14: monitoring.LabelMatcher
+	(*GetMetricsRequest)(nil),          // 15: monitoring.GetMetricsRequest
+	(*QuantileRequest)(nil),            // 16: monitoring.QuantileRequest
+	(*GetMetricsResponse)(nil),         // 17: monitoring.GetMetricsResponse
+	(*ListNamesRequest)(nil),           // 18: monitoring.ListNamesRequest
+	(*ListNamesResponse)(nil),          // 19: monitoring.ListNamesResponse
+	(*LabelNamesRequest)(nil),          // 20: monitoring.LabelNamesRequest
+	(*LabelNamesResponse)(nil),         // 21: monitoring.LabelNamesResponse
+	(*LabelValuesRequest)(nil),         // 22: monitoring.LabelValuesRequest
+	(*LabelValuesResponse)(nil),        // 23: monitoring.LabelValuesResponse
+	(*UsageStatsRequest)(nil),          // 24: monitoring.UsageStatsRequest
+	(*UsageStatsResponse)(nil),         // 25: monitoring.UsageStatsResponse
+	(*MetricUsage)(nil),                // 26: monitoring.MetricUsage
+	(*LabelUsage)(nil),                 // 27: monitoring.LabelUsage
+	(*GetMetadataRequest)(nil),         // 28: monitoring.GetMetadataRequest
+	(*GetMetadataResponse)(nil),        // 29: monitoring.GetMetadataResponse
+	(*VerifyKeyRequest)(nil),           // 30: monitoring.VerifyKeyRequest
+	(*VerifyKeyResponse)(nil),          // 31: monitoring.VerifyKeyResponse
+	(*CreateUserRequest)(nil),          // 32: monitoring.CreateUserRequest
+	(*CreateUserResponse)(nil),         // 33: monitoring.CreateUserResponse
+	(*AlertRule)(nil),                  // 34: monitoring.AlertRule
+	(*CreateRuleRequest)(nil),          // 35: monitoring.CreateRuleRequest
+	(*CreateRuleResponse)(nil),         // 36: monitoring.CreateRuleResponse
+	(*GetRulesRequest)(nil),            // 37: monitoring.GetRulesRequest
+	(*GetRulesResponse)(nil),           // 38: monitoring.GetRulesResponse
+	(*DeleteRuleRequest)(nil),          // 39: monitoring.DeleteRuleRequest
+	(*DeleteRuleResponse)(nil),         // 40: monitoring.DeleteRuleResponse
+	(*DeleteMetricRequest)(nil),        // 41: monitoring.DeleteMetricRequest
+	(*DeleteMetricResponse)(nil),       // 42: monitoring.DeleteMetricResponse
+	(*DeleteSeriesRequest)(nil),        // 43: monitoring.DeleteSeriesRequest
+	(*DeleteSeriesResponse)(nil),       // 44: monitoring.DeleteSeriesResponse
+	(*RetentionPolicy)(nil),            // 45: monitoring.RetentionPolicy
+	(*CreatePolicyRequest)(nil),        // 46: monitoring.CreatePolicyRequest
+	(*CreatePolicyResponse)(nil),       // 47: monitoring.CreatePolicyResponse
+	(*GetPoliciesRequest)(nil),         // 48: monitoring.GetPoliciesRequest
+	(*GetPoliciesResponse)(nil),        // 49: monitoring.GetPoliciesResponse
+	(*DeletePolicyRequest)(nil),        // 50: monitoring.DeletePolicyRequest
+	(*DeletePolicyResponse)(nil),       // 51: monitoring.DeletePolicyResponse
+	(*ArchiveRecord)(nil),              // 52: monitoring.ArchiveRecord
+	(*ExportRequest)(nil),              // 53: monitoring.ExportRequest
+	(*ImportRequest)(nil),              // 54: monitoring.ImportRequest
+	(*ImportResponse)(nil),             // 55: monitoring.ImportResponse
+	(*TenantLimits)(nil),               // 56: monitoring.TenantLimits
+	(*QuotaRequest)(nil),               // 57: monitoring.QuotaRequest
+	(*QuotaResponse)(nil),              // 58: monitoring.QuotaResponse
+	nil,                                // 59: monitoring.Metric.LabelsEntry
 }
 var file_proto_monitoring_proto_depIdxs = []int32{
-	22, // 0: monitoring.Metric.labels:type_name -> monitoring.Metric.LabelsEntry
-	0,  // 1: monitoring.TimeSeries.metric:type_name -> monitoring.Metric
-	1,  // 2: monitoring.TimeSeries.samples:type_name -> monitoring.Sample
-	2,  // 3: monitoring.UploadRequest.list:type_name -> monitoring.TimeSeries
-	2,  // 4: monitoring.GetMetricsResponse.list:type_name -> monitoring.TimeSeries
-	13, // 5: monitoring.GetRulesResponse.rules:type_name -> monitoring.AlertRule
-	3,  // 6: monitoring.MonitoringService.UploadSamples:input_type -> monitoring.UploadRequest
-	5,  // 7: monitoring.MonitoringService.GetMetrics:input_type -> monitoring.GetMetricsRequest
-	7,  // 8: monitoring.MonitoringService.ListMetricNames:input_type -> monitoring.ListNamesRequest
-	9,  // 9: monitoring.MonitoringService.VerifyKey:input_type -> monitoring.VerifyKeyRequest
-	11, // 10: monitoring.MonitoringService.CreateUser:input_type -> monitoring.CreateUserRequest
-	14, // 11: monitoring.MonitoringService.CreateAlertRule:input_type -> monitoring.CreateRuleRequest
-	16, // 12: monitoring.MonitoringService.GetAlertRules:input_type -> monitoring.GetRulesRequest
-	18, // 13: monitoring.MonitoringService.DeleteAlertRule:input_type -> monitoring.DeleteRuleRequest
-	20, // 14: monitoring.MonitoringService.DeleteMetric:input_type -> monitoring.DeleteMetricRequest
-	4,  // 15: monitoring.MonitoringService.UploadSamples:output_type -> monitoring.UploadResponse
-	6,  // 16: monitoring.MonitoringService.GetMetrics:output_type -> monitoring.GetMetricsResponse
-	8,  // 17: monitoring.MonitoringService.ListMetricNames:output_type -> monitoring.ListNamesResponse
-	10, // 18: monitoring.MonitoringService.VerifyKey:output_type -> monitoring.VerifyKeyResponse
-	12, // 19: monitoring.MonitoringService.CreateUser:output_type -> monitoring.CreateUserResponse
-	15, // 20: monitoring.MonitoringService.CreateAlertRule:output_type -> monitoring.CreateRuleResponse
-	17, // 21: monitoring.MonitoringService.GetAlertRules:output_type -> monitoring.GetRulesResponse
-	19, // 22: monitoring.MonitoringService.DeleteAlertRule:output_type -> monitoring.DeleteRuleResponse
-	21, // 23: monitoring.MonitoringService.DeleteMetric:output_type -> monitoring.DeleteMetricResponse
-	15, // [15:24] is the sub-list for method output_type
-	6,  // [6:15] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	59, // 0: monitoring.Metric.labels:type_name -> monitoring.Metric.LabelsEntry
+	4,  // 1: monitoring.TimeSeries.metric:type_name -> monitoring.Metric
+	5,  // 2: monitoring.TimeSeries.samples:type_name -> monitoring.Sample
+	6,  // 3: monitoring.TimeSeries.histograms:type_name -> monitoring.HistogramSample
+	0,  // 4: monitoring.MetricMetadata.type:type_name -> monitoring.MetricMetadata.Type
+	7,  // 5: monitoring.UploadRequest.list:type_name -> monitoring.TimeSeries
+	8,  // 6: monitoring.UploadRequest.metadata:type_name -> monitoring.MetricMetadata
+	7,  // 7: monitoring.StreamUploadRequest.list:type_name -> monitoring.TimeSeries
+	13, // 8: monitoring.StreamUploadResponse.failures:type_name -> monitoring.UploadFailure
+	1,  // 9: monitoring.LabelMatcher.type:type_name -> monitoring.LabelMatcher.Type
+	14, // 10: monitoring.GetMetricsRequest.matchers:type_name -> monitoring.LabelMatcher
+	2,  // 11: monitoring.GetMetricsRequest.aggregation:type_name -> monitoring.GetMetricsRequest.Aggregation
+	14, // 12: monitoring.QuantileRequest.matchers:type_name -> monitoring.LabelMatcher
+	7,  // 13: monitoring.GetMetricsResponse.list:type_name -> monitoring.TimeSeries
+	14, // 14: monitoring.LabelNamesRequest.matchers:type_name -> monitoring.LabelMatcher
+	14, // 15: monitoring.LabelValuesRequest.matchers:type_name -> monitoring.LabelMatcher
+	26, // 16: monitoring.UsageStatsResponse.metrics:type_name -> monitoring.MetricUsage
+	27, // 17: monitoring.UsageStatsResponse.labels:type_name -> monitoring.LabelUsage
+	8,  // 18: monitoring.GetMetadataResponse.metadata:type_name -> monitoring.MetricMetadata
+	34, // 19: monitoring.GetRulesResponse.rules:type_name -> monitoring.AlertRule
+	14, // 20: monitoring.DeleteSeriesRequest.matchers:type_name -> monitoring.LabelMatcher
+	14, // 21: monitoring.RetentionPolicy.matchers:type_name -> monitoring.LabelMatcher
+	14, // 22: monitoring.CreatePolicyRequest.matchers:type_name -> monitoring.LabelMatcher
+	45, // 23: monitoring.GetPoliciesResponse.policies:type_name -> monitoring.RetentionPolicy
+	8,  // 24: monitoring.ArchiveRecord.metadata:type_name -> monitoring.MetricMetadata
+	34, // 25: monitoring.ArchiveRecord.rule:type_name -> monitoring.AlertRule
+	7,  // 26: monitoring.ArchiveRecord.series:type_name -> monitoring.TimeSeries
+	3,  // 27: monitoring.ImportRequest.conflict:type_name -> monitoring.ImportRequest.Conflict
+	52, // 28: monitoring.ImportRequest.records:type_name -> monitoring.ArchiveRecord
+	4,  // 29: monitoring.QuotaRequest.series:type_name -> monitoring.Metric
+	56, // 30: monitoring.QuotaResponse.limits:type_name -> monitoring.TenantLimits
+	9,  // 31: monitoring.MonitoringService.UploadSamples:input_type -> monitoring.UploadRequest
+	11, // 32: monitoring.MonitoringService.StreamUpload:input_type -> monitoring.StreamUploadRequest
+	15, // 33: monitoring.MonitoringService.GetMetrics:input_type -> monitoring.GetMetricsRequest
+	15, // 34: monitoring.MonitoringService.StreamMetrics:input_type -> monitoring.GetMetricsRequest
+	16, // 35: monitoring.MonitoringService.QueryQuantiles:input_type -> monitoring.QuantileRequest
+	18, // 36: monitoring.MonitoringService.ListMetricNames:input_type -> monitoring.ListNamesRequest
+	20, // 37: monitoring.MonitoringService.ListLabelNames:input_type -> monitoring.LabelNamesRequest
+	22, // 38: monitoring.MonitoringService.ListLabelValues:input_type -> monitoring.LabelValuesRequest
+	24, // 39: monitoring.MonitoringService.GetUsageStats:input_type -> monitoring.UsageStatsRequest
+	28, // 40: monitoring.MonitoringService.GetMetadata:input_type -> monitoring.GetMetadataRequest
+	30, // 41: monitoring.MonitoringService.VerifyKey:input_type -> monitoring.VerifyKeyRequest
+	32, // 42: monitoring.MonitoringService.CreateUser:input_type -> monitoring.CreateUserRequest
+	35, // 43: monitoring.MonitoringService.CreateAlertRule:input_type -> monitoring.CreateRuleRequest
+	37, // 44: monitoring.MonitoringService.GetAlertRules:input_type -> monitoring.GetRulesRequest
+	39, // 45: monitoring.MonitoringService.DeleteAlertRule:input_type -> monitoring.DeleteRuleRequest
+	41, // 46: monitoring.MonitoringService.DeleteMetric:input_type -> monitoring.DeleteMetricRequest
+	43, // 47: monitoring.MonitoringService.DeleteSeries:input_type -> monitoring.DeleteSeriesRequest
+	46, // 48: monitoring.MonitoringService.CreateRetentionPolicy:input_type -> monitoring.CreatePolicyRequest
+	48, // 49: monitoring.MonitoringService.GetRetentionPolicies:input_type -> monitoring.GetPoliciesRequest
+	50, // 50: monitoring.MonitoringService.DeleteRetentionPolicy:input_type -> monitoring.DeletePolicyRequest
+	53, // 51: monitoring.MonitoringService.ExportTenant:input_type -> monitoring.ExportRequest
+	54, // 52: monitoring.MonitoringService.ImportTenant:input_type -> monitoring.ImportRequest
+	57, // 53: monitoring.MonitoringService.GetQuota:input_type -> monitoring.QuotaRequest
+	10, // 54: monitoring.MonitoringService.UploadSamples:output_type -> monitoring.UploadResponse
+	12, // 55: monitoring.MonitoringService.StreamUpload:output_type -> monitoring.StreamUploadResponse
+	17, // 56: monitoring.MonitoringService.GetMetrics:output_type -> monitoring.GetMetricsResponse
+	17, // 57: monitoring.MonitoringService.StreamMetrics:output_type -> monitoring.GetMetricsResponse
+	17, // 58: monitoring.MonitoringService.QueryQuantiles:output_type -> monitoring.GetMetricsResponse
+	19, // 59: monitoring.MonitoringService.ListMetricNames:output_type -> monitoring.ListNamesResponse
+	21, // 60: monitoring.MonitoringService.ListLabelNames:output_type -> monitoring.LabelNamesResponse
+	23, // 61: monitoring.MonitoringService.ListLabelValues:output_type -> monitoring.LabelValuesResponse
+	25, // 62: monitoring.MonitoringService.GetUsageStats:output_type -> monitoring.UsageStatsResponse
+	29, // 63: monitoring.MonitoringService.GetMetadata:output_type -> monitoring.GetMetadataResponse
+	31, // 64: monitoring.MonitoringService.VerifyKey:output_type -> monitoring.VerifyKeyResponse
+	33, // 65: monitoring.MonitoringService.CreateUser:output_type -> monitoring.CreateUserResponse
+	36, // 66: monitoring.MonitoringService.CreateAlertRule:output_type -> monitoring.CreateRuleResponse
+	38, // 67: monitoring.MonitoringService.GetAlertRules:output_type -> monitoring.GetRulesResponse
+	40, // 68: monitoring.MonitoringService.DeleteAlertRule:output_type -> monitoring.DeleteRuleResponse
+	42, // 69: monitoring.MonitoringService.DeleteMetric:output_type -> monitoring.DeleteMetricResponse
+	44, // 70: monitoring.MonitoringService.DeleteSeries:output_type -> monitoring.DeleteSeriesResponse
+	47, // 71: monitoring.MonitoringService.CreateRetentionPolicy:output_type -> monitoring.CreatePolicyResponse
+	49, // 72: monitoring.MonitoringService.GetRetentionPolicies:output_type -> monitoring.GetPoliciesResponse
+	51, // 73: monitoring.MonitoringService.DeleteRetentionPolicy:output_type -> monitoring.DeletePolicyResponse
+	52, // 74: monitoring.MonitoringService.ExportTenant:output_type -> monitoring.ArchiveRecord
+	55, // 75: monitoring.MonitoringService.ImportTenant:output_type -> monitoring.ImportResponse
+	58, // 76: monitoring.MonitoringService.GetQuota:output_type -> monitoring.QuotaResponse
+	54, // [54:77] is the sub-list for method output_type
+	31, // [31:54] is the sub-list for method input_type
+	31, // [31:31] is the sub-list for extension type_name
+	31, // [31:31] is the sub-list for extension extendee
+	0,  // [0:31] is the sub-list for field type_name
 }
 
 func init() { file_proto_monitoring_proto_init() }
@@ -1319,18 +4145,24 @@ func file_proto_monitoring_proto_init() {
 	if File_proto_monitoring_proto != nil {
 		return
 	}
+	file_proto_monitoring_proto_msgTypes[48].OneofWrappers = []any{
+		(*ArchiveRecord_Metadata)(nil),
+		(*ArchiveRecord_Rule)(nil),
+		(*ArchiveRecord_Series)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_monitoring_proto_rawDesc), len(file_proto_monitoring_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   23,
+			NumEnums:      4,
+			NumMessages:   56,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_monitoring_proto_goTypes,
 		DependencyIndexes: file_proto_monitoring_proto_depIdxs,
+		EnumInfos:         file_proto_monitoring_proto_enumTypes,
 		MessageInfos:      file_proto_monitoring_proto_msgTypes,
 	}.Build()
 	File_proto_monitoring_proto = out.File
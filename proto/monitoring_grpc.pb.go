@@ -19,15 +19,29 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MonitoringService_UploadSamples_FullMethodName   = "/monitoring.MonitoringService/UploadSamples"
-	MonitoringService_GetMetrics_FullMethodName      = "/monitoring.MonitoringService/GetMetrics"
-	MonitoringService_ListMetricNames_FullMethodName = "/monitoring.MonitoringService/ListMetricNames"
-	MonitoringService_VerifyKey_FullMethodName       = "/monitoring.MonitoringService/VerifyKey"
-	MonitoringService_CreateUser_FullMethodName      = "/monitoring.MonitoringService/CreateUser"
-	MonitoringService_CreateAlertRule_FullMethodName = "/monitoring.MonitoringService/CreateAlertRule"
-	MonitoringService_GetAlertRules_FullMethodName   = "/monitoring.MonitoringService/GetAlertRules"
-	MonitoringService_DeleteAlertRule_FullMethodName = "/monitoring.MonitoringService/DeleteAlertRule"
-	MonitoringService_DeleteMetric_FullMethodName    = "/monitoring.MonitoringService/DeleteMetric"
+	MonitoringService_UploadSamples_FullMethodName         = "/monitoring.MonitoringService/UploadSamples"
+	MonitoringService_StreamUpload_FullMethodName          = "/monitoring.MonitoringService/StreamUpload"
+	MonitoringService_GetMetrics_FullMethodName            = "/monitoring.MonitoringService/GetMetrics"
+	MonitoringService_StreamMetrics_FullMethodName         = "/monitoring.MonitoringService/StreamMetrics"
+	MonitoringService_QueryQuantiles_FullMethodName        = "/monitoring.MonitoringService/QueryQuantiles"
+	MonitoringService_ListMetricNames_FullMethodName       = "/monitoring.MonitoringService/ListMetricNames"
+	MonitoringService_ListLabelNames_FullMethodName        = "/monitoring.MonitoringService/ListLabelNames"
+	MonitoringService_ListLabelValues_FullMethodName       = "/monitoring.MonitoringService/ListLabelValues"
+	MonitoringService_GetUsageStats_FullMethodName         = "/monitoring.MonitoringService/GetUsageStats"
+	MonitoringService_GetMetadata_FullMethodName           = "/monitoring.MonitoringService/GetMetadata"
+	MonitoringService_VerifyKey_FullMethodName             = "/monitoring.MonitoringService/VerifyKey"
+	MonitoringService_CreateUser_FullMethodName            = "/monitoring.MonitoringService/CreateUser"
+	MonitoringService_CreateAlertRule_FullMethodName       = "/monitoring.MonitoringService/CreateAlertRule"
+	MonitoringService_GetAlertRules_FullMethodName         = "/monitoring.MonitoringService/GetAlertRules"
+	MonitoringService_DeleteAlertRule_FullMethodName       = "/monitoring.MonitoringService/DeleteAlertRule"
+	MonitoringService_DeleteMetric_FullMethodName          = "/monitoring.MonitoringService/DeleteMetric"
+	MonitoringService_DeleteSeries_FullMethodName          = "/monitoring.MonitoringService/DeleteSeries"
+	MonitoringService_CreateRetentionPolicy_FullMethodName = "/monitoring.MonitoringService/CreateRetentionPolicy"
+	MonitoringService_GetRetentionPolicies_FullMethodName  = "/monitoring.MonitoringService/GetRetentionPolicies"
+	MonitoringService_DeleteRetentionPolicy_FullMethodName = "/monitoring.MonitoringService/DeleteRetentionPolicy"
+	MonitoringService_ExportTenant_FullMethodName          = "/monitoring.MonitoringService/ExportTenant"
+	MonitoringService_ImportTenant_FullMethodName          = "/monitoring.MonitoringService/ImportTenant"
+	MonitoringService_GetQuota_FullMethodName              = "/monitoring.MonitoringService/GetQuota"
 )
 
 // MonitoringServiceClient is the client API for MonitoringService service.
@@ -35,14 +49,28 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MonitoringServiceClient interface {
 	UploadSamples(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*UploadResponse, error)
+	StreamUpload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[StreamUploadRequest, StreamUploadResponse], error)
 	GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error)
+	StreamMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetMetricsResponse], error)
+	QueryQuantiles(ctx context.Context, in *QuantileRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error)
 	ListMetricNames(ctx context.Context, in *ListNamesRequest, opts ...grpc.CallOption) (*ListNamesResponse, error)
+	ListLabelNames(ctx context.Context, in *LabelNamesRequest, opts ...grpc.CallOption) (*LabelNamesResponse, error)
+	ListLabelValues(ctx context.Context, in *LabelValuesRequest, opts ...grpc.CallOption) (*LabelValuesResponse, error)
+	GetUsageStats(ctx context.Context, in *UsageStatsRequest, opts ...grpc.CallOption) (*UsageStatsResponse, error)
+	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*GetMetadataResponse, error)
 	VerifyKey(ctx context.Context, in *VerifyKeyRequest, opts ...grpc.CallOption) (*VerifyKeyResponse, error)
 	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error)
 	CreateAlertRule(ctx context.Context, in *CreateRuleRequest, opts ...grpc.CallOption) (*CreateRuleResponse, error)
 	GetAlertRules(ctx context.Context, in *GetRulesRequest, opts ...grpc.CallOption) (*GetRulesResponse, error)
 	DeleteAlertRule(ctx context.Context, in *DeleteRuleRequest, opts ...grpc.CallOption) (*DeleteRuleResponse, error)
 	DeleteMetric(ctx context.Context, in *DeleteMetricRequest, opts ...grpc.CallOption) (*DeleteMetricResponse, error)
+	DeleteSeries(ctx context.Context, in *DeleteSeriesRequest, opts ...grpc.CallOption) (*DeleteSeriesResponse, error)
+	CreateRetentionPolicy(ctx context.Context, in *CreatePolicyRequest, opts ...grpc.CallOption) (*CreatePolicyResponse, error)
+	GetRetentionPolicies(ctx context.Context, in *GetPoliciesRequest, opts ...grpc.CallOption) (*GetPoliciesResponse, error)
+	DeleteRetentionPolicy(ctx context.Context, in *DeletePolicyRequest, opts ...grpc.CallOption) (*DeletePolicyResponse, error)
+	ExportTenant(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ArchiveRecord], error)
+	ImportTenant(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportRequest, ImportResponse], error)
+	GetQuota(ctx context.Context, in *QuotaRequest, opts ...grpc.CallOption) (*QuotaResponse, error)
 }
 
 type monitoringServiceClient struct {
@@ -63,6 +91,19 @@ func (c *monitoringServiceClient) UploadSamples(ctx context.Context, in *UploadR
 	return out, nil
 }
 
+func (c *monitoringServiceClient) StreamUpload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[StreamUploadRequest, StreamUploadResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MonitoringService_ServiceDesc.Streams[0], MonitoringService_StreamUpload_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamUploadRequest, StreamUploadResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MonitoringService_StreamUploadClient = grpc.ClientStreamingClient[StreamUploadRequest, StreamUploadResponse]
+
 func (c *monitoringServiceClient) GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetMetricsResponse)
@@ -73,6 +114,35 @@ func (c *monitoringServiceClient) GetMetrics(ctx context.Context, in *GetMetrics
 	return out, nil
 }
 
+func (c *monitoringServiceClient) StreamMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetMetricsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MonitoringService_ServiceDesc.Streams[1], MonitoringService_StreamMetrics_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetMetricsRequest, GetMetricsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MonitoringService_StreamMetricsClient = grpc.ServerStreamingClient[GetMetricsResponse]
+
+func (c *monitoringServiceClient) QueryQuantiles(ctx context.Context, in *QuantileRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMetricsResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_QueryQuantiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *monitoringServiceClient) ListMetricNames(ctx context.Context, in *ListNamesRequest, opts ...grpc.CallOption) (*ListNamesResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ListNamesResponse)
@@ -83,6 +153,46 @@ func (c *monitoringServiceClient) ListMetricNames(ctx context.Context, in *ListN
 	return out, nil
 }
 
+func (c *monitoringServiceClient) ListLabelNames(ctx context.Context, in *LabelNamesRequest, opts ...grpc.CallOption) (*LabelNamesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LabelNamesResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_ListLabelNames_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitoringServiceClient) ListLabelValues(ctx context.Context, in *LabelValuesRequest, opts ...grpc.CallOption) (*LabelValuesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LabelValuesResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_ListLabelValues_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitoringServiceClient) GetUsageStats(ctx context.Context, in *UsageStatsRequest, opts ...grpc.CallOption) (*UsageStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UsageStatsResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_GetUsageStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitoringServiceClient) GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*GetMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMetadataResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_GetMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *monitoringServiceClient) VerifyKey(ctx context.Context, in *VerifyKeyRequest, opts ...grpc.CallOption) (*VerifyKeyResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(VerifyKeyResponse)
@@ -143,19 +253,115 @@ func (c *monitoringServiceClient) DeleteMetric(ctx context.Context, in *DeleteMe
 	return out, nil
 }
 
+func (c *monitoringServiceClient) DeleteSeries(ctx context.Context, in *DeleteSeriesRequest, opts ...grpc.CallOption) (*DeleteSeriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSeriesResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_DeleteSeries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitoringServiceClient) CreateRetentionPolicy(ctx context.Context, in *CreatePolicyRequest, opts ...grpc.CallOption) (*CreatePolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreatePolicyResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_CreateRetentionPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitoringServiceClient) GetRetentionPolicies(ctx context.Context, in *GetPoliciesRequest, opts ...grpc.CallOption) (*GetPoliciesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPoliciesResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_GetRetentionPolicies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitoringServiceClient) DeleteRetentionPolicy(ctx context.Context, in *DeletePolicyRequest, opts ...grpc.CallOption) (*DeletePolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeletePolicyResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_DeleteRetentionPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitoringServiceClient) ExportTenant(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ArchiveRecord], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MonitoringService_ServiceDesc.Streams[2], MonitoringService_ExportTenant_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportRequest, ArchiveRecord]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MonitoringService_ExportTenantClient = grpc.ServerStreamingClient[ArchiveRecord]
+
+func (c *monitoringServiceClient) ImportTenant(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportRequest, ImportResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MonitoringService_ServiceDesc.Streams[3], MonitoringService_ImportTenant_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImportRequest, ImportResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MonitoringService_ImportTenantClient = grpc.ClientStreamingClient[ImportRequest, ImportResponse]
+
+func (c *monitoringServiceClient) GetQuota(ctx context.Context, in *QuotaRequest, opts ...grpc.CallOption) (*QuotaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuotaResponse)
+	err := c.cc.Invoke(ctx, MonitoringService_GetQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MonitoringServiceServer is the server API for MonitoringService service.
 // All implementations must embed UnimplementedMonitoringServiceServer
 // for forward compatibility.
 type MonitoringServiceServer interface {
 	UploadSamples(context.Context, *UploadRequest) (*UploadResponse, error)
+	StreamUpload(grpc.ClientStreamingServer[StreamUploadRequest, StreamUploadResponse]) error
 	GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error)
+	StreamMetrics(*GetMetricsRequest, grpc.ServerStreamingServer[GetMetricsResponse]) error
+	QueryQuantiles(context.Context, *QuantileRequest) (*GetMetricsResponse, error)
 	ListMetricNames(context.Context, *ListNamesRequest) (*ListNamesResponse, error)
+	ListLabelNames(context.Context, *LabelNamesRequest) (*LabelNamesResponse, error)
+	ListLabelValues(context.Context, *LabelValuesRequest) (*LabelValuesResponse, error)
+	GetUsageStats(context.Context, *UsageStatsRequest) (*UsageStatsResponse, error)
+	GetMetadata(context.Context, *GetMetadataRequest) (*GetMetadataResponse, error)
 	VerifyKey(context.Context, *VerifyKeyRequest) (*VerifyKeyResponse, error)
 	CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error)
 	CreateAlertRule(context.Context, *CreateRuleRequest) (*CreateRuleResponse, error)
 	GetAlertRules(context.Context, *GetRulesRequest) (*GetRulesResponse, error)
 	DeleteAlertRule(context.Context, *DeleteRuleRequest) (*DeleteRuleResponse, error)
 	DeleteMetric(context.Context, *DeleteMetricRequest) (*DeleteMetricResponse, error)
+	DeleteSeries(context.Context, *DeleteSeriesRequest) (*DeleteSeriesResponse, error)
+	CreateRetentionPolicy(context.Context, *CreatePolicyRequest) (*CreatePolicyResponse, error)
+	GetRetentionPolicies(context.Context, *GetPoliciesRequest) (*GetPoliciesResponse, error)
+	DeleteRetentionPolicy(context.Context, *DeletePolicyRequest) (*DeletePolicyResponse, error)
+	ExportTenant(*ExportRequest, grpc.ServerStreamingServer[ArchiveRecord]) error
+	ImportTenant(grpc.ClientStreamingServer[ImportRequest, ImportResponse]) error
+	GetQuota(context.Context, *QuotaRequest) (*QuotaResponse, error)
 	mustEmbedUnimplementedMonitoringServiceServer()
 }
 
@@ -169,12 +375,33 @@ type UnimplementedMonitoringServiceServer struct{}
 func (UnimplementedMonitoringServiceServer) UploadSamples(context.Context, *UploadRequest) (*UploadResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method UploadSamples not implemented")
 }
+func (UnimplementedMonitoringServiceServer) StreamUpload(grpc.ClientStreamingServer[StreamUploadRequest, StreamUploadResponse]) error {
+	return status.Error(codes.Unimplemented, "method StreamUpload not implemented")
+}
 func (UnimplementedMonitoringServiceServer) GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetMetrics not implemented")
 }
+func (UnimplementedMonitoringServiceServer) StreamMetrics(*GetMetricsRequest, grpc.ServerStreamingServer[GetMetricsResponse]) error {
+	return status.Error(codes.Unimplemented, "method StreamMetrics not implemented")
+}
+func (UnimplementedMonitoringServiceServer) QueryQuantiles(context.Context, *QuantileRequest) (*GetMetricsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method QueryQuantiles not implemented")
+}
 func (UnimplementedMonitoringServiceServer) ListMetricNames(context.Context, *ListNamesRequest) (*ListNamesResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ListMetricNames not implemented")
 }
+func (UnimplementedMonitoringServiceServer) ListLabelNames(context.Context, *LabelNamesRequest) (*LabelNamesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListLabelNames not implemented")
+}
+func (UnimplementedMonitoringServiceServer) ListLabelValues(context.Context, *LabelValuesRequest) (*LabelValuesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListLabelValues not implemented")
+}
+func (UnimplementedMonitoringServiceServer) GetUsageStats(context.Context, *UsageStatsRequest) (*UsageStatsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetUsageStats not implemented")
+}
+func (UnimplementedMonitoringServiceServer) GetMetadata(context.Context, *GetMetadataRequest) (*GetMetadataResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetMetadata not implemented")
+}
 func (UnimplementedMonitoringServiceServer) VerifyKey(context.Context, *VerifyKeyRequest) (*VerifyKeyResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method VerifyKey not implemented")
 }
@@ -193,6 +420,27 @@ func (UnimplementedMonitoringServiceServer) DeleteAlertRule(context.Context, *De
 func (UnimplementedMonitoringServiceServer) DeleteMetric(context.Context, *DeleteMetricRequest) (*DeleteMetricResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method DeleteMetric not implemented")
 }
+func (UnimplementedMonitoringServiceServer) DeleteSeries(context.Context, *DeleteSeriesRequest) (*DeleteSeriesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteSeries not implemented")
+}
+func (UnimplementedMonitoringServiceServer) CreateRetentionPolicy(context.Context, *CreatePolicyRequest) (*CreatePolicyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateRetentionPolicy not implemented")
+}
+func (UnimplementedMonitoringServiceServer) GetRetentionPolicies(context.Context, *GetPoliciesRequest) (*GetPoliciesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRetentionPolicies not implemented")
+}
+func (UnimplementedMonitoringServiceServer) DeleteRetentionPolicy(context.Context, *DeletePolicyRequest) (*DeletePolicyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteRetentionPolicy not implemented")
+}
+func (UnimplementedMonitoringServiceServer) ExportTenant(*ExportRequest, grpc.ServerStreamingServer[ArchiveRecord]) error {
+	return status.Error(codes.Unimplemented, "method ExportTenant not implemented")
+}
+func (UnimplementedMonitoringServiceServer) ImportTenant(grpc.ClientStreamingServer[ImportRequest, ImportResponse]) error {
+	return status.Error(codes.Unimplemented, "method ImportTenant not implemented")
+}
+func (UnimplementedMonitoringServiceServer) GetQuota(context.Context, *QuotaRequest) (*QuotaResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetQuota not implemented")
+}
 func (UnimplementedMonitoringServiceServer) mustEmbedUnimplementedMonitoringServiceServer() {}
 func (UnimplementedMonitoringServiceServer) testEmbeddedByValue()                           {}
 
@@ -232,6 +480,13 @@ func _MonitoringService_UploadSamples_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MonitoringService_StreamUpload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MonitoringServiceServer).StreamUpload(&grpc.GenericServerStream[StreamUploadRequest, StreamUploadResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MonitoringService_StreamUploadServer = grpc.ClientStreamingServer[StreamUploadRequest, StreamUploadResponse]
+
 func _MonitoringService_GetMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetMetricsRequest)
 	if err := dec(in); err != nil {
@@ -250,6 +505,35 @@ func _MonitoringService_GetMetrics_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MonitoringService_StreamMetrics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetMetricsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MonitoringServiceServer).StreamMetrics(m, &grpc.GenericServerStream[GetMetricsRequest, GetMetricsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MonitoringService_StreamMetricsServer = grpc.ServerStreamingServer[GetMetricsResponse]
+
+func _MonitoringService_QueryQuantiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuantileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).QueryQuantiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_QueryQuantiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).QueryQuantiles(ctx, req.(*QuantileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _MonitoringService_ListMetricNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListNamesRequest)
 	if err := dec(in); err != nil {
@@ -268,6 +552,78 @@ func _MonitoringService_ListMetricNames_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MonitoringService_ListLabelNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LabelNamesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).ListLabelNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_ListLabelNames_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).ListLabelNames(ctx, req.(*LabelNamesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MonitoringService_ListLabelValues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LabelValuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).ListLabelValues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_ListLabelValues_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).ListLabelValues(ctx, req.(*LabelValuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MonitoringService_GetUsageStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UsageStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).GetUsageStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_GetUsageStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).GetUsageStats(ctx, req.(*UsageStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MonitoringService_GetMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).GetMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_GetMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).GetMetadata(ctx, req.(*GetMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _MonitoringService_VerifyKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(VerifyKeyRequest)
 	if err := dec(in); err != nil {
@@ -376,6 +732,114 @@ func _MonitoringService_DeleteMetric_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MonitoringService_DeleteSeries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).DeleteSeries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_DeleteSeries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).DeleteSeries(ctx, req.(*DeleteSeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MonitoringService_CreateRetentionPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).CreateRetentionPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_CreateRetentionPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).CreateRetentionPolicy(ctx, req.(*CreatePolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MonitoringService_GetRetentionPolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).GetRetentionPolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_GetRetentionPolicies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).GetRetentionPolicies(ctx, req.(*GetPoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MonitoringService_DeleteRetentionPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).DeleteRetentionPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_DeleteRetentionPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).DeleteRetentionPolicy(ctx, req.(*DeletePolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MonitoringService_ExportTenant_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MonitoringServiceServer).ExportTenant(m, &grpc.GenericServerStream[ExportRequest, ArchiveRecord]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MonitoringService_ExportTenantServer = grpc.ServerStreamingServer[ArchiveRecord]
+
+func _MonitoringService_ImportTenant_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MonitoringServiceServer).ImportTenant(&grpc.GenericServerStream[ImportRequest, ImportResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MonitoringService_ImportTenantServer = grpc.ClientStreamingServer[ImportRequest, ImportResponse]
+
+func _MonitoringService_GetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitoringServiceServer).GetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitoringService_GetQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitoringServiceServer).GetQuota(ctx, req.(*QuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MonitoringService_ServiceDesc is the grpc.ServiceDesc for MonitoringService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -391,10 +855,30 @@ var MonitoringService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMetrics",
 			Handler:    _MonitoringService_GetMetrics_Handler,
 		},
+		{
+			MethodName: "QueryQuantiles",
+			Handler:    _MonitoringService_QueryQuantiles_Handler,
+		},
 		{
 			MethodName: "ListMetricNames",
 			Handler:    _MonitoringService_ListMetricNames_Handler,
 		},
+		{
+			MethodName: "ListLabelNames",
+			Handler:    _MonitoringService_ListLabelNames_Handler,
+		},
+		{
+			MethodName: "ListLabelValues",
+			Handler:    _MonitoringService_ListLabelValues_Handler,
+		},
+		{
+			MethodName: "GetUsageStats",
+			Handler:    _MonitoringService_GetUsageStats_Handler,
+		},
+		{
+			MethodName: "GetMetadata",
+			Handler:    _MonitoringService_GetMetadata_Handler,
+		},
 		{
 			MethodName: "VerifyKey",
 			Handler:    _MonitoringService_VerifyKey_Handler,
@@ -419,7 +903,48 @@ var MonitoringService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteMetric",
 			Handler:    _MonitoringService_DeleteMetric_Handler,
 		},
+		{
+			MethodName: "DeleteSeries",
+			Handler:    _MonitoringService_DeleteSeries_Handler,
+		},
+		{
+			MethodName: "CreateRetentionPolicy",
+			Handler:    _MonitoringService_CreateRetentionPolicy_Handler,
+		},
+		{
+			MethodName: "GetRetentionPolicies",
+			Handler:    _MonitoringService_GetRetentionPolicies_Handler,
+		},
+		{
+			MethodName: "DeleteRetentionPolicy",
+			Handler:    _MonitoringService_DeleteRetentionPolicy_Handler,
+		},
+		{
+			MethodName: "GetQuota",
+			Handler:    _MonitoringService_GetQuota_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamUpload",
+			Handler:       _MonitoringService_StreamUpload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "StreamMetrics",
+			Handler:       _MonitoringService_StreamMetrics_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportTenant",
+			Handler:       _MonitoringService_ExportTenant_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportTenant",
+			Handler:       _MonitoringService_ImportTenant_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/monitoring.proto",
 }
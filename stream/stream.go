@@ -0,0 +1,82 @@
+// Package stream holds the JetStream stream batches travel on from the
+// gateway to the storage and alert services, and the redelivery settings
+// their consumers share.
+//
+// Each service reads the stream through a durable consumer of its own, so a
+// batch waits for every one of them and is redelivered to a worker that
+// fails or dies before acknowledging it.
+package stream
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	Name          = "METRICS"
+	UploadSubject = "metrics.upload"
+	// ReplaySubject carries dead-lettered batches being replayed. Only
+	// storage consumes it, so old data does not fire alerts.
+	ReplaySubject = "metrics.replay"
+	// MaxAge bounds how long a batch waits for a consumer that is down.
+	MaxAge = 24 * time.Hour
+)
+
+// Ensure creates the stream, or updates it to this config. Every service
+// does so at startup, whichever comes up first, which is why the config
+// lives here rather than in any one of them.
+func Ensure(ctx context.Context, js jetstream.JetStream) (jetstream.Stream, error) {
+	return js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     Name,
+		Subjects: []string{UploadSubject, ReplaySubject},
+		Storage:  jetstream.FileStorage,
+		MaxAge:   MaxAge,
+	})
+}
+
+// Redelivery is how a consumer retries batches it failed to process:
+// NATS_MAX_DELIVER attempts in all (default 10), waiting the NATS_BACKOFF
+// delays between them (a comma-separated list of durations whose last entry
+// repeats), and redelivering after NATS_ACK_WAIT if a worker goes quiet.
+type Redelivery struct {
+	MaxDeliver int
+	AckWait    time.Duration
+	Backoff    []time.Duration
+}
+
+func RedeliveryFromEnv() (Redelivery, error) {
+	r := Redelivery{
+		MaxDeliver: 10,
+		AckWait:    30 * time.Second,
+	}
+	if n, err := strconv.Atoi(os.Getenv("NATS_MAX_DELIVER")); err == nil && n > 0 {
+		r.MaxDeliver = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("NATS_ACK_WAIT")); err == nil && d > 0 {
+		r.AckWait = d
+	}
+	spec := os.Getenv("NATS_BACKOFF")
+	if spec == "" {
+		spec = "1s,5s,30s,2m"
+	}
+	for _, field := range strings.Split(spec, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil || d <= 0 {
+			return r, fmt.Errorf("invalid NATS_BACKOFF %q: expected positive durations such as 1s,5s,30s", spec)
+		}
+		r.Backoff = append(r.Backoff, d)
+	}
+	return r, nil
+}
+
+// Delay is how long to wait before redelivering a batch that failed on
+// the given attempt, counting from 1.
+func (r Redelivery) Delay(attempt uint64) time.Duration {
+	return r.Backoff[min(int(attempt), len(r.Backoff))-1]
+}
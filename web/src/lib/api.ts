@@ -62,22 +62,109 @@ export async function verifyKey(key: string): Promise<boolean> {
 // ── Metrics ───────────────────────────────────────────────────────────────────
 
 export interface Sample { t: number; v: number }
-export interface Metric { name: string; samples: Sample[] }
+export interface Metric { name: string; labels: Record<string, string>; samples: Sample[] }
 
 export async function getMetricNames(): Promise<string[]> {
 	return get<string[]>('/api/metrics/names');
 }
 
-export async function getMetrics(opts?: { name?: string; from?: number; to?: number }): Promise<Metric[]> {
+export async function getMetrics(opts?: {
+	name?: string;
+	match?: string;
+	from?: number;
+	to?: number;
+	step?: number;
+	maxPoints?: number;
+	agg?: 'avg' | 'min' | 'max' | 'sum' | 'count' | 'last';
+}): Promise<Metric[]> {
 	const p = new URLSearchParams();
 	if (opts?.name) p.set('name', opts.name);
+	if (opts?.match) p.set('match', opts.match);
 	if (opts?.from) p.set('from', String(opts.from));
 	if (opts?.to) p.set('to', String(opts.to));
+	if (opts?.step) p.set('step', String(opts.step));
+	if (opts?.maxPoints) p.set('max_points', String(opts.maxPoints));
+	if (opts?.agg) p.set('agg', opts.agg);
 	const qs = p.toString() ? '?' + p.toString() : '';
 	const data = await get<Metric[]>('/api/metrics' + qs);
 	return data ?? [];
 }
 
+// Quantiles estimated from histogram metrics, one series per histogram
+// series and quantile (in the "quantile" label).
+export async function getQuantiles(opts: {
+	name?: string;
+	match?: string;
+	from?: number;
+	to?: number;
+	step?: number;
+	quantiles?: number[];
+}): Promise<Metric[]> {
+	const p = new URLSearchParams();
+	if (opts.name) p.set('name', opts.name);
+	if (opts.match) p.set('match', opts.match);
+	if (opts.from) p.set('from', String(opts.from));
+	if (opts.to) p.set('to', String(opts.to));
+	if (opts.step) p.set('step', String(opts.step));
+	if (opts.quantiles?.length) p.set('q', opts.quantiles.join(','));
+	const data = await get<Metric[]>('/api/histograms/quantiles?' + p.toString());
+	return data ?? [];
+}
+
+// Label discovery for autocomplete; the scope narrows it like getMetrics.
+export interface LabelScope { name?: string; match?: string; from?: number; to?: number }
+
+function labelQuery(scope?: LabelScope): string {
+	const p = new URLSearchParams();
+	if (scope?.name) p.set('name', scope.name);
+	if (scope?.match) p.set('match', scope.match);
+	if (scope?.from) p.set('from', String(scope.from));
+	if (scope?.to) p.set('to', String(scope.to));
+	return p.toString() ? '?' + p.toString() : '';
+}
+
+export async function getLabelNames(scope?: LabelScope): Promise<string[]> {
+	return get<string[]>('/api/labels' + labelQuery(scope));
+}
+
+export async function getLabelValues(label: string, scope?: LabelScope): Promise<string[]> {
+	return get<string[]>(`/api/labels/${encodeURIComponent(label)}/values` + labelQuery(scope));
+}
+
+export interface MetricUsage {
+	name: string;
+	series: number;
+	samples_last_day: number;
+	bytes_last_day: number;
+	first_seen: number;
+	last_seen: number;
+}
+export interface LabelUsage { name: string; distinct_values: number; series: number }
+export interface UsageStats {
+	series: number;
+	samples_last_day: number;
+	bytes_last_day: number;
+	metrics: MetricUsage[];
+	labels: LabelUsage[];
+}
+
+export async function getStats(limit?: number): Promise<UsageStats> {
+	return get<UsageStats>('/api/stats' + (limit !== undefined ? `?limit=${limit}` : ''));
+}
+
+export interface MetricMetadata {
+	name: string;
+	type: 'unknown' | 'counter' | 'gauge' | 'histogram' | 'summary';
+	unit?: string;
+	help?: string;
+}
+
+export async function getMetadata(name?: string): Promise<MetricMetadata[]> {
+	const qs = name ? `?name=${encodeURIComponent(name)}` : '';
+	const data = await get<MetricMetadata[]>('/api/metadata' + qs);
+	return data ?? [];
+}
+
 export async function deleteMetric(name: string): Promise<void> {
 	await del(`/api/metrics?name=${encodeURIComponent(name)}`);
 }